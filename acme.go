@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"golang.org/x/crypto/acme"
+)
+
+// letsEncryptStagingURL is the Let's Encrypt staging directory, whose
+// certificates are not trusted but whose rate limits are generous.
+const letsEncryptStagingURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+type AcmeConfig struct {
+	Domains        []string
+	Email          string
+	Directory      string
+	AccountKeyFile string
+	KeyType        string
+	HostedZoneID   string
+	OutDir         string
+	CertificateArn string
+	Regions        []string
+	AllRegions     bool
+	Tags           map[string]string
+	AutoTags       bool
+	Upsert         bool
+	Wait           bool
+	PublishArn     []publishTarget
+	Output         string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+func runAcme(ctx context.Context, args []string) error {
+	var cfg AcmeConfig
+	var domains, regions, tagString, tagsFile, publishArn string
+	var staging bool
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("acme", flag.ExitOnError)
+	fs.StringVar(&domains, "domains", "", "Names to include in the certificate, e.g. 'example.com,*.example.com' - REQUIRED")
+	fs.StringVar(&cfg.Email, "email", "", "Contact address for the ACME account (expiry and revocation notices)")
+	fs.StringVar(&cfg.Directory, "directory", acme.LetsEncryptURL, "ACME directory URL")
+	fs.BoolVar(&staging, "staging", false, "Use the Let's Encrypt staging directory (untrusted certificates, for testing)")
+	fs.StringVar(&cfg.AccountKeyFile, "account-key", "", "ACME account key file, created if missing (default: aws-certs/acme-account.pem in the user config directory)")
+	fs.StringVar(&cfg.KeyType, "key-type", "ec256", "Certificate key type: "+keyTypes)
+	fs.StringVar(&cfg.HostedZoneID, "hosted-zone-id", "", "Route 53 hosted zone for the DNS-01 records (default: the closest public zone of each name)")
+	fs.StringVar(&cfg.OutDir, "out-dir", "", "Also write cert.pem, chain.pem, fullchain.pem and privkey.pem to this directory")
+	fs.StringVar(&cfg.CertificateArn, "certificate-arn", "", "ARN of an existing imported certificate to replace in-place - OPTIONAL")
+	fs.StringVar(&regions, "regions", "", "Import into several regions concurrently, e.g. 'us-east-1,eu-west-1'")
+	fs.BoolVar(&cfg.AllRegions, "all-regions", false, "Import into every region enabled for the account")
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.StringVar(&tagsFile, "tags-file", "", "JSON or YAML file with a map of tag keys to values")
+	fs.BoolVar(&cfg.AutoTags, "auto-tags", false, "Add tags describing the certificate and the import (Domain, Issuer, NotAfter, ImportedBy, ...)")
+	fs.BoolVar(&cfg.Upsert, "upsert", false, "Re-import over an existing imported certificate with the same domain and SANs")
+	fs.BoolVar(&cfg.Wait, "wait", false, "After importing, wait until ACM reports the certificate as ISSUED and verify it matches")
+	fs.StringVar(&publishArn, "publish-arn", "", "After importing, write the ARN to 'ssm:/path/to/param' or 'secretsmanager:name' (comma-separated for several)")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s acme -domains NAMES [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Obtain a certificate from an ACME CA (Let's Encrypt by default) and import it into ACM.\n")
+		fmt.Fprintf(os.Stderr, "Domains are validated with DNS-01 challenges: the TXT records are created in Route 53\n")
+		fmt.Fprintf(os.Stderr, "and removed again once the CA has checked them.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s acme -domains example.com,www.example.com -email ops@example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s acme -domains '*.example.com' -staging -out-dir ./certs\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s acme -domains example.com -upsert -regions us-east-1,eu-west-1\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Domains = cliutil.SplitList(domains)
+	if len(cfg.Domains) == 0 {
+		fail(errors.New("-domains is required"))
+	}
+	if staging {
+		cfg.Directory = letsEncryptStagingURL
+	}
+	if err := checkKeyType(cfg.KeyType); err != nil {
+		fail(err)
+	}
+
+	cfg.Regions = cliutil.SplitList(regions)
+	var err error
+	cfg.PublishArn, err = parsePublishTargets(publishArn)
+	if err != nil {
+		fail(err)
+	}
+
+	var fileTags, inlineTags map[string]string
+	if tagsFile != "" {
+		fileTags, err = loadTagsFile(tagsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Tags = mergeTags(fileTags, inlineTags, tagList)
+
+	if err := cfg.importConfig().validateOptions(); err != nil {
+		fail(err)
+	}
+
+	if cfg.AccountKeyFile == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			fail(fmt.Errorf("-account-key is required: %v", err))
+		}
+		cfg.AccountKeyFile = filepath.Join(dir, "aws-certs", "acme-account.pem")
+	}
+
+	return issueAcme(ctx, cfg)
+}
+
+// importConfig returns the options for importing the issued certificate.
+func (cfg AcmeConfig) importConfig() *CertImportConfig {
+	return &CertImportConfig{
+		CertificateArn: cfg.CertificateArn,
+		Region:         cfg.Region,
+		Regions:        cfg.Regions,
+		AllRegions:     cfg.AllRegions,
+		AutoTags:       cfg.AutoTags,
+		Upsert:         cfg.Upsert,
+		Wait:           cfg.Wait,
+		PublishArn:     cfg.PublishArn,
+		Output:         cfg.Output,
+		Profile:        cfg.Profile,
+		Timeout:        cfg.Timeout,
+		Tags:           cfg.Tags,
+	}
+}
+
+func issueAcme(ctx context.Context, cfg AcmeConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	imp := cfg.importConfig()
+	awsCfg, regions, err := importTargets(ctx, imp)
+	if err != nil {
+		return err
+	}
+
+	accountKey, err := loadAccountKey(cfg.AccountKeyFile)
+	if err != nil {
+		return err
+	}
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: cfg.Directory,
+		UserAgent:    "aws-certs/" + toolVersion(),
+	}
+
+	account := &acme.Account{}
+	if cfg.Email != "" {
+		account.Contact = []string{"mailto:" + cfg.Email}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("failed to register ACME account: %w", err)
+	}
+	progress.Printf("✓ ACME account ready (%s)\n", cfg.Directory)
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(cfg.Domains...))
+	if err != nil {
+		return fmt.Errorf("failed to create ACME order: %w", err)
+	}
+
+	// Collect the DNS-01 challenges. A wildcard and its base name share one
+	// record name, so the values are grouped by name.
+	var challenges []*acme.Challenge
+	var authzURLs []string
+	values := make(map[string][]string)
+	for _, url := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, url)
+		if err != nil {
+			return fmt.Errorf("failed to get ACME authorization: %w", err)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+
+		var challenge *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == "dns-01" {
+				challenge = c
+			}
+		}
+		if challenge == nil {
+			return fmt.Errorf("ACME server offered no dns-01 challenge for %s", authz.Identifier.Value)
+		}
+		value, err := client.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return err
+		}
+
+		name := "_acme-challenge." + authz.Identifier.Value
+		values[name] = append(values[name], `"`+value+`"`)
+		challenges = append(challenges, challenge)
+		authzURLs = append(authzURLs, authz.URI)
+	}
+
+	if len(challenges) > 0 {
+		records := make([]dnsRecord, 0, len(values))
+		for name, v := range values {
+			records = append(records, dnsRecord{Name: name, Type: r53types.RRTypeTxt, Values: v})
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+
+		r53 := route53.NewFromConfig(awsCfg)
+		zones := newZoneFinder(r53, cfg.HostedZoneID)
+		progress.Printf("Creating %d DNS-01 records in Route 53...\n", len(records))
+		if err := changeRecords(ctx, r53, zones, r53types.ChangeActionUpsert, records); err != nil {
+			return err
+		}
+		defer func() {
+			// Remove the records even if the command timed out or was interrupted
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dnsChangeTimeout)
+			defer cancel()
+			if err := changeRecords(ctx, r53, zones, r53types.ChangeActionDelete, records); err != nil {
+				progress.Warnf("failed to remove DNS-01 records: %v", err)
+			}
+		}()
+		progress.Printf("✓ DNS-01 records in sync\n")
+
+		for i, challenge := range challenges {
+			if _, err := client.Accept(ctx, challenge); err != nil {
+				return fmt.Errorf("failed to accept ACME challenge: %w", err)
+			}
+			if _, err := client.WaitAuthorization(ctx, authzURLs[i]); err != nil {
+				return fmt.Errorf("ACME validation failed: %w", err)
+			}
+		}
+	}
+	progress.Printf("✓ Domains validated: %s\n", strings.Join(cfg.Domains, ", "))
+
+	if _, err := client.WaitOrder(ctx, order.URI); err != nil {
+		return fmt.Errorf("ACME order failed: %w", err)
+	}
+
+	certKey, err := generateKey(cfg.KeyType)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: strings.TrimPrefix(cfg.Domains[0], "*.")},
+		DNSNames: cfg.Domains,
+	}, certKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("failed to finalize ACME order: %w", err)
+	}
+
+	material := &certimport.Material{Key: certKey}
+	for i, b := range der {
+		cert, err := x509.ParseCertificate(b)
+		if err != nil {
+			return fmt.Errorf("ACME server returned an invalid certificate: %w", err)
+		}
+		if i == 0 {
+			material.Leaf = cert
+		} else {
+			material.Chain = append(material.Chain, cert)
+		}
+	}
+	progress.Printf("✓ Certificate issued (expires %s)\n", material.Leaf.NotAfter.Format("2006-01-02"))
+
+	// Keep a copy before importing, so a failed import does not cost another
+	// issuance against the CA's rate limits
+	if cfg.OutDir != "" {
+		if err := writeMaterial(cfg.OutDir, material); err != nil {
+			return err
+		}
+		progress.Printf("✓ Certificate written to %s\n", cfg.OutDir)
+	}
+
+	return importLoaded(ctx, *imp, awsCfg, regions, material)
+}
+
+// loadAccountKey reads the ACME account key from path, creating a new EC
+// P-256 key there if the file does not exist yet.
+func loadAccountKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return certimport.ParsePrivateKey(data, nil)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read ACME account key: %w", err)
+	}
+
+	key, err := generateKey("ec256")
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := certimport.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create ACME account key directory: %w", err)
+	}
+	if err := os.WriteFile(path, keyPEM, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write ACME account key: %w", err)
+	}
+	progress.Printf("✓ Created ACME account key %s\n", path)
+	return key, nil
+}
+
+// writeMaterial writes material in the certbot layout: cert.pem, chain.pem,
+// fullchain.pem and privkey.pem.
+func writeMaterial(dir string, material *certimport.Material) error {
+	keyPEM, err := certimport.EncodePrivateKey(material.Key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	leaf := []*x509.Certificate{material.Leaf}
+	files := []struct {
+		name string
+		data []byte
+		mode os.FileMode
+	}{
+		{"cert.pem", certimport.EncodeCertificates(leaf), 0o644},
+		{"chain.pem", certimport.EncodeCertificates(material.Chain), 0o644},
+		{"fullchain.pem", certimport.EncodeCertificates(append(leaf, material.Chain...)), 0o644},
+		{"privkey.pem", keyPEM, 0o600},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.data, f.mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type AdviseConfig struct {
+	CertificateArn string
+	CertFile       string
+	Output         string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+// Verdicts of advise.
+const (
+	adviseManaged    = "already managed"
+	adviseEligible   = "eligible"
+	adviseIneligible = "not eligible"
+)
+
+// Limits of certificates issued by ACM: 10 names by default, raised to at
+// most 100 on request, and these key algorithms.
+const (
+	acmDefaultNames = 10
+	acmMaxNames     = 100
+)
+
+var acmIssuedAlgorithms = []string{"RSA_2048", "ECDSA_P256", "ECDSA_P384"}
+
+// privateSuffixes are top-level names that never resolve publicly, so no
+// public CA can validate them.
+var privateSuffixes = []string{"local", "localhost", "internal", "intranet", "corp", "lan", "home", "localdomain", "test", "example", "invalid", "private"}
+
+// certificateAdvice is the JSON representation of advise.
+type certificateAdvice struct {
+	Source       string           `json:"source"`
+	Type         string           `json:"type,omitempty"`
+	DomainName   string           `json:"domainName"`
+	KeyAlgorithm string           `json:"keyAlgorithm"`
+	NotAfter     time.Time        `json:"notAfter"`
+	Verdict      string           `json:"verdict"`
+	Blockers     []string         `json:"blockers,omitempty"`
+	Notes        []string         `json:"notes,omitempty"`
+	Names        []nameValidation `json:"names"`
+	Request      []string         `json:"request,omitempty"`
+}
+
+// nameValidation is how ACM could validate a name of the certificate: in a
+// Route 53 hosted zone of the account, with a record created at another DNS
+// provider, or not at all when Problem says why.
+type nameValidation struct {
+	Name         string `json:"name"`
+	Validation   string `json:"validation,omitempty"`
+	HostedZoneID string `json:"hostedZoneId,omitempty"`
+	HostedZone   string `json:"hostedZone,omitempty"`
+	Problem      string `json:"problem,omitempty"`
+}
+
+// Ways of validating a name.
+const (
+	validateRoute53  = "route53"
+	validateExternal = "external-dns"
+)
+
+func runAdvise(ctx context.Context, args []string) error {
+	var cfg AdviseConfig
+
+	fs := flag.NewFlagSet("advise", flag.ExitOnError)
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the ACM certificate to assess")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.StringVar(&cfg.CertFile, "cert", "", "Local certificate to assess, instead of -arn")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s advise (-arn <arn> | -cert <file>) [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Assess whether a certificate could be replaced by one issued and renewed automatically by\n")
+		fmt.Fprintf(os.Stderr, "ACM, and which of its names Route 53 hosted zones of the account can validate by DNS.\n")
+		fmt.Fprintf(os.Stderr, "Nothing is changed.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s advise -arn arn:aws:acm:us-east-1:123456789012:certificate/abc\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s advise -cert /etc/letsencrypt/live/example.com/cert.pem -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if countSet(cfg.CertFile, cfg.CertificateArn) != 1 {
+		fail(errors.New("exactly one of -arn and -cert is required"))
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fail(errors.New("-output must be text or json"))
+	}
+	if cfg.CertificateArn != "" {
+		region, err := arnRegion(cfg.CertificateArn)
+		if err != nil {
+			fail(err)
+		}
+		cfg.Region = region
+	}
+
+	return adviseCertificate(ctx, cfg)
+}
+
+func adviseCertificate(ctx context.Context, cfg AdviseConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+
+	var leaf *x509.Certificate
+	var detail *types.CertificateDetail
+	source := cfg.CertFile
+	if cfg.CertificateArn != "" {
+		source = cfg.CertificateArn
+		client := acm.NewFromConfig(awsCfg)
+		if detail, err = describeCertificate(ctx, client, cfg.CertificateArn); err != nil {
+			return err
+		}
+		out, err := client.GetCertificate(ctx, &acm.GetCertificateInput{CertificateArn: aws.String(cfg.CertificateArn)})
+		if err != nil {
+			return fmt.Errorf("failed to get certificate: %w", err)
+		}
+		certs, err := certimport.ParseCertificates([]byte(aws.ToString(out.Certificate)), "certificate")
+		if err != nil {
+			return err
+		}
+		leaf = certs[0]
+	} else {
+		certs, err := readLocalCertificates(cfg.CertFile, "certificate")
+		if err != nil {
+			return err
+		}
+		leaf = findLeaf(certs)
+	}
+
+	advice := assessCertificate(source, leaf, detail)
+	zones := newZoneFinder(route53.NewFromConfig(awsCfg), "")
+	for i := range advice.Names {
+		n := &advice.Names[i]
+		if n.Problem != "" {
+			continue
+		}
+		// A wildcard is validated at the name it covers
+		zone, err := zones.lookup(ctx, strings.TrimPrefix(n.Name, "*."))
+		if err != nil {
+			progress.Warnf("Could not look up Route 53 hosted zones: %v", err)
+			break
+		}
+		if zone == nil {
+			n.Validation = validateExternal
+			continue
+		}
+		n.Validation = validateRoute53
+		n.HostedZoneID = strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/")
+		n.HostedZone = strings.TrimSuffix(aws.ToString(zone.Name), ".")
+	}
+	if advice.Verdict == adviseEligible {
+		for _, n := range advice.Names {
+			advice.Request = append(advice.Request, n.Name)
+		}
+	}
+
+	if cfg.Output == "json" {
+		return printJSON(advice)
+	}
+	printAdvice(advice, awsCfg.Region)
+	return nil
+}
+
+// assessCertificate decides whether ACM could issue leaf instead. detail
+// is the ACM description of the certificate, when it is in ACM.
+func assessCertificate(source string, leaf *x509.Certificate, detail *types.CertificateDetail) certificateAdvice {
+	advice := certificateAdvice{
+		Source:       source,
+		DomainName:   leaf.Subject.CommonName,
+		KeyAlgorithm: certimport.KeyAlgorithm(leaf),
+		NotAfter:     leaf.NotAfter.UTC(),
+		Verdict:      adviseEligible,
+	}
+	if detail != nil {
+		advice.Type = string(detail.Type)
+		switch detail.Type {
+		case types.CertificateTypeAmazonIssued:
+			advice.Verdict = adviseManaged
+			advice.Notes = append(advice.Notes, "ACM issued this certificate and renews it automatically")
+		case types.CertificateTypePrivate:
+			advice.Verdict = adviseManaged
+			advice.Notes = append(advice.Notes, "ACM Private CA issued this certificate; ACM renews it while the CA is active")
+		}
+		if detail.Type == types.CertificateTypeImported && len(detail.InUseBy) == 0 {
+			advice.Notes = append(advice.Notes, "No AWS resource uses it; if it is installed outside AWS, request the ACM certificate as exportable")
+		}
+	}
+
+	names := leaf.DNSNames
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = []string{leaf.Subject.CommonName}
+	}
+	if advice.DomainName == "" && len(names) > 0 {
+		advice.DomainName = names[0]
+	}
+	for _, name := range names {
+		advice.Names = append(advice.Names, nameValidation{Name: name, Problem: publicNameProblem(name)})
+	}
+
+	block := func(format string, args ...interface{}) {
+		advice.Blockers = append(advice.Blockers, fmt.Sprintf(format, args...))
+	}
+	for _, n := range advice.Names {
+		if n.Problem != "" {
+			block("%s: %s", n.Name, n.Problem)
+		}
+	}
+	if len(names) == 0 {
+		block("the certificate has no DNS names")
+	}
+	if len(leaf.IPAddresses) > 0 || len(leaf.EmailAddresses) > 0 || len(leaf.URIs) > 0 {
+		block("it names IP addresses, email addresses or URIs, which ACM cannot issue certificates for")
+	}
+	if len(names) > acmMaxNames {
+		block("it has %d names, more than the %d ACM allows", len(names), acmMaxNames)
+	} else if len(names) > acmDefaultNames {
+		advice.Notes = append(advice.Notes, fmt.Sprintf("It has %d names; ACM allows %d unless the quota is raised (up to %d)", len(names), acmDefaultNames, acmMaxNames))
+	}
+	if len(leaf.Subject.Organization) > 0 {
+		advice.Notes = append(advice.Notes, fmt.Sprintf("It is organization or extended validated (O=%s); ACM issues domain validated certificates only", leaf.Subject.Organization[0]))
+	}
+	if !slices.Contains(acmIssuedAlgorithms, advice.KeyAlgorithm) {
+		advice.Notes = append(advice.Notes, fmt.Sprintf("ACM issues %s keys, not %s", strings.Join(acmIssuedAlgorithms, ", "), advice.KeyAlgorithm))
+	}
+
+	if len(advice.Blockers) > 0 && advice.Verdict == adviseEligible {
+		advice.Verdict = adviseIneligible
+	}
+	return advice
+}
+
+// publicNameProblem returns why a public CA cannot validate name, or "".
+func publicNameProblem(name string) string {
+	name = strings.ToLower(strings.TrimPrefix(name, "*."))
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return "not a fully qualified domain name"
+	}
+	if slices.Contains(privateSuffixes, labels[len(labels)-1]) {
+		return "not a public domain; ACM Private CA can issue certificates for internal names"
+	}
+	return ""
+}
+
+func printAdvice(a certificateAdvice, region string) {
+	fmt.Printf("Certificate:    %s\n", a.Source)
+	if a.Type != "" {
+		fmt.Printf("Type:           %s\n", a.Type)
+	}
+	fmt.Printf("Domain:         %s\n", a.DomainName)
+	fmt.Printf("Key:            %s\n", a.KeyAlgorithm)
+	fmt.Printf("Expires:        %s (%d days)\n", a.NotAfter.Format("2006-01-02"), daysUntil(a.NotAfter))
+	fmt.Printf("ACM-issued:     %s\n", a.Verdict)
+
+	fmt.Printf("\nDNS validation:\n")
+	for _, n := range a.Names {
+		switch n.Validation {
+		case validateRoute53:
+			stdout.Printf("  ✓ %s: Route 53 zone %s (%s)\n", n.Name, n.HostedZone, n.HostedZoneID)
+		case validateExternal:
+			stdout.Printf("  ⚠ %s: no Route 53 hosted zone; create the validation CNAME at your DNS provider\n", n.Name)
+		default:
+			if n.Problem != "" {
+				stdout.Printf("  ✗ %s: %s\n", n.Name, n.Problem)
+			} else {
+				fmt.Printf("  - %s\n", n.Name)
+			}
+		}
+	}
+	if len(a.Blockers) > 0 {
+		fmt.Printf("\nBlockers:\n")
+		for _, b := range a.Blockers {
+			stdout.Printf("  ✗ %s\n", b)
+		}
+	}
+	if len(a.Notes) > 0 {
+		fmt.Printf("\nNotes:\n")
+		for _, note := range a.Notes {
+			fmt.Printf("  - %s\n", note)
+		}
+	}
+	if len(a.Request) > 0 {
+		fmt.Printf("\nTo have ACM issue and renew it (validation records are created in Route 53 where possible):\n")
+		command := fmt.Sprintf("%s request -domains '%s'", os.Args[0], strings.Join(a.Request, ","))
+		if region != "" {
+			command += " -region " + region
+		}
+		fmt.Printf("  %s\n", command)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	agtypes "github.com/aws/aws-sdk-go-v2/service/apigateway/types"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	agv2types "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+)
+
+type AttachAPIGatewayConfig struct {
+	DomainNames    []string
+	CertificateArn string
+	Profile        string
+	Region         string
+	Timeout        time.Duration
+}
+
+func runAttachAPIGateway(ctx context.Context, args []string) error {
+	var cfg AttachAPIGatewayConfig
+	var domainNames string
+
+	fs := flag.NewFlagSet("attach-apigw", flag.ExitOnError)
+	fs.StringVar(&domainNames, "domain-name", "", "API Gateway custom domain name (comma-separated for several) - REQUIRED")
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to use - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s attach-apigw -domain-name <name> -arn <arn> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Update the certificate of an API Gateway custom domain (REST, HTTP or WebSocket APIs).\n")
+		fmt.Fprintf(os.Stderr, "Regional domains need a certificate in -region, edge-optimized ones a certificate in %s.\n\n", cloudFrontRegion)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s attach-apigw -domain-name api.example.com -arn arn:aws:acm:eu-west-1:123456789012:certificate/abc -region eu-west-1\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -region eu-west-1 -attach-apigw api.example.com\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	cfg.DomainNames = cliutil.SplitList(domainNames)
+	if len(cfg.DomainNames) == 0 || cfg.CertificateArn == "" {
+		fmt.Fprintf(os.Stderr, "Error: -domain-name and -arn are required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	for _, name := range cfg.DomainNames {
+		if err := attachAPIDomain(ctx, awsCfg, name, cfg.CertificateArn); err != nil {
+			return err
+		}
+	}
+
+	if !progress.Quiet() {
+		stdout.Printf("✅ Certificate attached to %d custom domain(s)\n", len(cfg.DomainNames))
+	}
+	return nil
+}
+
+// apiDomainCertificateRegion returns the region the certificate of a custom
+// domain has to be in: us-east-1 for edge-optimized domains, the domain's own
+// region otherwise.
+func apiDomainCertificateRegion(ctx context.Context, awsCfg aws.Config, name string) (string, error) {
+	domain, err := apigateway.NewFromConfig(awsCfg).GetDomainName(ctx, &apigateway.GetDomainNameInput{DomainName: aws.String(name)})
+	if err != nil {
+		var notFound *agtypes.NotFoundException
+		if errors.As(err, &notFound) {
+			// Domains only known to the HTTP API are regional
+			return awsCfg.Region, nil
+		}
+		return "", fmt.Errorf("failed to get custom domain %s: %w", name, err)
+	}
+	if domain.EndpointConfiguration != nil && slices.Contains(domain.EndpointConfiguration.Types, agtypes.EndpointTypeEdge) {
+		return cloudFrontRegion, nil
+	}
+	return awsCfg.Region, nil
+}
+
+// attachAPIDomain points a custom domain at certificateArn. Domains are
+// updated through the REST API, which sees every custom domain in the
+// region; domains it does not know are updated through the HTTP API.
+func attachAPIDomain(ctx context.Context, awsCfg aws.Config, name, certificateArn string) error {
+	client := apigateway.NewFromConfig(awsCfg)
+	domain, err := client.GetDomainName(ctx, &apigateway.GetDomainNameInput{DomainName: aws.String(name)})
+	var notFound *agtypes.NotFoundException
+	if errors.As(err, &notFound) {
+		return attachHTTPAPIDomain(ctx, awsCfg, name, certificateArn)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get custom domain %s: %w", name, err)
+	}
+
+	path, current := "/regionalCertificateArn", aws.ToString(domain.RegionalCertificateArn)
+	if domain.EndpointConfiguration != nil && slices.Contains(domain.EndpointConfiguration.Types, agtypes.EndpointTypeEdge) {
+		path, current = "/certificateArn", aws.ToString(domain.CertificateArn)
+	}
+	if current == certificateArn {
+		progress.Printf("✓ Custom domain %s already uses %s\n", name, certificateArn)
+		return nil
+	}
+
+	_, err = client.UpdateDomainName(ctx, &apigateway.UpdateDomainNameInput{
+		DomainName: aws.String(name),
+		PatchOperations: []agtypes.PatchOperation{{
+			Op:    agtypes.OpReplace,
+			Path:  aws.String(path),
+			Value: aws.String(certificateArn),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update custom domain %s: %w", name, err)
+	}
+	progress.Printf("✓ Custom domain %s now uses %s\n", name, certificateArn)
+	return nil
+}
+
+// attachHTTPAPIDomain updates a custom domain through the HTTP API, keeping
+// the endpoint type, security policy and IP address type of its
+// configuration.
+func attachHTTPAPIDomain(ctx context.Context, awsCfg aws.Config, name, certificateArn string) error {
+	client := apigatewayv2.NewFromConfig(awsCfg)
+	domain, err := client.GetDomainName(ctx, &apigatewayv2.GetDomainNameInput{DomainName: aws.String(name)})
+	if err != nil {
+		return fmt.Errorf("failed to get custom domain %s: %w", name, err)
+	}
+
+	var configs []agv2types.DomainNameConfiguration
+	changed := false
+	for _, c := range domain.DomainNameConfigurations {
+		if aws.ToString(c.CertificateArn) != certificateArn {
+			changed = true
+		}
+		configs = append(configs, agv2types.DomainNameConfiguration{
+			CertificateArn: aws.String(certificateArn),
+			EndpointType:   c.EndpointType,
+			SecurityPolicy: c.SecurityPolicy,
+			IpAddressType:  c.IpAddressType,
+		})
+	}
+	if !changed {
+		progress.Printf("✓ Custom domain %s already uses %s\n", name, certificateArn)
+		return nil
+	}
+
+	_, err = client.UpdateDomainName(ctx, &apigatewayv2.UpdateDomainNameInput{
+		DomainName:               aws.String(name),
+		DomainNameConfigurations: configs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update custom domain %s: %w", name, err)
+	}
+	progress.Printf("✓ Custom domain %s now uses %s\n", name, certificateArn)
+	return nil
+}
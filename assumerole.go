@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"golang.org/x/term"
+)
+
+// assumeRole holds the -role-arn flags. The assumed credentials are cached
+// so that commands loading a configuration per region only prompt for an MFA
+// token once.
+var assumeRole struct {
+	roleArn     string
+	externalID  string
+	mfaSerial   string
+	sessionName string
+
+	mu          sync.Mutex
+	credentials *aws.CredentialsCache
+}
+
+// addAssumeRoleFlags registers the flags for running as another role.
+func addAssumeRoleFlags(fs *flag.FlagSet) {
+	fs.StringVar(&assumeRole.roleArn, "role-arn", "", "Assume this IAM role, e.g. to import into another account")
+	fs.StringVar(&assumeRole.externalID, "external-id", "", "External ID required by the role's trust policy")
+	fs.StringVar(&assumeRole.mfaSerial, "mfa-serial", "", "ARN of the MFA device required by the role (the token code is prompted for)")
+	fs.StringVar(&assumeRole.sessionName, "session-name", "", "Session name recorded in CloudTrail (default: aws-certs-<user>)")
+}
+
+// assumedCredentials returns credentials for -role-arn obtained with the
+// base configuration, or nil when no role is to be assumed.
+func assumedCredentials(base aws.Config) aws.CredentialsProvider {
+	if assumeRole.roleArn == "" {
+		return nil
+	}
+
+	assumeRole.mu.Lock()
+	defer assumeRole.mu.Unlock()
+	if assumeRole.credentials != nil {
+		return assumeRole.credentials
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(base), assumeRole.roleArn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = assumeRole.sessionName
+		if o.RoleSessionName == "" {
+			o.RoleSessionName = "aws-certs-" + sessionUser()
+		}
+		if assumeRole.externalID != "" {
+			o.ExternalID = aws.String(assumeRole.externalID)
+		}
+		if assumeRole.mfaSerial != "" {
+			o.SerialNumber = aws.String(assumeRole.mfaSerial)
+			o.TokenProvider = promptMFAToken
+		}
+	})
+	assumeRole.credentials = aws.NewCredentialsCache(provider)
+	return assumeRole.credentials
+}
+
+// sessionUser returns the local user name reduced to the
+// characters allowed in a role session name.
+func sessionUser() string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("=,.@-_", r):
+			return r
+		}
+		return '_'
+	}, localUser())
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	return name
+}
+
+// promptMFAToken reads an MFA token code from the terminal. The prompt goes
+// to stderr so that stdout only carries results.
+func promptMFAToken() (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("-mfa-serial needs a token code but stdin is not a terminal")
+	}
+	fmt.Fprintf(os.Stderr, "MFA token code for %s: ", assumeRole.mfaSerial)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read MFA token code: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
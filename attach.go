@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// errNotImported is returned when a resource to attach the certificate to
+// is in a region the certificate was not imported into.
+var errNotImported = errors.New("certificate was not imported into region")
+
+// validateAttach checks the -attach flags of an import.
+func (cfg *CertImportConfig) validateAttach() error {
+	if len(cfg.AttachListeners) == 0 && len(cfg.AttachDistributions) == 0 && len(cfg.AttachAPIDomains) == 0 {
+		return nil
+	}
+	if cfg.Dir != "" || cfg.TargetsFile != "" || cfg.IAM {
+		return errors.New("-attach-listener, -attach-distribution and -attach-apigw cannot be combined with -dir, -targets or -iam")
+	}
+	for _, listener := range cfg.AttachListeners {
+		if _, err := arnRegion(listener); err != nil {
+			return fmt.Errorf("-attach-listener: %w", err)
+		}
+	}
+	return nil
+}
+
+// attachImported points the resources named by the -attach flags at the
+// certificate imported into their region.
+func attachImported(ctx context.Context, awsCfg aws.Config, cfg CertImportConfig, result *certimport.Result) error {
+	for _, listener := range cfg.AttachListeners {
+		region, err := arnRegion(listener)
+		if err != nil {
+			return err
+		}
+		certificateArn, err := importedArn(result, region)
+		if err != nil {
+			return fmt.Errorf("cannot attach to listener %s: %w", listener, err)
+		}
+		if err := attachListener(ctx, awsCfg, listener, certificateArn, false); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range cfg.AttachDistributions {
+		certificateArn, err := importedArn(result, cloudFrontRegion)
+		if err != nil {
+			return fmt.Errorf("cannot attach to distribution %s (CloudFront needs the certificate in %s): %w", id, cloudFrontRegion, err)
+		}
+		if err := attachDistribution(ctx, awsCfg, id, certificateArn, false); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range cfg.AttachAPIDomains {
+		region, err := apiDomainCertificateRegion(ctx, awsCfg, name)
+		if err != nil {
+			return err
+		}
+		certificateArn, err := importedArn(result, region)
+		if err != nil {
+			return fmt.Errorf("cannot attach to custom domain %s: %w", name, err)
+		}
+		if err := attachAPIDomain(ctx, awsCfg, name, certificateArn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importedArn returns the ARN of the certificate imported into region.
+func importedArn(result *certimport.Result, region string) (string, error) {
+	for _, r := range result.Regions {
+		if r.Region == region && r.Err == nil && r.CertificateArn != "" {
+			return r.CertificateArn, nil
+		}
+	}
+	return "", fmt.Errorf("%w %s", errNotImported, region)
+}
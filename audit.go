@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwltypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// auditLogsPrefix selects a CloudWatch Logs group as the -audit-log
+// destination instead of a file.
+const auditLogsPrefix = "logs:"
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	importEvent
+	Account     string `json:"account,omitempty"`
+	Identity    string `json:"identity,omitempty"`
+	LocalUser   string `json:"localUser"`
+	Host        string `json:"host"`
+	ToolVersion string `json:"toolVersion"`
+}
+
+// auditMu serializes writes to the audit log file from concurrent imports.
+var auditMu sync.Mutex
+
+// writeAudit appends a record of event, with the AWS identity that performed
+// it, to the audit log file or CloudWatch Logs group.
+func writeAudit(ctx context.Context, awsCfg aws.Config, destination string, event importEvent) error {
+	record := auditRecord{
+		importEvent: event,
+		LocalUser:   localUser(),
+		ToolVersion: toolVersion(),
+	}
+	record.Host, _ = os.Hostname()
+	identity, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		// Still record the event; the failure may be the reason it failed
+		progress.Warnf("Could not determine AWS identity for the audit log: %v", err)
+	} else {
+		record.Account = aws.ToString(identity.Account)
+		record.Identity = aws.ToString(identity.Arn)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if group, ok := strings.CutPrefix(destination, auditLogsPrefix); ok {
+		return putAuditEvent(ctx, awsCfg, group, record.Host, event.Time, line)
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.OpenFile(destination, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log %s: %w", destination, err)
+	}
+	return f.Close()
+}
+
+// putAuditEvent writes line to a stream named after the host in an existing
+// CloudWatch Logs group, creating the stream on first use.
+func putAuditEvent(ctx context.Context, awsCfg aws.Config, group, host string, at time.Time, line []byte) error {
+	client := cloudwatchlogs.NewFromConfig(awsCfg)
+	stream := "aws-certs/" + host
+
+	_, err := client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(group),
+		LogStreamName: aws.String(stream),
+	})
+	var exists *cwltypes.ResourceAlreadyExistsException
+	if err != nil && !errors.As(err, &exists) {
+		return fmt.Errorf("failed to create log stream %s in %s: %w", stream, group, err)
+	}
+
+	_, err = client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(group),
+		LogStreamName: aws.String(stream),
+		LogEvents: []cwltypes.InputLogEvent{{
+			Message:   aws.String(string(line)),
+			Timestamp: aws.Int64(at.UnixMilli()),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write audit event to %s: %w", group, err)
+	}
+	return nil
+}
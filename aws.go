@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go/logging"
+)
+
+// defaultTimeout bounds a whole command, including retries, so that a hung
+// connection does not block automation forever.
+const defaultTimeout = 5 * time.Minute
+
+// retryOptions configures the SDK retryer for every AWS client. Like the log
+// flags they are shared by all subcommands, so they are kept globally rather
+// than in each command's config.
+var retryOptions struct {
+	maxRetries int
+	mode       aws.RetryMode
+}
+
+// endpointOptions redirects AWS requests, e.g. to LocalStack or VPC
+// endpoints. Like retryOptions they apply to every client.
+var endpointOptions struct {
+	url      string
+	services serviceEndpoints
+	fips     bool
+}
+
+// serviceEndpoints maps services, named like the services section of the
+// shared config file (acm, sts, secrets_manager, ...), to endpoint URLs. It
+// is added to the config sources so that each client looks up its own.
+type serviceEndpoints map[string]string
+
+func (s serviceEndpoints) GetServiceBaseEndpoint(ctx context.Context, sdkID string) (string, bool, error) {
+	endpoint, ok := s[strings.ReplaceAll(strings.ToLower(sdkID), " ", "_")]
+	return endpoint, ok, nil
+}
+
+// parseEndpointURL checks that value is an absolute http or https URL.
+func parseEndpointURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint URL %q, expected e.g. https://vpce-123.acm.us-east-1.vpce.amazonaws.com", value)
+	}
+	return nil
+}
+
+// addAWSFlags registers the -region, -profile and -timeout flags shared by
+// every subcommand that talks to AWS, along with the retry and endpoint
+// flags.
+func addAWSFlags(fs *flag.FlagSet, region, profile *string, timeout *time.Duration) {
+	fs.StringVar(region, "region", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	fs.StringVar(profile, "profile", "", "AWS profile to use (defaults to default profile)")
+	fs.DurationVar(timeout, "timeout", defaultTimeout, "Maximum time for the whole command, e.g. '30s' or '10m' (0 disables)")
+	fs.IntVar(&retryOptions.maxRetries, "max-retries", 0, "Retry throttled or failed AWS requests up to this many times (default: the SDK default of 2, or AWS_MAX_ATTEMPTS)")
+	fs.Func("retry-mode", "AWS retry mode: standard, or adaptive to also slow down requests while throttled (default: AWS_RETRY_MODE or standard)", func(value string) error {
+		mode, err := aws.ParseRetryMode(value)
+		if err != nil {
+			return err
+		}
+		retryOptions.mode = mode
+		return nil
+	})
+	fs.Func("endpoint-url", "Send every AWS request to this endpoint, e.g. http://localhost:4566 for LocalStack (default: AWS_ENDPOINT_URL)", func(value string) error {
+		if err := parseEndpointURL(value); err != nil {
+			return err
+		}
+		endpointOptions.url = value
+		return nil
+	})
+	fs.Func("service-endpoint", "Endpoint for one service in format 'service=url', e.g. 'acm=https://vpce-123.acm.us-east-1.vpce.amazonaws.com' (may be repeated)", func(value string) error {
+		service, endpoint, ok := strings.Cut(value, "=")
+		if !ok || service == "" {
+			return fmt.Errorf("invalid service endpoint %q, expected service=url", value)
+		}
+		if err := parseEndpointURL(endpoint); err != nil {
+			return err
+		}
+		if endpointOptions.services == nil {
+			endpointOptions.services = serviceEndpoints{}
+		}
+		endpointOptions.services[strings.ReplaceAll(strings.ToLower(service), "-", "_")] = endpoint
+		return nil
+	})
+	fs.BoolVar(&endpointOptions.fips, "fips", false, "Use FIPS 140 validated endpoints for ACM and the other AWS services (US, Canada and GovCloud regions)")
+	addAssumeRoleFlags(fs)
+}
+
+// withTimeout limits ctx to timeout. A zero timeout only adds cancellation.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// loadAWSConfig loads the shared AWS configuration, optionally pinned to a
+// named profile and region. Empty values fall back to the SDK defaults.
+func loadAWSConfig(ctx context.Context, region, profile string) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+	}
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+
+	if retryOptions.maxRetries > 0 {
+		opts = append(opts, config.WithRetryMaxAttempts(retryOptions.maxRetries+1))
+	}
+	if retryOptions.mode != "" {
+		opts = append(opts, config.WithRetryMode(retryOptions.mode))
+	}
+	if endpointOptions.url != "" {
+		opts = append(opts, config.WithBaseEndpoint(endpointOptions.url))
+	}
+	if endpointOptions.fips {
+		opts = append(opts, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+
+	// Request and retry traces in verbose mode. Bodies are never logged since
+	// ImportCertificate requests carry the private key.
+	if progress.Verbose() {
+		opts = append(opts,
+			config.WithLogger(logging.NewStandardLogger(progress)),
+			config.WithClientLogMode(aws.LogRetries|aws.LogRequest|aws.LogResponse),
+		)
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	src := findCredentialSource(ctx, profile)
+	if ssoLogin(ctx, awsCfg, src) {
+		// The providers hold on to the expired token, so start afresh
+		if awsCfg, err = config.LoadDefaultConfig(ctx, opts...); err != nil {
+			return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+	}
+	awsCfg.Credentials = diagnosedCredentials{provider: awsCfg.Credentials, source: src}
+	if len(endpointOptions.services) > 0 {
+		// Ahead of the environment and shared config, which the flags override
+		awsCfg.ConfigSources = append([]interface{}{endpointOptions.services}, awsCfg.ConfigSources...)
+	}
+	limitImports(&awsCfg)
+	if creds := assumedCredentials(awsCfg); creds != nil {
+		awsCfg.Credentials = creds
+		progress.Debugf("Assuming role %s\n", assumeRole.roleArn)
+	}
+	progress.Debugf("Loaded AWS config (region: %s, profile: %s)\n", awsCfg.Region, profileName(profile))
+	return awsCfg, nil
+}
+
+func profileName(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return profile
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"gopkg.in/yaml.v3"
+)
+
+type BatchConfig struct {
+	Manifest    string
+	Concurrency int
+	Report      string
+	StateFile   string
+	Resume      bool
+	Notify      notifier
+	Hooks       hooks
+	Output      string
+	Region      string
+	Profile     string
+	Timeout     time.Duration
+}
+
+// manifest describes the certificates imported by import-batch. Defaults
+// apply to every entry; entries override scalar settings and add to the
+// default tags.
+type manifest struct {
+	Defaults     manifestEntry   `json:"defaults" yaml:"defaults"`
+	Certificates []manifestEntry `json:"certificates" yaml:"certificates"`
+}
+
+// manifestEntry is one certificate in a manifest. Inputs accept the same
+// forms as the import flags (paths, ssm://, s3:// and vault:// URIs); relative
+// paths are resolved against the manifest's directory.
+type manifestEntry struct {
+	Name              string            `json:"name" yaml:"name"`
+	Cert              string            `json:"cert" yaml:"cert"`
+	Key               string            `json:"key" yaml:"key"`
+	Chain             string            `json:"chain" yaml:"chain"`
+	Bundle            string            `json:"bundle" yaml:"bundle"`
+	PKCS12            string            `json:"pkcs12" yaml:"pkcs12"`
+	FromSecretDir     string            `json:"fromSecretDir" yaml:"fromSecretDir"`
+	PassphraseFile    string            `json:"passphraseFile" yaml:"passphraseFile"`
+	KeyPassphraseFile string            `json:"keyPassphraseFile" yaml:"keyPassphraseFile"`
+	CertSecret        string            `json:"certSecret" yaml:"certSecret"`
+	KeySecret         string            `json:"keySecret" yaml:"keySecret"`
+	ChainSecret       string            `json:"chainSecret" yaml:"chainSecret"`
+	KeyKMSEncrypted   *bool             `json:"keyKmsEncrypted" yaml:"keyKmsEncrypted"`
+	KMSKeyID          string            `json:"kmsKeyId" yaml:"kmsKeyId"`
+	CertificateArn    string            `json:"certificateArn" yaml:"certificateArn"`
+	Regions           []string          `json:"regions" yaml:"regions"`
+	Tags              map[string]string `json:"tags" yaml:"tags"`
+	PublishArn        string            `json:"publishArn" yaml:"publishArn"`
+	AutoTags          *bool             `json:"autoTags" yaml:"autoTags"`
+	FixChain          *bool             `json:"fixChain" yaml:"fixChain"`
+	FetchChain        *bool             `json:"fetchChain" yaml:"fetchChain"`
+	AllowExpired      *bool             `json:"allowExpired" yaml:"allowExpired"`
+	CheckRevocation   *bool             `json:"checkRevocation" yaml:"checkRevocation"`
+	AllowRevoked      *bool             `json:"allowRevoked" yaml:"allowRevoked"`
+	CheckCT           *bool             `json:"checkCT" yaml:"checkCT"`
+	Upsert            *bool             `json:"upsert" yaml:"upsert"`
+	Force             *bool             `json:"force" yaml:"force"`
+}
+
+// batchResult is the outcome of importing one manifest entry.
+type batchResult struct {
+	Name        string         `json:"name"`
+	DomainName  string         `json:"domainName,omitempty"`
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	NotAfter    *time.Time     `json:"notAfter,omitempty"`
+	Regions     []regionResult `json:"regions,omitempty"`
+	DryRun      bool           `json:"dryRun,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	ErrorCode   string         `json:"errorCode,omitempty"`
+
+	// err is the error behind Error, for callers that report it themselves
+	err error
+}
+
+// setError records err, with its remediation hint, and its error code.
+func (r *batchResult) setError(err error) {
+	info := describeError(err, false)
+	r.Error, r.ErrorCode, r.err = info.text(), info.Code, err
+}
+
+func (r batchResult) failed() bool {
+	if r.Error != "" {
+		return true
+	}
+	for _, region := range r.Regions {
+		if region.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func runImportBatch(ctx context.Context, args []string) error {
+	var cfg BatchConfig
+
+	fs := flag.NewFlagSet("import-batch", flag.ExitOnError)
+	fs.StringVar(&cfg.Manifest, "manifest", "", "YAML or JSON manifest listing the certificates to import - REQUIRED")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 4, "Number of certificates to import at the same time")
+	addRateFlags(fs)
+	fs.StringVar(&cfg.StateFile, "state", "", "File recording which certificates were imported into which regions, updated after each one (default: <manifest>.state.json, removed once all succeed)")
+	fs.BoolVar(&cfg.Resume, "resume", false, "Skip the certificates and regions the state file says an earlier, failed or interrupted run imported, unless the certificate changed since")
+	fs.StringVar(&cfg.Report, "report", "", "Write a JSON report with per-certificate ARNs and errors to this file")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text, json or cfn-params (CloudFormation parameter file)")
+	addNotifyFlags(fs, &cfg.Notify)
+	addHookFlags(fs, &cfg.Hooks)
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s import-batch -manifest <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Import many certificates described by a manifest. -timeout applies to each certificate.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\n%s", hooksHelp)
+		fmt.Fprintf(os.Stderr, "\nManifest:\n")
+		fmt.Fprintf(os.Stderr, "  defaults:\n")
+		fmt.Fprintf(os.Stderr, "    regions: [us-east-1, eu-west-1]\n")
+		fmt.Fprintf(os.Stderr, "    tags: {Team: web}\n")
+		fmt.Fprintf(os.Stderr, "    upsert: true\n")
+		fmt.Fprintf(os.Stderr, "  certificates:\n")
+		fmt.Fprintf(os.Stderr, "    - name: www\n")
+		fmt.Fprintf(os.Stderr, "      cert: certs/www.pem\n")
+		fmt.Fprintf(os.Stderr, "      key: certs/www.key\n")
+		fmt.Fprintf(os.Stderr, "      chain: certs/chain.pem\n")
+		fmt.Fprintf(os.Stderr, "    - name: api\n")
+		fmt.Fprintf(os.Stderr, "      certSecret: prod/api#cert\n")
+		fmt.Fprintf(os.Stderr, "      keySecret: prod/api#key\n")
+		fmt.Fprintf(os.Stderr, "      tags: {Application: api}\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s import-batch -manifest certs.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import-batch -manifest certs.json -concurrency 8 -report results.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import-batch -manifest certs.yaml -concurrency 16 -rps 5\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import-batch -manifest certs.yaml -resume\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	if cfg.Manifest == "" {
+		fmt.Fprintf(os.Stderr, "Error: -manifest is required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if cfg.Concurrency < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -concurrency must be at least 1\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if cfg.Output != "text" && cfg.Output != "json" && cfg.Output != "cfn-params" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text, json or cfn-params\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if err := cfg.Notify.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return importBatch(ctx, cfg)
+}
+
+func importBatch(ctx context.Context, cfg BatchConfig) error {
+	m, err := loadManifest(cfg.Manifest)
+	if err != nil {
+		return err
+	}
+
+	// Check every entry before importing anything
+	dir := filepath.Dir(cfg.Manifest)
+	entries := make([]CertImportConfig, len(m.Certificates))
+	names := map[string]bool{}
+	for i, entry := range m.Certificates {
+		if entry.Name == "" {
+			entry.Name = fmt.Sprintf("#%d", i+1)
+			m.Certificates[i].Name = entry.Name
+		}
+		if names[entry.Name] && cfg.Resume {
+			return fmt.Errorf("manifest entry name %s is used twice; -resume tells entries apart by name", entry.Name)
+		}
+		names[entry.Name] = true
+		entries[i], err = entry.importConfig(m.Defaults, dir, cfg)
+		if err != nil {
+			return fmt.Errorf("manifest entry %s: %w", entry.Name, err)
+		}
+	}
+
+	if cfg.StateFile == "" {
+		cfg.StateFile = defaultStatePath(cfg.Manifest)
+	}
+	state, err := openBatchState(cfg.StateFile, cfg.Manifest, cfg.Resume)
+	if err != nil {
+		return err
+	}
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := preflight(ctx, awsCfg, progress); err != nil {
+		return err
+	}
+	progress.Printf("Importing %d certificates (concurrency: %d, %s)...\n", len(entries), cfg.Concurrency, rateNote())
+
+	results := make([]batchResult, len(entries))
+	spin := progress.Spin("Importing certificates", len(entries))
+	runConcurrently(cfg.Concurrency, len(entries), func(i int) {
+		defer spin.Step()
+		results[i] = importEntry(ctx, awsCfg, m.Certificates[i].Name, entries[i], state)
+	})
+	spin.Stop()
+
+	if slices.ContainsFunc(results, batchResult.failed) {
+		progress.Printf("Progress saved to %s; run again with -resume to retry only what failed\n", cfg.StateFile)
+	} else {
+		state.remove()
+	}
+
+	if cfg.Report != "" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(cfg.Report, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("failed to write report %s: %w", cfg.Report, err)
+		}
+		progress.Printf("✓ Report written to %s\n", cfg.Report)
+	}
+
+	if err := printBatchResults(cfg.Output, results); err != nil {
+		return err
+	}
+
+	return batchSummary(results, "certificates")
+}
+
+// runConcurrently calls fn for every index below count, running at most
+// concurrency calls at the same time.
+func runConcurrently(concurrency, count int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// batchSummary prints how many of the results succeeded and returns an error
+// if any failed. what names the results, e.g. "certificates".
+func batchSummary(results []batchResult, what string) error {
+	failed := 0
+	for _, r := range results {
+		if r.failed() {
+			failed++
+		}
+	}
+	progress.Printf("Summary: %d succeeded, %d failed\n", len(results)-failed, failed)
+	if failed == len(results) && failed > 0 {
+		return fmt.Errorf("all %d %s failed to import", failed, what)
+	}
+	if failed > 0 {
+		return partialFailure(fmt.Errorf("%d of %d %s failed to import", failed, len(results), what))
+	}
+	return nil
+}
+
+// importEntry reads, validates and imports one manifest entry. Errors are
+// recorded in the result so that the rest of the batch carries on. With a
+// state, regions an earlier run imported the same certificate into are
+// skipped, and the result is recorded.
+func importEntry(ctx context.Context, awsCfg aws.Config, name string, cfg CertImportConfig, state *batchState) batchResult {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	logger := prefixLogger{prefix: "[" + name + "] ", progressLogger: progress}
+	if len(cfg.Regions) == 1 {
+		awsCfg = awsCfg.Copy()
+		awsCfg.Region = cfg.Regions[0]
+	}
+
+	material, err := loadMaterial(ctx, cfg, awsCfg, logger)
+	if err != nil {
+		result := batchResult{Name: name}
+		result.setError(err)
+		notifyImport(ctx, awsCfg, logger, cfg.Notify, result)
+		return result
+	}
+
+	var earlier batchResult
+	resumed := false
+	if state != nil {
+		earlier, cfg.Regions, resumed = state.resume(logger, name, cfg.Regions, awsCfg.Region, material.Leaf)
+		if resumed && len(cfg.Regions) == 0 {
+			return withEarlier(earlier, batchResult{Name: name})
+		}
+	}
+
+	result := importMaterial(ctx, awsCfg, logger, name, cfg, material)
+	notifyImport(ctx, awsCfg, logger, cfg.Notify, result)
+	if state != nil {
+		if err := state.record(result); err != nil {
+			progress.Warnf("%v", err)
+		}
+	}
+	if resumed {
+		result = withEarlier(earlier, result)
+	}
+	return result
+}
+
+// importMaterial validates and imports already loaded material using the
+// settings in cfg, recording errors in the result.
+func importMaterial(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, name string, cfg CertImportConfig, material *certimport.Material) batchResult {
+	out := batchResult{Name: name}
+
+	regions := cfg.Regions
+	if len(regions) == 1 {
+		awsCfg = awsCfg.Copy()
+		awsCfg.Region, regions = regions[0], nil
+	}
+
+	importer, prepared, err := prepareImport(ctx, awsCfg, logger, cfg, material, regions)
+	if err != nil {
+		out.DomainName = material.Leaf.Subject.CommonName
+		out.setError(err)
+		return out
+	}
+	defer prepared.Clear()
+
+	if err := cfg.Hooks.runPre(ctx, logger, name, prepared.Leaf, hookRegions(awsCfg, regions), cfg.CertificateArn, cfg.DryRun); err != nil {
+		out.DomainName = material.Leaf.Subject.CommonName
+		out.setError(err)
+		return out
+	}
+
+	// A dry run looks up what each region would do without importing
+	var result *certimport.Result
+	if cfg.DryRun {
+		result = importer.Plan(ctx, prepared, regions)
+		out.DryRun = true
+	} else {
+		result = importer.ImportPrepared(ctx, prepared, regions)
+	}
+	out.DomainName = result.DomainName
+	out.Fingerprint = result.Fingerprint
+	out.NotAfter = &result.NotAfter
+	out.Regions = newImportResult(result).Regions
+
+	if cfg.DryRun {
+		for i, r := range result.Regions {
+			if r.Err != nil {
+				out.Regions[i].setError(r.Err)
+			}
+		}
+		return out
+	}
+	if err := publishArns(ctx, awsCfg, logger, cfg.PublishArn, result); err != nil {
+		out.setError(err)
+	} else if err := cfg.Hooks.runPost(ctx, logger, prepared.Leaf, out); err != nil {
+		out.setError(err)
+	}
+	return out
+}
+
+func printBatchResults(format string, results []batchResult) error {
+	if format == "json" {
+		return printJSON(results)
+	}
+	if format == "cfn-params" {
+		return printCfnParams(results)
+	}
+
+	// Quiet mode prints one ARN per line for use in shell pipelines
+	if progress.Quiet() {
+		for _, r := range results {
+			for _, region := range r.Regions {
+				if region.CertificateArn != "" {
+					fmt.Println(region.CertificateArn)
+				}
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tDOMAIN\tREGION\tSTATUS\tARN / ERROR\n")
+	for _, r := range results {
+		if r.Error != "" && len(r.Regions) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t-\tfailed\t%s\n", r.Name, r.DomainName, r.Error)
+			continue
+		}
+		for _, region := range r.Regions {
+			status, detail := "imported", region.CertificateArn
+			switch {
+			case region.Error != "":
+				status, detail = "failed", region.Error
+			case region.Resumed:
+				status = "imported earlier"
+			case region.Existing:
+				status = "existing"
+			case region.Reimported && r.DryRun:
+				status = "would re-import"
+			case region.Reimported:
+				status = "re-imported"
+			case r.DryRun:
+				status, detail = "would import", "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, r.DomainName, region.Region, status, detail)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\t%s\t-\tfailed\t%s\n", r.Name, r.DomainName, r.Error)
+		}
+	}
+	return w.Flush()
+}
+
+func loadManifest(path string) (*manifest, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := decodeStrict(path, data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if len(m.Certificates) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no certificates", path)
+	}
+	return &m, nil
+}
+
+// decodeStrict decodes a JSON or YAML file, chosen by its extension, into v.
+// Unknown keys are refused so that a misspelt setting such as "upsrt" does
+// not silently import a duplicate.
+func decodeStrict(path string, data []byte, v interface{}) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(v)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(v); err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("%s must have a .json, .yaml or .yml extension", path)
+}
+
+// importConfig applies the manifest defaults to an entry and validates the
+// result like the import flags.
+func (e manifestEntry) importConfig(defaults manifestEntry, dir string, batch BatchConfig) (CertImportConfig, error) {
+	cfg := CertImportConfig{
+		CertFile:          resolvePath(dir, e.Cert),
+		PrivateKeyFile:    resolvePath(dir, e.Key),
+		ChainFile:         resolvePath(dir, firstSet(e.Chain, defaults.Chain)),
+		BundleFile:        resolvePath(dir, e.Bundle),
+		PKCS12File:        resolvePath(dir, e.PKCS12),
+		SecretDir:         resolvePath(dir, e.FromSecretDir),
+		PassphraseFile:    resolvePath(dir, firstSet(e.PassphraseFile, defaults.PassphraseFile)),
+		KeyPassphraseFile: resolvePath(dir, firstSet(e.KeyPassphraseFile, defaults.KeyPassphraseFile)),
+		NoPrompt:          true,
+		CertSecret:        e.CertSecret,
+		KeySecret:         e.KeySecret,
+		ChainSecret:       firstSet(e.ChainSecret, defaults.ChainSecret),
+		KeyKMSEncrypted:   boolSetting(e.KeyKMSEncrypted, defaults.KeyKMSEncrypted),
+		KMSKeyID:          firstSet(e.KMSKeyID, defaults.KMSKeyID),
+		CertificateArn:    e.CertificateArn,
+		Regions:           e.Regions,
+		Tags:              mergeTags(defaults.Tags, e.Tags),
+		AutoTags:          boolSetting(e.AutoTags, defaults.AutoTags),
+		FixChain:          boolSetting(e.FixChain, defaults.FixChain),
+		FetchChain:        boolSetting(e.FetchChain, defaults.FetchChain),
+		AllowExpired:      boolSetting(e.AllowExpired, defaults.AllowExpired),
+		CheckRevocation:   boolSetting(e.CheckRevocation, defaults.CheckRevocation),
+		AllowRevoked:      boolSetting(e.AllowRevoked, defaults.AllowRevoked),
+		CheckCT:           boolSetting(e.CheckCT, defaults.CheckCT),
+		Upsert:            boolSetting(e.Upsert, defaults.Upsert),
+		Force:             boolSetting(e.Force, defaults.Force),
+		Notify:            batch.Notify,
+		Hooks:             batch.Hooks,
+		Output:            batch.Output,
+		Timeout:           batch.Timeout,
+	}
+	if len(cfg.Regions) == 0 {
+		cfg.Regions = defaults.Regions
+	}
+
+	for _, path := range []string{cfg.CertFile, cfg.PrivateKeyFile, cfg.ChainFile, cfg.BundleFile, cfg.PKCS12File} {
+		if path == "-" {
+			return cfg, fmt.Errorf("stdin cannot be used in a manifest")
+		}
+	}
+
+	var err error
+	cfg.PublishArn, err = parsePublishTargets(firstSet(e.PublishArn, defaults.PublishArn))
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// resolvePath makes a relative manifest path relative to the manifest's
+// directory. URIs and absolute paths are returned unchanged.
+func resolvePath(dir, path string) string {
+	if path == "" || path == "-" || strings.Contains(path, "://") || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func boolSetting(value, fallback *bool) bool {
+	if value != nil {
+		return *value
+	}
+	return fallback != nil && *fallback
+}
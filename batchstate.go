@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// batchState records which manifest entries import-batch has imported into
+// which regions, so that an interrupted or partly failed run can be resumed
+// without importing them again. It is saved after every entry.
+type batchState struct {
+	Manifest  string                 `json:"manifest"`
+	UpdatedAt time.Time              `json:"updatedAt"`
+	Entries   map[string]batchResult `json:"entries"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// defaultStatePath is where the state of a manifest is kept unless -state
+// says otherwise.
+func defaultStatePath(manifest string) string {
+	return manifest + ".state.json"
+}
+
+// openBatchState reads the state at path when resuming, or starts a new
+// one. A state left by another manifest is refused.
+func openBatchState(path, manifest string, resume bool) (*batchState, error) {
+	abs, err := filepath.Abs(manifest)
+	if err != nil {
+		return nil, err
+	}
+	state := &batchState{Manifest: abs, Entries: map[string]batchResult{}, path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if resume {
+			progress.Warnf("No state file %s to resume from; importing every certificate", path)
+		}
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+	if !resume {
+		progress.Warnf("Starting afresh and discarding the state file %s left by an earlier run (use -resume to skip what it imported)", path)
+		state.remove()
+		return state, nil
+	}
+
+	var saved batchState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	if saved.Manifest != abs {
+		return nil, fmt.Errorf("state file %s belongs to manifest %s, not %s", path, saved.Manifest, abs)
+	}
+	if saved.Entries != nil {
+		state.Entries = saved.Entries
+	}
+	progress.Printf("✓ Resuming from %s (%d certificates imported by earlier runs, last updated %s)\n", path, len(state.Entries), saved.UpdatedAt.Local().Format(time.RFC3339))
+	return state, nil
+}
+
+// done returns the earlier result of the entry name, holding the regions it
+// was imported into.
+func (s *batchState) done(name string) (batchResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.Entries[name]
+	return r, ok
+}
+
+// resume returns the earlier result of the entry name and the regions that
+// are left to import leaf into: those earlier runs did not import it into.
+// When the certificate changed since, e.g. because it was renewed, the
+// earlier imports are stale, so every region is left and resumed is false.
+// defaultRegion stands in for an entry without regions.
+func (s *batchState) resume(logger certimport.Logger, name string, regions []string, defaultRegion string, leaf *x509.Certificate) (earlier batchResult, remaining []string, resumed bool) {
+	earlier, ok := s.done(name)
+	if !ok {
+		return earlier, regions, false
+	}
+	if fingerprint := certimport.Fingerprint(leaf); earlier.Fingerprint != fingerprint {
+		logger.Warnf("The certificate changed since it was imported by an earlier run (SHA-256 %s, now %s), importing it into every region again", earlier.Fingerprint, fingerprint)
+		return earlier, regions, false
+	}
+	if len(regions) == 0 {
+		regions = []string{defaultRegion}
+	}
+	for _, region := range regions {
+		if !slices.ContainsFunc(earlier.Regions, func(r regionResult) bool { return r.Region == region }) {
+			remaining = append(remaining, region)
+		}
+	}
+	return earlier, remaining, true
+}
+
+// record adds the regions result was imported into and saves the state.
+func (s *batchState) record(result batchResult) error {
+	if result.DryRun {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The regions of a certificate that has since changed are not kept
+	entry, ok := s.Entries[result.Name]
+	if !ok || entry.Fingerprint != result.Fingerprint {
+		entry = batchResult{Name: result.Name}
+	}
+	entry.DomainName = result.DomainName
+	entry.Fingerprint = result.Fingerprint
+	entry.NotAfter = result.NotAfter
+	added := false
+	for _, region := range result.Regions {
+		if region.Error == "" && !region.Resumed {
+			entry.Regions = append(entry.Regions, region)
+			added = true
+		}
+	}
+	if !added {
+		return nil
+	}
+	s.Entries[result.Name] = entry
+	s.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write and rename, so that an interrupted run never leaves half a file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", s.path, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// withEarlier adds the regions an earlier run imported the entry into to
+// the result of this run.
+func withEarlier(earlier, result batchResult) batchResult {
+	regions := make([]regionResult, 0, len(earlier.Regions)+len(result.Regions))
+	for _, region := range earlier.Regions {
+		region.Resumed = true
+		regions = append(regions, region)
+	}
+	result.Regions = append(regions, result.Regions...)
+	if result.DomainName == "" {
+		result.DomainName = earlier.DomainName
+		result.Fingerprint = earlier.Fingerprint
+		result.NotAfter = earlier.NotAfter
+	}
+	return result
+}
+
+// remove deletes the state once every entry has been imported.
+func (s *batchState) remove() {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		progress.Warnf("Could not remove state file %s: %v", s.path, err)
+	}
+}
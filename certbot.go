@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// defaultLiveDir is where certbot keeps the current files of each lineage.
+const defaultLiveDir = "/etc/letsencrypt/live"
+
+// lineageTag names the tag recording which certbot lineage a certificate was
+// imported from. Re-imports look the certificate up by this tag, so its ARN
+// survives "certbot --expand" and other changes to the names.
+const lineageTag = "CertbotLineage"
+
+type CertbotConfig struct {
+	LiveDir    string
+	Lineages   []string
+	Regions    []string
+	AllRegions bool
+	Tags       map[string]string
+	AutoTags   bool
+	DryRun     bool
+	Notify     notifier
+	Hooks      hooks
+	Output     string
+	Region     string
+	Profile    string
+	Timeout    time.Duration
+}
+
+func runCertbot(ctx context.Context, args []string) error {
+	var cfg CertbotConfig
+	var lineages, regions, tagString, tagsFile string
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("certbot", flag.ExitOnError)
+	fs.StringVar(&cfg.LiveDir, "live-dir", defaultLiveDir, "certbot live directory holding one directory per lineage")
+	fs.StringVar(&lineages, "lineage", "", "Only import these lineages, e.g. 'example.com,api.example.com' (default: all, or $RENEWED_LINEAGE in a deploy hook)")
+	fs.StringVar(&regions, "regions", "", "Import into several regions concurrently, e.g. 'us-east-1,eu-west-1'")
+	fs.BoolVar(&cfg.AllRegions, "all-regions", false, "Import into every region enabled for the account")
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.StringVar(&tagsFile, "tags-file", "", "JSON or YAML file with a map of tag keys to values")
+	fs.BoolVar(&cfg.AutoTags, "auto-tags", false, "Add tags describing the certificate and the import (Domain, Issuer, NotAfter, ImportedBy, ...)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Validate every lineage and show what would be imported or replaced without importing")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text, json or cfn-params (CloudFormation parameter file)")
+	addNotifyFlags(fs, &cfg.Notify)
+	addHookFlags(fs, &cfg.Hooks)
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s certbot [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Import or re-import certbot lineages (cert.pem, privkey.pem, chain.pem) into ACM.\n")
+		fmt.Fprintf(os.Stderr, "Certificates are tagged %s=<lineage> and each lineage replaces the certificate\n", lineageTag)
+		fmt.Fprintf(os.Stderr, "with its tag (or, the first time, the one with the same domain and SANs), so ARNs\n")
+		fmt.Fprintf(os.Stderr, "stay stable across renewals and SAN changes. As a deploy hook only $RENEWED_LINEAGE\n")
+		fmt.Fprintf(os.Stderr, "is imported.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\n%s", hooksHelp)
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s certbot -regions us-east-1,eu-west-1\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s certbot -lineage example.com -tag Environment=prod\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  certbot renew --deploy-hook '%s certbot -regions us-east-1 -quiet'\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	if cfg.Output != "text" && cfg.Output != "json" && cfg.Output != "cfn-params" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text, json or cfn-params\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Regions = cliutil.SplitList(regions)
+	if len(cfg.Regions) > 0 && cfg.AllRegions {
+		fmt.Fprintf(os.Stderr, "Error: -regions and -all-regions are mutually exclusive\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	var fileTags, inlineTags map[string]string
+	if tagsFile != "" {
+		var err error
+		fileTags, err = loadTagsFile(tagsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Tags = mergeTags(fileTags, inlineTags, tagList)
+	if err := certimport.ValidateTags(cfg.Tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if err := cfg.Notify.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	// certbot runs deploy hooks once per renewed lineage with its path set
+	cfg.Lineages = cliutil.SplitList(lineages)
+	if len(cfg.Lineages) == 0 {
+		if renewed := os.Getenv("RENEWED_LINEAGE"); renewed != "" {
+			cfg.LiveDir, cfg.Lineages = filepath.Dir(renewed), []string{filepath.Base(renewed)}
+		}
+	}
+
+	return importLineages(ctx, cfg)
+}
+
+func importLineages(ctx context.Context, cfg CertbotConfig) error {
+	lineages := cfg.Lineages
+	if len(lineages) == 0 {
+		var err error
+		lineages, err = findLineages(cfg.LiveDir)
+		if err != nil {
+			return err
+		}
+	}
+	if len(lineages) == 0 {
+		return fmt.Errorf("no certbot lineages found in %s", cfg.LiveDir)
+	}
+
+	regions := cfg.Regions
+	if cfg.AllRegions {
+		var err error
+		regions, err = enabledRegions(ctx, cfg.Region, cfg.Profile)
+		if err != nil {
+			return err
+		}
+	}
+
+	progress.Printf("✓ Found %d certbot lineages in %s\n", len(lineages), cfg.LiveDir)
+	entries := make([]CertImportConfig, len(lineages))
+	for i, name := range lineages {
+		dir := filepath.Join(cfg.LiveDir, name)
+		lineage := map[string]string{lineageTag: certimport.SanitizeTagValue(name)}
+		entries[i] = CertImportConfig{
+			CertFile:       filepath.Join(dir, "cert.pem"),
+			PrivateKeyFile: filepath.Join(dir, "privkey.pem"),
+			Regions:        regions,
+			Tags:           mergeTags(cfg.Tags, lineage),
+			AutoTags:       cfg.AutoTags,
+			Upsert:         true,
+			UpsertTags:     lineage,
+			NoPrompt:       true,
+			DryRun:         cfg.DryRun,
+			Notify:         cfg.Notify,
+			Hooks:          cfg.Hooks,
+			Timeout:        cfg.Timeout,
+		}
+		if _, err := os.Stat(filepath.Join(dir, "chain.pem")); err == nil {
+			entries[i].ChainFile = filepath.Join(dir, "chain.pem")
+		}
+		progress.Printf("  %s: %s\n", name, dir)
+	}
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := preflight(ctx, awsCfg, progress); err != nil {
+		return err
+	}
+
+	var results []batchResult
+	spin := progress.Spin("Importing lineages", len(lineages))
+	for i, name := range lineages {
+		results = append(results, importEntry(ctx, awsCfg, name, entries[i], nil))
+		spin.Step()
+	}
+	spin.Stop()
+
+	if err := printBatchResults(cfg.Output, results); err != nil {
+		return err
+	}
+	if err := batchSummary(results, "certificates"); err != nil {
+		return err
+	}
+	if cfg.DryRun {
+		progress.Printf("✅ Dry run complete, no changes made\n")
+	}
+	return nil
+}
+
+// findLineages returns the lineage directories in a certbot live directory,
+// i.e. those containing a cert.pem.
+func findLineages(liveDir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(liveDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certbot live directory: %w", err)
+	}
+
+	var lineages []string
+	for _, entry := range dirEntries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(liveDir, entry.Name(), "cert.pem")); err == nil {
+			lineages = append(lineages, entry.Name())
+		}
+	}
+	sort.Strings(lineages)
+	return lineages, nil
+}
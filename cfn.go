@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// cfnParameter is an entry of a CloudFormation parameter file, as accepted
+// by aws cloudformation create-stack --parameters file://params.json.
+type cfnParameter struct {
+	ParameterKey   string `json:"ParameterKey"`
+	ParameterValue string `json:"ParameterValue"`
+}
+
+// printCfnParams prints the ARNs of the imported certificates as a
+// CloudFormation parameter file. Each certificate is named after its batch
+// entry, e.g. WwwExampleComCertificateArn, or CertificateArn for a single
+// import, with the region appended when it was imported into several.
+func printCfnParams(results []batchResult) error {
+	params := []cfnParameter{}
+	for _, r := range results {
+		for _, region := range r.Regions {
+			if region.CertificateArn == "" {
+				continue
+			}
+			key := cfnName(r.Name) + "CertificateArn"
+			if len(r.Regions) > 1 {
+				key += cfnName(region.Region)
+			}
+			params = append(params, cfnParameter{ParameterKey: key, ParameterValue: region.CertificateArn})
+		}
+	}
+	return printJSON(params)
+}
+
+// cfnName turns a name into the alphanumeric PascalCase that CloudFormation
+// allows in parameter names, e.g. eu-west-1 into EuWest1.
+func cfnName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cftypes "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+)
+
+// cloudFrontRegion is the only region CloudFront reads ACM certificates from.
+const cloudFrontRegion = "us-east-1"
+
+// cloudFrontUpdateAttempts bounds the retries when another change to the
+// distribution lands between reading its config and updating it.
+const cloudFrontUpdateAttempts = 3
+
+type AttachCloudFrontConfig struct {
+	DistributionIDs []string
+	CertificateArn  string
+	Wait            bool
+	Profile         string
+	Region          string
+	Timeout         time.Duration
+}
+
+func runAttachCloudFront(ctx context.Context, args []string) error {
+	var cfg AttachCloudFrontConfig
+	var distributions string
+
+	fs := flag.NewFlagSet("attach-cloudfront", flag.ExitOnError)
+	fs.StringVar(&distributions, "distribution-id", "", "ID of the CloudFront distribution (comma-separated for several) - REQUIRED")
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to use, which must be in us-east-1 - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.BoolVar(&cfg.Wait, "wait", false, "Wait until the distribution is deployed")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s attach-cloudfront -distribution-id <id> -arn <arn> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Point a CloudFront distribution's viewer certificate at an ACM certificate.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s attach-cloudfront -distribution-id E1ABCDEF2GHIJK -arn arn:aws:acm:us-east-1:123456789012:certificate/abc\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -region us-east-1 -attach-distribution E1ABCDEF2GHIJK\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cloudFrontRegion, cfg.Profile); err != nil {
+		return err
+	}
+
+	cfg.DistributionIDs = cliutil.SplitList(distributions)
+	if len(cfg.DistributionIDs) == 0 || cfg.CertificateArn == "" {
+		fmt.Fprintf(os.Stderr, "Error: -distribution-id and -arn are required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if region, err := arnRegion(cfg.CertificateArn); err != nil || region != cloudFrontRegion {
+		fmt.Fprintf(os.Stderr, "Error: CloudFront only uses certificates in %s\n\n", cloudFrontRegion)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	for _, id := range cfg.DistributionIDs {
+		if err := attachDistribution(ctx, awsCfg, id, cfg.CertificateArn, cfg.Wait); err != nil {
+			return err
+		}
+	}
+
+	if !progress.Quiet() {
+		stdout.Printf("✅ Certificate attached to %d distribution(s)\n", len(cfg.DistributionIDs))
+	}
+	return nil
+}
+
+// attachDistribution sets the viewer certificate of a distribution to
+// certificateArn, keeping its SSL support method and minimum protocol
+// version. It is a no-op when the distribution already uses the certificate.
+func attachDistribution(ctx context.Context, awsCfg aws.Config, id, certificateArn string, wait bool) error {
+	client := cloudfront.NewFromConfig(awsCfg)
+
+	for attempt := 1; ; attempt++ {
+		current, err := client.GetDistributionConfig(ctx, &cloudfront.GetDistributionConfigInput{Id: aws.String(id)})
+		if err != nil {
+			return fmt.Errorf("failed to get config of distribution %s: %w", id, err)
+		}
+
+		config := current.DistributionConfig
+		viewer := config.ViewerCertificate
+		if viewer != nil && aws.ToString(viewer.ACMCertificateArn) == certificateArn {
+			progress.Printf("✓ Distribution %s already uses %s\n", id, certificateArn)
+			return nil
+		}
+
+		updated := &cftypes.ViewerCertificate{
+			ACMCertificateArn:            aws.String(certificateArn),
+			CloudFrontDefaultCertificate: aws.Bool(false),
+			SSLSupportMethod:             cftypes.SSLSupportMethodSniOnly,
+			MinimumProtocolVersion:       cftypes.MinimumProtocolVersionTLSv122021,
+		}
+		if viewer != nil && !aws.ToBool(viewer.CloudFrontDefaultCertificate) {
+			updated.SSLSupportMethod = viewer.SSLSupportMethod
+			updated.MinimumProtocolVersion = viewer.MinimumProtocolVersion
+		}
+		config.ViewerCertificate = updated
+
+		// The ETag makes the update fail if the distribution changed since
+		// it was read, in which case it is read again
+		_, err = client.UpdateDistribution(ctx, &cloudfront.UpdateDistributionInput{
+			Id:                 aws.String(id),
+			IfMatch:            current.ETag,
+			DistributionConfig: config,
+		})
+		var conflict *cftypes.PreconditionFailed
+		if errors.As(err, &conflict) && attempt < cloudFrontUpdateAttempts {
+			progress.Debugf("Distribution %s changed while updating, retrying\n", id)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update distribution %s: %w", id, err)
+		}
+		break
+	}
+	progress.Printf("✓ Distribution %s now uses %s\n", id, certificateArn)
+
+	if wait {
+		progress.Printf("Waiting for distribution %s to deploy...\n", id)
+		maxWait := 30 * time.Minute
+		if deadline, ok := ctx.Deadline(); ok {
+			maxWait = time.Until(deadline)
+		}
+		err := cloudfront.NewDistributionDeployedWaiter(client).Wait(ctx, &cloudfront.GetDistributionInput{Id: aws.String(id)}, maxWait)
+		if err != nil {
+			return fmt.Errorf("distribution %s did not finish deploying: %w", id, err)
+		}
+		progress.Printf("✓ Distribution %s deployed\n", id)
+	}
+	return nil
+}
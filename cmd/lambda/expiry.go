@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/inventory"
+)
+
+// modeCheckExpiry is the MODE in which the function checks expiry dates
+// instead of importing.
+const modeCheckExpiry = "check-expiry"
+
+// expiringCertificate is a certificate found by the expiry check, as
+// reported by aws-certs check-expiry.
+type expiringCertificate = inventory.ExpiringCertificate
+
+// handleExpiry checks the certificates of REGIONS and publishes an alert
+// listing those expiring within DAYS to ALERT_TOPIC_ARN. Finding expiring
+// certificates does not fail the invocation, so that Lambda does not retry
+// it and send the alert again.
+func handleExpiry(ctx context.Context, _ json.RawMessage) ([]expiringCertificate, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	days := 30
+	if value := os.Getenv("DAYS"); value != "" {
+		if days, err = strconv.Atoi(value); err != nil || days < 1 {
+			return nil, fmt.Errorf("invalid DAYS %q, expected a positive number", value)
+		}
+	}
+	certType := strings.ToUpper(os.Getenv("TYPE"))
+	if err := inventory.CheckType(certType); err != nil {
+		return nil, fmt.Errorf("invalid TYPE: %w", err)
+	}
+	regions := cliutil.SplitList(os.Getenv("REGIONS"))
+	if len(regions) == 0 {
+		regions = []string{awsCfg.Region}
+	}
+
+	expiring, checked, err := inventory.CheckExpiry(ctx, regions, func(_ context.Context, region string) (*acm.Client, error) {
+		return acm.NewFromConfig(awsCfg, func(o *acm.Options) { o.Region = region }), nil
+	}, inventory.Filter{Type: certType}, days)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(expiring) == 0 {
+		log.Printf("✓ No certificates expire within %d days (%d checked)", days, checked)
+		return expiring, nil
+	}
+	log.Printf("⚠ %d of %d certificates expire within %d days", len(expiring), checked, days)
+	if topic := os.Getenv("ALERT_TOPIC_ARN"); topic != "" {
+		if err := publishAlert(ctx, awsCfg, topic, days, expiring); err != nil {
+			return expiring, err
+		}
+		log.Printf("✓ Alert published to %s", topic)
+	}
+	return expiring, nil
+}
+
+// publishAlert publishes the expiring certificates to an SNS topic, as text
+// for email subscribers.
+func publishAlert(ctx context.Context, awsCfg aws.Config, topicArn string, days int, expiring []expiringCertificate) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d certificates expire within %d days:\n\n", len(expiring), days)
+	for _, c := range expiring {
+		fmt.Fprintf(&b, "%s (%s, %s)\n  expires %s, in %d days%s\n  %s\n\n",
+			c.DomainName, c.Type, c.Region, c.NotAfter.Format("2006-01-02"), c.DaysUntilExpiry, inUseNote(c.InUse), c.CertificateArn)
+	}
+	b.WriteString("Imported certificates are not renewed by ACM: re-import them with aws-certs import -certificate-arn <arn>.\n")
+
+	client := sns.NewFromConfig(awsCfg, func(o *sns.Options) {
+		// The topic may be in another region than the function
+		if parts := strings.Split(topicArn, ":"); len(parts) > 3 && parts[3] != "" {
+			o.Region = parts[3]
+		}
+	})
+	_, err := client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		// SNS limits subjects to 100 characters
+		Subject: aws.String(fmt.Sprintf("%d certificates expire within %d days", len(expiring), days)),
+		Message: aws.String(b.String()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish the alert to %s: %w", topicArn, err)
+	}
+	return nil
+}
+
+func inUseNote(inUse bool) string {
+	if inUse {
+		return ", in use"
+	}
+	return ""
+}
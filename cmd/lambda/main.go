@@ -0,0 +1,259 @@
+// Command lambda runs the importer as an AWS Lambda function. It imports PEM
+// bundles (certificate, chain and private key in one object, such as
+// fullchain.pem followed by privkey.pem) from S3:
+//
+//   - when invoked by an S3 object created notification, directly or through
+//     EventBridge, the uploaded object is imported;
+//   - when invoked by an EventBridge schedule, every object in SOURCES is
+//     imported, so that renewals uploaded without a notification are picked
+//     up too.
+//
+// Each certificate replaces the imported certificate with the same names, so
+// that its ARN stays the same across renewals, and identical certificates
+// are not imported again. The function is configured with environment
+// variables:
+//
+//	MODE          'check-expiry' to check expiry dates instead of importing (see below)
+//	SOURCES       comma-separated s3://bucket/key bundles for scheduled runs
+//	REGIONS       comma-separated regions to import into (default: the function's region)
+//	TAGS          tags in format 'key1=value1,key2=value2'
+//	FIX_CHAIN     'true' to strip the root CA and unrelated certificates from the chain
+//	FETCH_CHAIN   'true' to download missing intermediates
+//
+// With MODE=check-expiry, as deployed by aws-certs deploy-monitor, every
+// invocation lists the certificates of REGIONS instead and publishes the
+// ones expiring soon to an SNS topic:
+//
+//	DAYS             alert on certificates expiring within this many days (default: 30)
+//	TYPE             only check certificates of this type, e.g. IMPORTED
+//	ALERT_TOPIC_ARN  the SNS topic to publish alerts to
+//
+// Build it for the provided.al2023 runtime with:
+//
+//	GOOS=linux GOARCH=arm64 go build -tags lambda.norpc -o bootstrap ./cmd/lambda
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/s3object"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// object is an S3 object to import.
+type object struct {
+	Region string
+	Bucket string
+	Key    string
+}
+
+func (o object) String() string {
+	return "s3://" + o.Bucket + "/" + o.Key
+}
+
+// importResult is the outcome of importing one object, returned to the
+// caller of the function.
+type importResult struct {
+	Source      string            `json:"source"`
+	DomainName  string            `json:"domainName,omitempty"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	Arns        map[string]string `json:"certificateArns,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// logger sends the importer's progress messages to CloudWatch Logs.
+type logger struct{}
+
+func (logger) Printf(format string, v ...interface{}) { log.Printf(format, v...) }
+func (logger) Debugf(format string, v ...interface{}) {}
+func (logger) Warnf(format string, v ...interface{})  { log.Printf("Warning: "+format, v...) }
+
+func main() {
+	log.SetFlags(0)
+	if os.Getenv("MODE") == modeCheckExpiry {
+		lambda.Start(handleExpiry)
+		return
+	}
+	lambda.Start(handle)
+}
+
+func handle(ctx context.Context, event json.RawMessage) ([]importResult, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	opts, err := importOptions()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := eventObjects(event, awsCfg.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	importer := certimport.New(awsCfg, certimport.WithLogger(logger{}))
+	var results []importResult
+	failed := 0
+	for _, obj := range objects {
+		result := importObject(ctx, awsCfg, importer, obj, opts)
+		if result.Error != "" {
+			log.Printf("✗ %s: %s", obj, result.Error)
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	// Failing the invocation lets Lambda retry it and report it in its metrics
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d imports failed", failed, len(objects))
+	}
+	return results, nil
+}
+
+// eventObjects returns the objects an invocation is about: the object of an
+// S3 notification or an EventBridge "Object Created" event, or SOURCES for
+// any other event, such as a schedule.
+func eventObjects(event json.RawMessage, region string) ([]object, error) {
+	var s3Event events.S3Event
+	if err := json.Unmarshal(event, &s3Event); err == nil && len(s3Event.Records) > 0 {
+		var objects []object
+		for _, r := range s3Event.Records {
+			// Keys in notifications are URL-encoded
+			key, err := url.QueryUnescape(r.S3.Object.Key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid object key %q: %w", r.S3.Object.Key, err)
+			}
+			objects = append(objects, object{Region: r.AWSRegion, Bucket: r.S3.Bucket.Name, Key: key})
+		}
+		return objects, nil
+	}
+
+	var ebEvent events.CloudWatchEvent
+	if err := json.Unmarshal(event, &ebEvent); err == nil && ebEvent.Source == "aws.s3" && ebEvent.DetailType == "Object Created" {
+		var detail struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		}
+		if err := json.Unmarshal(ebEvent.Detail, &detail); err != nil {
+			return nil, fmt.Errorf("invalid Object Created event: %w", err)
+		}
+		return []object{{Region: ebEvent.Region, Bucket: detail.Bucket.Name, Key: detail.Object.Key}}, nil
+	}
+
+	var objects []object
+	for _, uri := range cliutil.SplitList(os.Getenv("SOURCES")) {
+		bucket, key, err := s3object.SplitURI(uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOURCES: %w", err)
+		}
+		objects = append(objects, object{Region: region, Bucket: bucket, Key: key})
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("the event is not an S3 object notification and SOURCES is not set")
+	}
+	return objects, nil
+}
+
+// importObject downloads and imports one bundle.
+func importObject(ctx context.Context, awsCfg aws.Config, importer *certimport.Importer, obj object, opts certimport.ImportOptions) importResult {
+	result := importResult{Source: obj.String()}
+
+	data, err := s3object.Read(ctx, awsCfg, logger{}, obj.Region, obj.Bucket, obj.Key)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	material, err := certimport.LoadBundle(data, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	prepared, err := importer.Prepare(ctx, material, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer prepared.Clear()
+	imported := importer.ImportPrepared(ctx, prepared, opts.Regions)
+	result.DomainName = imported.DomainName
+	result.Fingerprint = imported.Fingerprint
+	result.Arns = map[string]string{}
+	var errs []string
+	for _, r := range imported.Regions {
+		if r.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Region, r.Err))
+			continue
+		}
+		result.Arns[r.Region] = r.CertificateArn
+		switch {
+		case r.Existing:
+			log.Printf("✓ %s: %s already imported as %s", obj, imported.DomainName, r.CertificateArn)
+		case r.Reimported:
+			log.Printf("✓ %s: %s re-imported as %s", obj, imported.DomainName, r.CertificateArn)
+		default:
+			log.Printf("✓ %s: %s imported as %s", obj, imported.DomainName, r.CertificateArn)
+		}
+	}
+	result.Error = strings.Join(errs, "; ")
+	return result
+}
+
+// importOptions reads the import settings from the environment.
+func importOptions() (certimport.ImportOptions, error) {
+	opts := certimport.ImportOptions{
+		Regions: cliutil.SplitList(os.Getenv("REGIONS")),
+		Upsert:  true,
+		Tags:    map[string]string{},
+		// The CLI's defaults, so that the function accepts the same keys
+		Policy: certimport.KeyPolicy{MinRSABits: certimport.DefaultMinRSABits},
+	}
+	for _, pair := range cliutil.SplitList(os.Getenv("TAGS")) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return opts, fmt.Errorf("invalid tag %q in TAGS, expected key=value", pair)
+		}
+		opts.Tags[key] = value
+	}
+	if err := certimport.ValidateTags(opts.Tags); err != nil {
+		return opts, err
+	}
+
+	var err error
+	if opts.FixChain, err = envBool("FIX_CHAIN"); err != nil {
+		return opts, err
+	}
+	if opts.FetchChain, err = envBool("FETCH_CHAIN"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func envBool(name string) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q, expected true or false", name, value)
+	}
+	return b, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultConfigFile is read from the home directory when -config is not
+// given. It is optional.
+const defaultConfigFile = ".aws-certs.yaml"
+
+// configFile holds flag defaults. Each section maps flag names (without the
+// dash) to values; lists become comma-separated values and maps become
+// 'key=value' pairs, so "tags: {Team: web}" sets -tags Team=web.
+type configFile struct {
+	// Defaults apply to every command that has the flag.
+	Defaults map[string]interface{} `json:"defaults" yaml:"defaults"`
+	// Commands apply to one command, e.g. commands.import.upsert.
+	Commands map[string]map[string]interface{} `json:"commands" yaml:"commands"`
+	// Profiles are named sets of flags for recurring imports, selected with
+	// -cert-profile.
+	Profiles map[string]map[string]interface{} `json:"profiles" yaml:"profiles"`
+}
+
+// envPrefix starts the environment variables that set flags, e.g.
+// AWS_CERTS_REGIONS for -regions.
+const envPrefix = "AWS_CERTS_"
+
+// parseFlags parses args and fills in the flags that were not given from
+// AWS_CERTS_* environment variables and the config file. Precedence, highest
+// first: command line, environment, -cert-profile, commands.<name>,
+// defaults.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	var configPath, profile string
+	fs.StringVar(&configPath, "config", "", "Config file with flag defaults (default: ~/"+defaultConfigFile+" if it exists)")
+	fs.StringVar(&profile, "cert-profile", "", "Apply the named profile from the config file")
+	fs.Parse(args)
+
+	err := applyEnv(fs)
+	if err == nil {
+		err = applyConfig(fs, configPath, profile)
+	}
+	if err == nil {
+		err = applyOutputTemplate(fs)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if output := fs.Lookup("output"); output != nil && output.Value.String() == "json" {
+		jsonErrors = true
+	}
+}
+
+// envName returns the environment variable for a flag.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets the flags that were not given on the command line from
+// their environment variables. Variables set to an empty string are ignored.
+func applyEnv(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value := os.Getenv(envName(f.Name))
+		if err != nil || set[f.Name] || value == "" {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+func applyConfig(fs *flag.FlagSet, path, profile string) error {
+	explicit := path != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, defaultConfigFile)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		if profile != "" {
+			return fmt.Errorf("-cert-profile %s needs a config file", profile)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg configFile
+	if err := decodeStrict(path, data, &cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	progress.Debugf("Loaded config file %s\n", path)
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var profileValues map[string]interface{}
+	if profile != "" {
+		var ok bool
+		if profileValues, ok = cfg.Profiles[profile]; !ok {
+			return fmt.Errorf("profile %q not found in %s", profile, path)
+		}
+	}
+
+	// Apply the most specific section first; flags it sets are then skipped
+	// by the more general ones
+	if err := applySection(fs, set, profileValues, true); err != nil {
+		return fmt.Errorf("%s: profiles.%s: %w", path, profile, err)
+	}
+	if err := applySection(fs, set, cfg.Commands[fs.Name()], true); err != nil {
+		return fmt.Errorf("%s: commands.%s: %w", path, fs.Name(), err)
+	}
+	if err := applySection(fs, set, cfg.Defaults, false); err != nil {
+		return fmt.Errorf("%s: defaults: %w", path, err)
+	}
+	return nil
+}
+
+// applySection sets the flags in values that are not in set yet. Unknown
+// flags are errors in command specific sections and skipped otherwise,
+// since defaults are shared by commands with different flags.
+func applySection(fs *flag.FlagSet, set map[string]bool, values map[string]interface{}, strict bool) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "config" || name == "cert-profile" {
+			return fmt.Errorf("%s cannot be set in the config file", name)
+		}
+		if fs.Lookup(name) == nil {
+			if strict {
+				return fmt.Errorf("unknown flag %q for %s", name, fs.Name())
+			}
+			continue
+		}
+		if set[name] {
+			continue
+		}
+		value, err := configValue(values[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		set[name] = true
+	}
+	return nil
+}
+
+// configValue converts a YAML or JSON value to its flag form.
+func configValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(v))
+		for _, key := range keys {
+			s, err := configValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, key+"="+s)
+		}
+		return strings.Join(parts, ","), nil
+	case nil:
+		return "", errors.New("value is empty")
+	}
+	return fmt.Sprint(v), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// errNotConfirmed is returned when a deletion is declined at the prompt.
+var errNotConfirmed = errors.New("cancelled, nothing was deleted")
+
+// addYesFlag registers -yes, which skips the confirmation of commands that
+// delete certificates.
+func addYesFlag(fs *flag.FlagSet, yes *bool) {
+	fs.BoolVar(yes, "yes", false, "Delete without asking for confirmation; required when stdin is not a terminal, e.g. in scripts and CI")
+}
+
+// confirmDeletion shows what is about to be deleted and asks whether to go
+// ahead, unless yes is set. Without a terminal to ask on it refuses, so that
+// scripts state their intent with -yes.
+func confirmDeletion(yes bool, what string, items []string) error {
+	if yes {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return &exitCodeError{code: exitUsage, err: fmt.Errorf("refusing to delete %s without confirmation: use -yes when stdin is not a terminal", what)}
+	}
+
+	fmt.Fprintf(os.Stderr, "\nAbout to delete %s:\n", what)
+	for _, item := range items {
+		fmt.Fprintf(os.Stderr, "  - %s\n", item)
+	}
+	w := &wizard{in: bufio.NewReader(os.Stdin)}
+	ok, err := w.confirm("Delete?", false)
+	if errors.Is(err, errCancelled) || err == nil && !ok {
+		return errNotConfirmed
+	}
+	return err
+}
+
+// deletionItem describes a certificate in a confirmation prompt.
+func deletionItem(certificateArn, domainName string, notAfter *time.Time, inUseBy []string) string {
+	var details []string
+	if domainName != "" {
+		details = append(details, domainName)
+	}
+	if notAfter != nil {
+		details = append(details, "expires "+notAfter.Format("2006-01-02"))
+	}
+	if len(inUseBy) > 0 {
+		details = append(details, fmt.Sprintf("in use by %d resource(s)", len(inUseBy)))
+	}
+	if len(details) == 0 {
+		return certificateArn
+	}
+	return fmt.Sprintf("%s (%s)", certificateArn, strings.Join(details, ", "))
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/inventory"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type CopyConfig struct {
+	CertificateArn        string
+	ToRegions             []string
+	SourceRoleArn         string
+	SourceExternalID      string
+	DestinationRoleArn    string
+	DestinationExternalID string
+	KeyFile               string
+	KeyEnv                string
+	KeySecret             string
+	KeyData               string
+	KeyPassphrase         string
+	KeyPassphraseFile     string
+	KeyKMSEncrypted       bool
+	KMSKeyID              string
+	Tags                  map[string]string
+	AllowExpired          bool
+	DryRun                bool
+	Output                string
+	Region                string
+	Profile               string
+	Timeout               time.Duration
+}
+
+// copyResult is the outcome of copying a certificate: the source ARN and
+// the copies made of it in each region. The accounts are recorded when the
+// copy crosses accounts.
+type copyResult struct {
+	SourceArn          string         `json:"sourceArn"`
+	SourceAccount      string         `json:"sourceAccount,omitempty"`
+	DestinationAccount string         `json:"destinationAccount,omitempty"`
+	DomainName         string         `json:"domainName,omitempty"`
+	NotAfter           *time.Time     `json:"notAfter,omitempty"`
+	Regions            []regionResult `json:"regions,omitempty"`
+	DryRun             bool           `json:"dryRun,omitempty"`
+	Error              string         `json:"error,omitempty"`
+}
+
+func runCopy(ctx context.Context, args []string) error {
+	var cfg CopyConfig
+	var toRegions, tagString string
+
+	fs := flag.NewFlagSet("copy", flag.ExitOnError)
+	fs.StringVar(&toRegions, "to-region", "", "Region (or comma-separated regions) to copy the certificate to - REQUIRED")
+	addCopyFlags(fs, &cfg, &tagString)
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s copy -arn <arn> -to-region <region> -key <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Copy an imported certificate to other regions. ACM only returns the certificate and\n")
+		fmt.Fprintf(os.Stderr, "chain, so the private key comes from -key, -key-env or -key-secret. The copies get the\n")
+		fmt.Fprintf(os.Stderr, "tags of the original and a CopiedFrom tag. The source region is taken from -arn.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s copy -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -to-region eu-central-1 -key key.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s copy -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -to-region eu-west-1,ap-southeast-2 -key-secret prod/tls#key\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if err := cfg.validate(tagString); err != nil {
+		fail(err)
+	}
+	cfg.ToRegions = cliutil.SplitList(toRegions)
+	if len(cfg.ToRegions) == 0 {
+		fail(errors.New("-to-region is required"))
+	}
+	if slices.Contains(cfg.ToRegions, cfg.Region) {
+		fail(fmt.Errorf("the certificate is already in %s", cfg.Region))
+	}
+
+	return copyCertificate(ctx, cfg)
+}
+
+// addCopyFlags registers the flags shared by copy and copy-account: the
+// source certificate, its private key and the options of the import.
+func addCopyFlags(fs *flag.FlagSet, cfg *CopyConfig, tagString *string) {
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to copy - REQUIRED")
+	fs.StringVar(&cfg.KeyFile, "key", "", "Path to the certificate's private key (PEM or DER format), - for stdin, or an ssm://, s3:// or vault:// URI")
+	fs.StringVar(&cfg.KeyEnv, "key-env", "", "Environment variable holding the private key, instead of -key")
+	fs.StringVar(&cfg.KeySecret, "key-secret", "", "Secrets Manager secret name or ARN holding the private key ('name#jsonKey' selects a JSON field)")
+	fs.StringVar(&cfg.KeyData, "key-data", "", "Base64-encoded private key, instead of -key (visible to other local users in the process list; prefer -key-env for secrets)")
+	fs.StringVar(&cfg.KeyPassphrase, "key-passphrase", "", "Passphrase for an encrypted private key (prompted for if omitted)")
+	fs.StringVar(&cfg.KeyPassphraseFile, "key-passphrase-file", "", "File containing the passphrase for an encrypted private key")
+	fs.BoolVar(&cfg.KeyKMSEncrypted, "key-kms-encrypted", false, "The private key input is a KMS ciphertext, decrypted with -kms-key-id")
+	fs.StringVar(&cfg.KMSKeyID, "kms-key-id", "", "ID, ARN or alias of the KMS key that encrypted the private key, for -key-kms-encrypted")
+	fs.StringVar(tagString, "tags", "", "Extra tags for the copies in format 'key1=value1,key2=value2', overriding the copied ones")
+	fs.BoolVar(&cfg.AllowExpired, "allow-expired", false, "Copy an expired certificate too")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Show what would be imported without importing")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+}
+
+// validate checks the flags registered by addCopyFlags and takes the source
+// region from the ARN.
+func (cfg *CopyConfig) validate(tagString string) error {
+	if cfg.CertificateArn == "" {
+		return errors.New("-arn is required")
+	}
+	region, err := arnRegion(cfg.CertificateArn)
+	if err != nil {
+		return err
+	}
+	cfg.Region = region
+	if !cfg.key().IsSet() {
+		return errors.New("the private key is required: use -key, -key-env, -key-secret or -key-data")
+	}
+	if countSet(cfg.KeyFile, cfg.KeyEnv, cfg.KeySecret, cfg.KeyData) > 1 {
+		return errors.New("use only one of -key, -key-env, -key-secret and -key-data")
+	}
+	if cfg.KeyKMSEncrypted != (cfg.KMSKeyID != "") {
+		return errors.New("-key-kms-encrypted and -kms-key-id must be used together")
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		return errors.New("-output must be text or json")
+	}
+	cfg.Tags = parseTags(tagString)
+	return certimport.ValidateTags(cfg.Tags)
+}
+
+// key is the input the private key is read from.
+func (cfg CopyConfig) key() input {
+	return input{Path: cfg.KeyFile, Env: cfg.KeyEnv, Secret: cfg.KeySecret, Data: cfg.KeyData, KMSKeyID: cfg.KMSKeyID}
+}
+
+// copyCertificate reads the certificate with the source role, if any, and
+// imports it with the destination role.
+func copyCertificate(ctx context.Context, cfg CopyConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	base, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	sourceCfg, err := targetConfig(ctx, base, cfg.Region, importTarget{RoleArn: cfg.SourceRoleArn, ExternalID: cfg.SourceExternalID})
+	if err != nil {
+		return err
+	}
+	destinationCfg, err := targetConfig(ctx, base, cfg.Region, importTarget{RoleArn: cfg.DestinationRoleArn, ExternalID: cfg.DestinationExternalID})
+	if err != nil {
+		return err
+	}
+	account, err := preflight(ctx, destinationCfg, progress)
+	if err != nil {
+		return err
+	}
+
+	material, tags, err := readCopySource(ctx, sourceCfg, cfg.CertificateArn, cfg.key(), keyPassphraseFunc(cfg.KeyPassphrase, cfg.KeyPassphraseFile))
+	if err != nil {
+		return err
+	}
+
+	result := copyMaterial(ctx, destinationCfg, cfg.CertificateArn, CertImportConfig{
+		Regions:      cfg.ToRegions,
+		Tags:         mergeTags(tags, cfg.Tags, map[string]string{"CopiedFrom": cfg.CertificateArn}),
+		AllowExpired: cfg.AllowExpired,
+		DryRun:       cfg.DryRun,
+		Output:       cfg.Output,
+		Timeout:      cfg.Timeout,
+	}, material)
+	if sourceAccount := arnAccount(cfg.CertificateArn); sourceAccount != account {
+		result.SourceAccount, result.DestinationAccount = sourceAccount, account
+	}
+
+	if err := printCopyResults(cfg.Output, []copyResult{result}); err != nil {
+		return err
+	}
+	return copyError(result)
+}
+
+// readCopySource downloads the certificate and chain at arn, pairs them with
+// the private key and returns them with the certificate's tags. Tags with the
+// reserved aws: prefix are left out, since they cannot be set on an import.
+func readCopySource(ctx context.Context, awsCfg aws.Config, arn string, key input, passphrase certimport.PassphraseFunc) (*certimport.Material, map[string]string, error) {
+	client := acm.NewFromConfig(awsCfg)
+
+	progress.Printf("Downloading %s...\n", arn)
+	out, err := client.GetCertificate(ctx, &acm.GetCertificateInput{CertificateArn: aws.String(arn)})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get certificate: %w", err)
+	}
+	tags, err := inventory.Tags(ctx, client, arn)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name := range tags {
+		if strings.HasPrefix(name, "aws:") {
+			delete(tags, name)
+		}
+	}
+
+	keyPEM, err := key.read(ctx, awsCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(keyPEM)
+
+	material, err := certimport.LoadPEM([]byte(aws.ToString(out.Certificate)), keyPEM, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	if chain := aws.ToString(out.CertificateChain); chain != "" {
+		if _, err := material.AddChain([]byte(chain)); err != nil {
+			return nil, nil, err
+		}
+	}
+	progress.Printf("✓ Certificate read (subject: %s, %d chain certificates, %d tags)\n", material.Leaf.Subject, len(material.Chain), len(tags))
+	return material, tags, nil
+}
+
+// copyMaterial imports material into the regions of cfg like import would.
+func copyMaterial(ctx context.Context, awsCfg aws.Config, sourceArn string, cfg CertImportConfig, material *certimport.Material) copyResult {
+	result := importMaterial(ctx, awsCfg, progress, sourceArn, cfg, material)
+	return copyResult{
+		SourceArn:  sourceArn,
+		DomainName: result.DomainName,
+		NotAfter:   result.NotAfter,
+		Regions:    result.Regions,
+		DryRun:     result.DryRun,
+		Error:      result.Error,
+	}
+}
+
+func printCopyResults(format string, results []copyResult) error {
+	if format == "json" {
+		return printJSON(results)
+	}
+	if progress.Quiet() {
+		for _, r := range results {
+			for _, region := range r.Regions {
+				if region.CertificateArn != "" {
+					fmt.Println(region.CertificateArn)
+				}
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "SOURCE\tDOMAIN\tREGION\tSTATUS\tARN / ERROR\n")
+	for _, r := range results {
+		if r.Error != "" && len(r.Regions) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t-\tfailed\t%s\n", r.SourceArn, r.DomainName, r.Error)
+			continue
+		}
+		for _, region := range r.Regions {
+			status, detail := "copied", region.CertificateArn
+			switch {
+			case region.Error != "":
+				status, detail = "failed", region.Error
+			case region.Existing:
+				status = "existing"
+			case r.DryRun:
+				status, detail = "would copy", "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.SourceArn, r.DomainName, region.Region, status, detail)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\t%s\t-\tfailed\t%s\n", r.SourceArn, r.DomainName, r.Error)
+		}
+	}
+	return w.Flush()
+}
+
+// copyError returns an error if the copy failed in any region, and a partial
+// failure if it still succeeded in some.
+func copyError(r copyResult) error {
+	failed := 0
+	for _, region := range r.Regions {
+		if region.Error != "" {
+			failed++
+		}
+	}
+	switch {
+	case r.Error != "" && len(r.Regions) == 0:
+		return fmt.Errorf("failed to copy %s: %s", r.SourceArn, r.Error)
+	case failed > 0 && failed == len(r.Regions):
+		return fmt.Errorf("failed to copy %s to any region", r.SourceArn)
+	case failed > 0 || r.Error != "":
+		return partialFailure(fmt.Errorf("%s was copied to %d of %d regions", r.SourceArn, len(r.Regions)-failed, len(r.Regions)))
+	}
+	return nil
+}
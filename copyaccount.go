@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+)
+
+func runCopyAccount(ctx context.Context, args []string) error {
+	var cfg CopyConfig
+	var toRegions, tagString string
+
+	fs := flag.NewFlagSet("copy-account", flag.ExitOnError)
+	fs.StringVar(&cfg.SourceRoleArn, "source-role-arn", "", "Role to read the certificate with (default: the base credentials)")
+	fs.StringVar(&cfg.SourceExternalID, "source-external-id", "", "External ID required by the source role's trust policy")
+	fs.StringVar(&cfg.DestinationRoleArn, "destination-role-arn", "", "Role to import the copy with (default: the base credentials)")
+	fs.StringVar(&cfg.DestinationExternalID, "destination-external-id", "", "External ID required by the destination role's trust policy")
+	fs.StringVar(&toRegions, "to-region", "", "Region (or comma-separated regions) to import the copy into (default: the region of -arn)")
+	addCopyFlags(fs, &cfg, &tagString)
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s copy-account -arn <arn> -destination-role-arn <role> -key <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Copy an imported certificate to another account. The certificate, chain and tags are read\n")
+		fmt.Fprintf(os.Stderr, "with -source-role-arn and imported with -destination-role-arn; either may be left out to use\n")
+		fmt.Fprintf(os.Stderr, "the base credentials. The private key is read with the source role. The output maps the\n")
+		fmt.Fprintf(os.Stderr, "source ARN and account to the ARNs in the destination account. -expect-account checks the\n")
+		fmt.Fprintf(os.Stderr, "destination account.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s copy-account -arn arn:aws:acm:us-east-1:111111111111:certificate/abc -destination-role-arn arn:aws:iam::222222222222:role/CertImporter -key key.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s copy-account -arn arn:aws:acm:us-east-1:111111111111:certificate/abc -source-role-arn arn:aws:iam::111111111111:role/CertReader -destination-role-arn arn:aws:iam::222222222222:role/CertImporter -key-secret prod/tls#key -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if err := cfg.validate(tagString); err != nil {
+		fail(err)
+	}
+	if cfg.SourceRoleArn == "" && cfg.DestinationRoleArn == "" {
+		fail(errors.New("-source-role-arn or -destination-role-arn is required (use copy within an account)"))
+	}
+	if cfg.SourceExternalID != "" && cfg.SourceRoleArn == "" || cfg.DestinationExternalID != "" && cfg.DestinationRoleArn == "" {
+		fail(errors.New("-source-external-id and -destination-external-id require the matching role ARN"))
+	}
+	cfg.ToRegions = cliutil.SplitList(toRegions)
+	if len(cfg.ToRegions) == 0 {
+		cfg.ToRegions = []string{cfg.Region}
+	}
+
+	return copyCertificate(ctx, cfg)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"golang.org/x/term"
+)
+
+// credentialSource describes where the SDK gets credentials from, so that a
+// failure to get them can be explained in terms of what the user set up.
+type credentialSource struct {
+	// Kind is environment, sso, web-identity, process, assume-role,
+	// shared-credentials or default
+	Kind    string
+	Profile string
+	Detail  string
+}
+
+// findCredentialSource works out which provider the SDK's default chain will
+// use for profile, in the same order as the SDK.
+func findCredentialSource(ctx context.Context, profile string) credentialSource {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	src := credentialSource{Kind: "default", Profile: profileName(profile)}
+
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" && profile == "" {
+		src.Kind = "environment"
+		return src
+	}
+	if file := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); file != "" && profile == "" {
+		src.Kind, src.Detail = "web-identity", file
+		return src
+	}
+
+	shared, err := config.LoadSharedConfigProfile(ctx, src.Profile)
+	if err != nil {
+		return src
+	}
+	switch {
+	case shared.Credentials.HasKeys():
+		src.Kind = "shared-credentials"
+	case shared.SSOSessionName != "" || shared.SSOStartURL != "":
+		src.Kind, src.Detail = "sso", shared.SSOStartURL
+		if shared.SSOSession != nil {
+			src.Detail = shared.SSOSession.SSOStartURL
+		}
+	case shared.WebIdentityTokenFile != "":
+		src.Kind, src.Detail = "web-identity", shared.WebIdentityTokenFile
+	case shared.CredentialProcess != "":
+		src.Kind, src.Detail = "process", shared.CredentialProcess
+	case shared.RoleARN != "":
+		src.Kind, src.Detail = "assume-role", shared.RoleARN
+	}
+	return src
+}
+
+// diagnosedCredentials explains credential failures with the source that
+// failed and what usually fixes it, instead of the SDK's generic chain of
+// errors.
+type diagnosedCredentials struct {
+	provider aws.CredentialsProvider
+	source   credentialSource
+}
+
+func (d diagnosedCredentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := d.provider.Retrieve(ctx)
+	if err != nil {
+		return creds, &exitCodeError{code: exitAuth, err: d.source.explain(err)}
+	}
+	return creds, nil
+}
+
+func (src credentialSource) explain(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	switch src.Kind {
+	case "environment":
+		return fmt.Errorf("AWS credentials from AWS_ACCESS_KEY_ID were rejected (check AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN): %w", err)
+	case "sso":
+		return fmt.Errorf("the SSO session of profile %s (%s) has expired or was never started; run 'aws sso login --profile %s': %w", src.Profile, src.Detail, src.Profile, err)
+	case "web-identity":
+		return fmt.Errorf("web identity credentials from %s failed; on EKS check that the service account is annotated with eks.amazonaws.com/role-arn and that the role trusts the cluster's OIDC provider: %w", src.Detail, err)
+	case "process":
+		return fmt.Errorf("credential_process %q of profile %s failed; run it by hand to see its output: %w", src.Detail, src.Profile, err)
+	case "assume-role":
+		return fmt.Errorf("profile %s could not assume %s (check its source_profile or credential_source and the role's trust policy): %w", src.Profile, src.Detail, err)
+	case "shared-credentials":
+		return fmt.Errorf("the access keys of profile %s were rejected: %w", src.Profile, err)
+	}
+	return fmt.Errorf("no AWS credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, use -profile, or run with an instance, container or IRSA role: %w", err)
+}
+
+// ssoLogin starts 'aws sso login' for an SSO profile whose credentials
+// cannot be retrieved, when someone is at the terminal to complete it. It
+// reports whether the login succeeded, in which case the configuration has
+// to be loaded again to pick up the new token.
+func ssoLogin(ctx context.Context, awsCfg aws.Config, src credentialSource) bool {
+	if src.Kind != "sso" || !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return false
+	}
+	if _, err := awsCfg.Credentials.Retrieve(ctx); err == nil {
+		return false
+	}
+	if _, err := exec.LookPath("aws"); err != nil {
+		return false
+	}
+
+	progress.Printf("The SSO session of profile %s has expired, starting 'aws sso login'...\n", src.Profile)
+	cmd := exec.CommandContext(ctx, "aws", "sso", "login", "--profile", src.Profile)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		progress.Warnf("aws sso login failed: %v", err)
+		return false
+	}
+	return true
+}
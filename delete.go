@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+)
+
+type DeleteConfig struct {
+	CertificateArn string
+	Force          bool
+	Yes            bool
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+func runDelete(ctx context.Context, args []string) error {
+	var cfg DeleteConfig
+
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to delete - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.BoolVar(&cfg.Force, "force", false, "Attempt deletion even if the certificate is in use")
+	addYesFlag(fs, &cfg.Yes)
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s delete -arn <arn> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Delete a certificate from AWS Certificate Manager\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	if cfg.CertificateArn == "" {
+		fmt.Fprintf(os.Stderr, "Error: -arn is required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return deleteCertificate(ctx, cfg)
+}
+
+func deleteCertificate(ctx context.Context, cfg DeleteConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	client := acm.NewFromConfig(awsCfg)
+
+	// Check whether the certificate is still associated with other resources
+	detail, err := describeCertificate(ctx, client, cfg.CertificateArn)
+	if err != nil {
+		return err
+	}
+
+	if len(detail.InUseBy) > 0 {
+		progress.Printf("Certificate is in use by %d resource(s):\n", len(detail.InUseBy))
+		for _, resource := range detail.InUseBy {
+			progress.Printf("  - %s\n", resource)
+		}
+		if !cfg.Force {
+			return fmt.Errorf("refusing to delete certificate %s while it is in use (use -force to override)", cfg.CertificateArn)
+		}
+		progress.Warnf("Continuing because -force was given")
+	}
+
+	item := deletionItem(cfg.CertificateArn, aws.ToString(detail.DomainName), detail.NotAfter, detail.InUseBy)
+	if err := confirmDeletion(cfg.Yes, "this certificate", []string{item}); err != nil {
+		return err
+	}
+
+	progress.Printf("Deleting certificate %s...\n", cfg.CertificateArn)
+
+	_, err = client.DeleteCertificate(ctx, &acm.DeleteCertificateInput{
+		CertificateArn: aws.String(cfg.CertificateArn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete certificate: %w", err)
+	}
+
+	if !progress.Quiet() {
+		stdout.Printf("✅ Certificate deleted successfully!\n")
+	}
+	return nil
+}
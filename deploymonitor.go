@@ -0,0 +1,496 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/s3object"
+)
+
+type DeployMonitorConfig struct {
+	StackName     string
+	Code          string
+	CodeBucket    string
+	Architecture  string
+	Schedule      string
+	Days          int
+	Type          string
+	Regions       []string
+	AlertTopicArn string
+	Emails        []string
+	PrintTemplate bool
+	Output        string
+	Region        string
+	Profile       string
+	Timeout       time.Duration
+}
+
+// monitorResult is the outcome of deploy-monitor.
+type monitorResult struct {
+	StackName     string   `json:"stackName"`
+	StackId       string   `json:"stackId"`
+	Status        string   `json:"status"`
+	FunctionArn   string   `json:"functionArn,omitempty"`
+	AlertTopicArn string   `json:"alertTopicArn,omitempty"`
+	Schedule      string   `json:"schedule"`
+	Days          int      `json:"days"`
+	Type          string   `json:"type,omitempty"`
+	Regions       []string `json:"regions"`
+	Emails        []string `json:"emails,omitempty"`
+}
+
+// monitorCodePrefix is the key prefix of the Lambda packages uploaded to
+// -code-bucket. Keys end with a hash of the package so that deploying a new
+// build changes the key, which is what makes CloudFormation update the code.
+const monitorCodePrefix = "aws-certs-monitor/"
+
+// stackUnchanged is the status reported when re-deploying changes nothing.
+const stackUnchanged = "UNCHANGED"
+
+func runDeployMonitor(ctx context.Context, args []string) error {
+	var cfg DeployMonitorConfig
+	var regions, emails string
+
+	fs := flag.NewFlagSet("deploy-monitor", flag.ExitOnError)
+	fs.StringVar(&cfg.Code, "code", "", "The Lambda package built from ./cmd/lambda: a local function.zip, or s3://bucket/key in -region - REQUIRED")
+	fs.StringVar(&cfg.CodeBucket, "code-bucket", "", "S3 bucket in -region to upload a local -code package to")
+	fs.StringVar(&cfg.StackName, "stack-name", "aws-certs-monitor", "CloudFormation stack to create or update")
+	fs.StringVar(&cfg.Architecture, "arch", "arm64", "Architecture the package was built for: arm64 or x86_64")
+	fs.StringVar(&cfg.Schedule, "schedule", "rate(1 day)", "EventBridge schedule expression for the check, e.g. 'cron(0 8 * * ? *)'")
+	fs.IntVar(&cfg.Days, "days", 30, "Alert on certificates expiring within this many days")
+	fs.StringVar(&cfg.Type, "type", "IMPORTED", "Only check certificates of this type (IMPORTED, AMAZON_ISSUED, PRIVATE), or ALL")
+	fs.StringVar(&regions, "regions", "", "Check these regions, e.g. 'us-east-1,eu-west-1' (default: -region)")
+	fs.StringVar(&cfg.AlertTopicArn, "alert-topic-arn", "", "Publish alerts to this existing SNS topic instead of creating one")
+	fs.StringVar(&emails, "email", "", "Subscribe these email addresses to the alerts (comma-separated)")
+	fs.BoolVar(&cfg.PrintTemplate, "print-template", false, "Print the CloudFormation template instead of deploying it, e.g. to review it or deploy it with other tools")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s deploy-monitor -code <function.zip|s3://bucket/key> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Deploy a CloudFormation stack that runs check-expiry on a schedule: the Lambda function of\n")
+		fmt.Fprintf(os.Stderr, "./cmd/lambda in check-expiry mode, its IAM role, an EventBridge rule and an SNS topic for\n")
+		fmt.Fprintf(os.Stderr, "the alerts. ACM does not renew imported certificates, so by default only they are checked.\n")
+		fmt.Fprintf(os.Stderr, "Running it again updates the stack, e.g. after building a new package.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  GOOS=linux GOARCH=arm64 go build -tags lambda.norpc -o bootstrap ./cmd/lambda && zip function.zip bootstrap\n")
+		fmt.Fprintf(os.Stderr, "  %s deploy-monitor -code function.zip -code-bucket my-artifacts -email ops@example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s deploy-monitor -code s3://my-artifacts/function.zip -regions us-east-1,eu-west-1 -days 14 \\\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "      -alert-topic-arn arn:aws:sns:us-east-1:123456789012:ops-alerts\n")
+		fmt.Fprintf(os.Stderr, "  %s deploy-monitor -print-template -email ops@example.com > monitor.json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	cfg.Regions = cliutil.SplitList(regions)
+	cfg.Emails = cliutil.SplitList(emails)
+	cfg.Type = strings.ToUpper(cfg.Type)
+	if err := cfg.validate(); err != nil {
+		fail(err)
+	}
+
+	if cfg.PrintTemplate {
+		return printMonitorTemplate(cfg)
+	}
+	return deployMonitor(ctx, cfg)
+}
+
+func (cfg *DeployMonitorConfig) validate() error {
+	switch {
+	case cfg.Code == "" && !cfg.PrintTemplate:
+		return errors.New("-code is required")
+	case cfg.Code != "" && cfg.PrintTemplate:
+		return errors.New("-code cannot be combined with -print-template, the template takes the package as parameters")
+	case strings.HasPrefix(cfg.Code, "s3://") && cfg.CodeBucket != "":
+		return errors.New("-code-bucket only applies to a local -code package")
+	case cfg.Code != "" && !strings.HasPrefix(cfg.Code, "s3://") && cfg.CodeBucket == "":
+		return errors.New("-code-bucket is required to upload a local -code package, since Lambda reads packages from S3")
+	case cfg.Architecture != "arm64" && cfg.Architecture != "x86_64":
+		return errors.New("-arch must be arm64 or x86_64")
+	case !strings.HasPrefix(cfg.Schedule, "rate(") && !strings.HasPrefix(cfg.Schedule, "cron("):
+		return fmt.Errorf("invalid -schedule %q, expected rate(...) or cron(...)", cfg.Schedule)
+	case cfg.Days < 1:
+		return errors.New("-days must be at least 1")
+	case cfg.AlertTopicArn != "" && !strings.HasPrefix(cfg.AlertTopicArn, "arn:"):
+		return fmt.Errorf("invalid -alert-topic-arn %q, expected an SNS topic ARN", cfg.AlertTopicArn)
+	case cfg.Output != "text" && cfg.Output != "json":
+		return errors.New("-output must be text or json")
+	}
+	if strings.HasPrefix(cfg.Code, "s3://") {
+		if _, _, err := s3object.SplitURI(cfg.Code); err != nil {
+			return err
+		}
+	}
+	switch cfg.Type {
+	case "ALL":
+		cfg.Type = ""
+	case "IMPORTED", "AMAZON_ISSUED", "PRIVATE":
+	default:
+		return fmt.Errorf("invalid -type %q, expected IMPORTED, AMAZON_ISSUED, PRIVATE or ALL", cfg.Type)
+	}
+	for _, email := range cfg.Emails {
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("invalid -email %q", email)
+		}
+	}
+	return nil
+}
+
+func deployMonitor(ctx context.Context, cfg DeployMonitorConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := preflight(ctx, awsCfg, progress); err != nil {
+		return err
+	}
+
+	bucket, key, err := monitorCode(ctx, awsCfg, cfg)
+	if err != nil {
+		return err
+	}
+
+	template, err := json.Marshal(monitorTemplate(cfg))
+	if err != nil {
+		return err
+	}
+	params := []cfntypes.Parameter{
+		{ParameterKey: aws.String("CodeS3Bucket"), ParameterValue: aws.String(bucket)},
+		{ParameterKey: aws.String("CodeS3Key"), ParameterValue: aws.String(key)},
+	}
+
+	client := cloudformation.NewFromConfig(awsCfg)
+	stack, err := deployStack(ctx, client, cfg.StackName, string(template), params)
+	if err != nil {
+		return err
+	}
+
+	result := monitorResult{
+		StackName:     cfg.StackName,
+		StackId:       aws.ToString(stack.StackId),
+		Status:        string(stack.StackStatus),
+		AlertTopicArn: cfg.AlertTopicArn,
+		Schedule:      cfg.Schedule,
+		Days:          cfg.Days,
+		Type:          cfg.Type,
+		Regions:       cfg.Regions,
+		Emails:        cfg.Emails,
+	}
+	if len(result.Regions) == 0 {
+		result.Regions = []string{awsCfg.Region}
+	}
+	for _, output := range stack.Outputs {
+		switch aws.ToString(output.OutputKey) {
+		case "FunctionArn":
+			result.FunctionArn = aws.ToString(output.OutputValue)
+		case "AlertTopicArn":
+			result.AlertTopicArn = aws.ToString(output.OutputValue)
+		}
+	}
+	return printMonitor(cfg.Output, result)
+}
+
+// monitorCode returns the S3 location of the Lambda package, uploading a
+// local -code package to -code-bucket first.
+func monitorCode(ctx context.Context, awsCfg aws.Config, cfg DeployMonitorConfig) (string, string, error) {
+	if strings.HasPrefix(cfg.Code, "s3://") {
+		return s3object.SplitURI(cfg.Code)
+	}
+
+	data, err := os.ReadFile(cfg.Code)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the Lambda package: %w", err)
+	}
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		return "", "", fmt.Errorf("%s is not a zip file, package the bootstrap binary with: zip function.zip bootstrap", cfg.Code)
+	}
+	sum := sha256.Sum256(data)
+	key := monitorCodePrefix + hex.EncodeToString(sum[:8]) + "-" + filepath.Base(cfg.Code)
+
+	_, err = s3.NewFromConfig(awsCfg).PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(cfg.CodeBucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to upload %s to s3://%s/%s: %w", cfg.Code, cfg.CodeBucket, key, err)
+	}
+	progress.Printf("✓ Uploaded %s to s3://%s/%s\n", cfg.Code, cfg.CodeBucket, key)
+	return cfg.CodeBucket, key, nil
+}
+
+// deployStack creates the stack, or updates it if it exists, and waits for
+// CloudFormation to finish. It returns the stack as deployed.
+func deployStack(ctx context.Context, client *cloudformation.Client, name, template string, params []cfntypes.Parameter) (cfntypes.Stack, error) {
+	describe := &cloudformation.DescribeStacksInput{StackName: aws.String(name)}
+	capabilities := []cfntypes.Capability{cfntypes.CapabilityCapabilityIam}
+
+	exists := true
+	if _, err := client.DescribeStacks(ctx, describe); err != nil {
+		if !stackNotFound(err) {
+			return cfntypes.Stack{}, fmt.Errorf("failed to describe stack %s: %w", name, err)
+		}
+		exists = false
+	}
+
+	unchanged := false
+	if exists {
+		progress.Printf("Updating stack %s...\n", name)
+		_, err := client.UpdateStack(ctx, &cloudformation.UpdateStackInput{
+			StackName:    aws.String(name),
+			TemplateBody: aws.String(template),
+			Parameters:   params,
+			Capabilities: capabilities,
+		})
+		switch {
+		case noStackUpdates(err):
+			unchanged = true
+		case err != nil:
+			return cfntypes.Stack{}, fmt.Errorf("failed to update stack %s: %w", name, err)
+		default:
+			if err := cloudformation.NewStackUpdateCompleteWaiter(client).Wait(ctx, describe, stackWaitTime); err != nil {
+				return cfntypes.Stack{}, stackFailure(ctx, client, name, err)
+			}
+		}
+	} else {
+		progress.Printf("Creating stack %s...\n", name)
+		_, err := client.CreateStack(ctx, &cloudformation.CreateStackInput{
+			StackName:    aws.String(name),
+			TemplateBody: aws.String(template),
+			Parameters:   params,
+			Capabilities: capabilities,
+			Tags:         []cfntypes.Tag{{Key: aws.String("CreatedBy"), Value: aws.String("aws-certs")}},
+		})
+		if err != nil {
+			return cfntypes.Stack{}, fmt.Errorf("failed to create stack %s: %w", name, err)
+		}
+		if err := cloudformation.NewStackCreateCompleteWaiter(client).Wait(ctx, describe, stackWaitTime); err != nil {
+			return cfntypes.Stack{}, stackFailure(ctx, client, name, err)
+		}
+	}
+
+	output, err := client.DescribeStacks(ctx, describe)
+	if err != nil {
+		return cfntypes.Stack{}, fmt.Errorf("failed to describe stack %s: %w", name, err)
+	}
+	if len(output.Stacks) == 0 {
+		return cfntypes.Stack{}, fmt.Errorf("stack %s not found", name)
+	}
+	stack := output.Stacks[0]
+	if unchanged {
+		stack.StackStatus = stackUnchanged
+	}
+	return stack, nil
+}
+
+// stackWaitTime bounds the wait for a stack operation; -timeout applies too.
+const stackWaitTime = 15 * time.Minute
+
+// stackNotFound reports whether err is DescribeStacks failing for a stack
+// that does not exist, which CloudFormation reports as a ValidationError.
+func stackNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "does not exist")
+}
+
+// noStackUpdates reports whether err is UpdateStack refusing an update that
+// changes nothing.
+func noStackUpdates(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "No updates are to be performed")
+}
+
+// stackFailure explains a failed stack operation with the reason of the
+// first resource that failed, which is more useful than the waiter's error.
+func stackFailure(ctx context.Context, client *cloudformation.Client, name string, err error) error {
+	output, describeErr := client.DescribeStackEvents(ctx, &cloudformation.DescribeStackEventsInput{StackName: aws.String(name)})
+	if describeErr != nil {
+		return fmt.Errorf("stack %s failed: %w", name, err)
+	}
+	// Events are newest first
+	var reason string
+	for _, event := range output.StackEvents {
+		if strings.HasSuffix(string(event.ResourceStatus), "_FAILED") && aws.ToString(event.ResourceStatusReason) != "" {
+			reason = fmt.Sprintf("%s: %s", aws.ToString(event.LogicalResourceId), aws.ToString(event.ResourceStatusReason))
+		}
+	}
+	if reason == "" {
+		return fmt.Errorf("stack %s failed: %w", name, err)
+	}
+	return fmt.Errorf("stack %s failed (%s): %w", name, reason, err)
+}
+
+// monitorTemplate returns the CloudFormation template of the monitor. The
+// package location is left to parameters so that the printed template can
+// be deployed with other tools.
+func monitorTemplate(cfg DeployMonitorConfig) map[string]any {
+	var topic any = cfg.AlertTopicArn
+	resources := map[string]any{}
+	if cfg.AlertTopicArn == "" {
+		topic = map[string]any{"Ref": "AlertTopic"}
+		resources["AlertTopic"] = map[string]any{
+			"Type":       "AWS::SNS::Topic",
+			"Properties": map[string]any{"DisplayName": "Certificate expiry alerts"},
+		}
+	}
+	for i, email := range cfg.Emails {
+		resources[fmt.Sprintf("EmailSubscription%d", i+1)] = map[string]any{
+			"Type": "AWS::SNS::Subscription",
+			"Properties": map[string]any{
+				"TopicArn": topic,
+				"Protocol": "email",
+				"Endpoint": email,
+			},
+		}
+	}
+
+	resources["FunctionRole"] = map[string]any{
+		"Type": "AWS::IAM::Role",
+		"Properties": map[string]any{
+			"AssumeRolePolicyDocument": map[string]any{
+				"Version": "2012-10-17",
+				"Statement": []any{map[string]any{
+					"Effect":    "Allow",
+					"Principal": map[string]any{"Service": "lambda.amazonaws.com"},
+					"Action":    "sts:AssumeRole",
+				}},
+			},
+			"ManagedPolicyArns": []any{
+				map[string]any{"Fn::Sub": "arn:${AWS::Partition}:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+			},
+			"Policies": []any{map[string]any{
+				"PolicyName": "CheckExpiry",
+				"PolicyDocument": map[string]any{
+					"Version": "2012-10-17",
+					"Statement": []any{
+						map[string]any{"Effect": "Allow", "Action": "acm:ListCertificates", "Resource": "*"},
+						map[string]any{"Effect": "Allow", "Action": "sns:Publish", "Resource": topic},
+					},
+				},
+			}},
+		},
+	}
+
+	env := map[string]any{
+		"MODE":            "check-expiry",
+		"DAYS":            fmt.Sprint(cfg.Days),
+		"ALERT_TOPIC_ARN": topic,
+	}
+	if cfg.Type != "" {
+		env["TYPE"] = cfg.Type
+	}
+	if len(cfg.Regions) > 0 {
+		env["REGIONS"] = strings.Join(cfg.Regions, ",")
+	}
+	resources["Function"] = map[string]any{
+		"Type": "AWS::Lambda::Function",
+		"Properties": map[string]any{
+			"Description":   "Alerts on ACM certificates expiring soon (aws-certs deploy-monitor)",
+			"Runtime":       "provided.al2023",
+			"Handler":       "bootstrap",
+			"Architectures": []any{cfg.Architecture},
+			"Timeout":       300,
+			"Role":          map[string]any{"Fn::GetAtt": []any{"FunctionRole", "Arn"}},
+			"Code": map[string]any{
+				"S3Bucket": map[string]any{"Ref": "CodeS3Bucket"},
+				"S3Key":    map[string]any{"Ref": "CodeS3Key"},
+			},
+			"Environment": map[string]any{"Variables": env},
+		},
+	}
+	resources["Schedule"] = map[string]any{
+		"Type": "AWS::Events::Rule",
+		"Properties": map[string]any{
+			"Description":        "Runs the certificate expiry check",
+			"ScheduleExpression": cfg.Schedule,
+			"Targets": []any{map[string]any{
+				"Id":  "CheckExpiry",
+				"Arn": map[string]any{"Fn::GetAtt": []any{"Function", "Arn"}},
+			}},
+		},
+	}
+	resources["SchedulePermission"] = map[string]any{
+		"Type": "AWS::Lambda::Permission",
+		"Properties": map[string]any{
+			"FunctionName": map[string]any{"Ref": "Function"},
+			"Action":       "lambda:InvokeFunction",
+			"Principal":    "events.amazonaws.com",
+			"SourceArn":    map[string]any{"Fn::GetAtt": []any{"Schedule", "Arn"}},
+		},
+	}
+
+	return map[string]any{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              "Scheduled check of ACM certificate expiry dates, deployed by aws-certs deploy-monitor",
+		"Parameters": map[string]any{
+			"CodeS3Bucket": map[string]any{"Type": "String", "Description": "S3 bucket of the function.zip built from ./cmd/lambda"},
+			"CodeS3Key":    map[string]any{"Type": "String", "Description": "S3 key of the function.zip built from ./cmd/lambda"},
+		},
+		"Resources": resources,
+		"Outputs": map[string]any{
+			"FunctionArn":   map[string]any{"Value": map[string]any{"Fn::GetAtt": []any{"Function", "Arn"}}},
+			"AlertTopicArn": map[string]any{"Value": topic},
+		},
+	}
+}
+
+func printMonitorTemplate(cfg DeployMonitorConfig) error {
+	data, err := json.MarshalIndent(monitorTemplate(cfg), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func printMonitor(output string, r monitorResult) error {
+	if output == "json" {
+		return printJSON(r)
+	}
+
+	if r.Status == stackUnchanged {
+		stdout.Printf("✓ Stack %s is up to date\n", r.StackName)
+	} else {
+		stdout.Printf("✓ Stack %s deployed (%s)\n", r.StackName, r.Status)
+	}
+	fmt.Printf("  Function:    %s\n", r.FunctionArn)
+	fmt.Printf("  Alerts:      %s\n", r.AlertTopicArn)
+	fmt.Printf("  Schedule:    %s\n", r.Schedule)
+	certType := r.Type
+	if certType == "" {
+		certType = "all"
+	}
+	fmt.Printf("  Checks:      %s certificates expiring within %d days in %s\n", certType, r.Days, strings.Join(r.Regions, ", "))
+	if len(r.Emails) > 0 {
+		stdout.Printf("⚠ Each email address must confirm the subscription sent by SNS before it receives alerts\n")
+	}
+	return nil
+}
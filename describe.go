@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+type DescribeConfig struct {
+	CertificateArn string
+	Domain         string
+	Output         string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+// describedCertificate is the JSON representation of a certificate returned
+// by the describe subcommand.
+type describedCertificate struct {
+	CertificateArn     string     `json:"certificateArn"`
+	DomainName         string     `json:"domainName"`
+	SANs               []string   `json:"subjectAlternativeNames,omitempty"`
+	Status             string     `json:"status"`
+	Type               string     `json:"type"`
+	Subject            string     `json:"subject,omitempty"`
+	Issuer             string     `json:"issuer,omitempty"`
+	Serial             string     `json:"serial,omitempty"`
+	KeyAlgorithm       string     `json:"keyAlgorithm"`
+	SignatureAlgorithm string     `json:"signatureAlgorithm,omitempty"`
+	NotBefore          *time.Time `json:"notBefore,omitempty"`
+	NotAfter           *time.Time `json:"notAfter,omitempty"`
+	ImportedAt         *time.Time `json:"importedAt,omitempty"`
+	RenewalEligibility string     `json:"renewalEligibility"`
+	InUseBy            []string   `json:"inUseBy"`
+}
+
+func runDescribe(ctx context.Context, args []string) error {
+	var cfg DescribeConfig
+
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to describe")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.StringVar(&cfg.Domain, "domain", "", "Describe certificates whose domain or SANs match this name (glob allowed)")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s describe [OPTIONS] [ARN|DOMAIN]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Describe a certificate in AWS Certificate Manager\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s describe arn:aws:acm:us-east-1:123456789012:certificate/abc\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s describe -domain www.example.com -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	// A positional argument is treated as an ARN or a domain name
+	if fs.NArg() > 0 && cfg.CertificateArn == "" && cfg.Domain == "" {
+		if strings.HasPrefix(fs.Arg(0), "arn:") {
+			cfg.CertificateArn = fs.Arg(0)
+		} else {
+			cfg.Domain = fs.Arg(0)
+		}
+	}
+
+	if cfg.CertificateArn == "" && cfg.Domain == "" {
+		fmt.Fprintf(os.Stderr, "Error: a certificate ARN or domain is required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text or json\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return describeCertificates(ctx, cfg)
+}
+
+func describeCertificates(ctx context.Context, cfg DescribeConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	client := acm.NewFromConfig(awsCfg)
+
+	arns := []string{cfg.CertificateArn}
+	if cfg.CertificateArn == "" {
+		matches, err := findCertificates(ctx, client, ListConfig{DomainGlob: cfg.Domain})
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no certificate found for domain %s in %s", cfg.Domain, awsCfg.Region)
+		}
+		arns = arns[:0]
+		for _, match := range matches {
+			arns = append(arns, match.CertificateArn)
+		}
+	}
+
+	var described []describedCertificate
+	for _, arn := range arns {
+		detail, err := describeCertificate(ctx, client, arn)
+		if err != nil {
+			return err
+		}
+		described = append(described, newDescribedCertificate(detail))
+	}
+
+	if cfg.Output == "json" {
+		// A single ARN prints a single object rather than a list
+		if cfg.CertificateArn != "" {
+			return printJSON(described[0])
+		}
+		return printJSON(described)
+	}
+
+	for i, cert := range described {
+		if i > 0 {
+			fmt.Println()
+		}
+		printDescribedCertificate(cert)
+	}
+	return nil
+}
+
+func describeCertificate(ctx context.Context, client *acm.Client, arn string) (*types.CertificateDetail, error) {
+	result, err := client.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+		CertificateArn: aws.String(arn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe certificate %s: %w", arn, err)
+	}
+	return result.Certificate, nil
+}
+
+func newDescribedCertificate(detail *types.CertificateDetail) describedCertificate {
+	cert := describedCertificate{
+		CertificateArn:     aws.ToString(detail.CertificateArn),
+		DomainName:         aws.ToString(detail.DomainName),
+		SANs:               detail.SubjectAlternativeNames,
+		Status:             string(detail.Status),
+		Type:               string(detail.Type),
+		Subject:            aws.ToString(detail.Subject),
+		Issuer:             aws.ToString(detail.Issuer),
+		Serial:             aws.ToString(detail.Serial),
+		KeyAlgorithm:       string(detail.KeyAlgorithm),
+		SignatureAlgorithm: aws.ToString(detail.SignatureAlgorithm),
+		NotBefore:          detail.NotBefore,
+		NotAfter:           detail.NotAfter,
+		ImportedAt:         detail.ImportedAt,
+		RenewalEligibility: string(detail.RenewalEligibility),
+		InUseBy:            detail.InUseBy,
+	}
+	if cert.InUseBy == nil {
+		cert.InUseBy = []string{}
+	}
+	return cert
+}
+
+func printDescribedCertificate(cert describedCertificate) {
+	fmt.Printf("Certificate ARN:      %s\n", cert.CertificateArn)
+	fmt.Printf("Domain:               %s\n", cert.DomainName)
+	if len(cert.SANs) > 0 {
+		fmt.Printf("SANs:                 %s\n", strings.Join(cert.SANs, ", "))
+	}
+	fmt.Printf("Status:               %s\n", cert.Status)
+	fmt.Printf("Type:                 %s\n", cert.Type)
+	if cert.Subject != "" {
+		fmt.Printf("Subject:              %s\n", cert.Subject)
+	}
+	if cert.Issuer != "" {
+		fmt.Printf("Issuer:               %s\n", cert.Issuer)
+	}
+	if cert.Serial != "" {
+		fmt.Printf("Serial:               %s\n", cert.Serial)
+	}
+	fmt.Printf("Key Algorithm:        %s\n", cert.KeyAlgorithm)
+	if cert.SignatureAlgorithm != "" {
+		fmt.Printf("Signature Algorithm:  %s\n", cert.SignatureAlgorithm)
+	}
+	if cert.NotBefore != nil {
+		fmt.Printf("Not Before:           %s\n", cert.NotBefore.Format(time.RFC3339))
+	}
+	if cert.NotAfter != nil {
+		fmt.Printf("Not After:            %s (%d days)\n", cert.NotAfter.Format(time.RFC3339), daysUntil(*cert.NotAfter))
+	}
+	if cert.ImportedAt != nil {
+		fmt.Printf("Imported At:          %s\n", cert.ImportedAt.Format(time.RFC3339))
+	}
+	fmt.Printf("Renewal Eligibility:  %s\n", cert.RenewalEligibility)
+	if len(cert.InUseBy) == 0 {
+		fmt.Printf("In Use By:            (none)\n")
+	} else {
+		fmt.Printf("In Use By:\n")
+		for _, resource := range cert.InUseBy {
+			fmt.Printf("  - %s\n", resource)
+		}
+	}
+}
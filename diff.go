@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type DiffConfig struct {
+	CertificateArn string
+	CertFile       string
+	ChainFile      string
+	Output         string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+// diffField is one compared property of the two certificates.
+type diffField struct {
+	Name  string `json:"name"`
+	Local string `json:"local"`
+	ACM   string `json:"acm"`
+	Same  bool   `json:"same"`
+}
+
+// diffResult is the JSON representation of diff.
+type diffResult struct {
+	CertificateArn string      `json:"certificateArn"`
+	Identical      bool        `json:"identical"`
+	Fields         []diffField `json:"fields"`
+}
+
+func runDiff(ctx context.Context, args []string) error {
+	var cfg DiffConfig
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the ACM certificate to compare - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.StringVar(&cfg.CertFile, "cert", "", "Local certificate: a file, - for stdin, or an ssm://, s3:// or vault:// URI; further certificates in it are the chain - REQUIRED")
+	fs.StringVar(&cfg.ChainFile, "chain", "", "Local certificate chain, if not in the -cert file")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff -arn <arn> -cert <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compare a local certificate with the one in ACM (serial, fingerprint, names, chain and\n")
+		fmt.Fprintf(os.Stderr, "validity) to decide whether a re-import is needed. Exits non-zero if they differ.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s diff -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -cert fullchain.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s diff -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -cert cert.pem -chain chain.pem -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if cfg.CertificateArn == "" || cfg.CertFile == "" {
+		fail(errors.New("-arn and -cert are required"))
+	}
+	if cfg.CertFile == "-" && cfg.ChainFile == "-" {
+		fail(errors.New("only one input can be read from stdin"))
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fail(errors.New("-output must be text or json"))
+	}
+	region, err := arnRegion(cfg.CertificateArn)
+	if err != nil {
+		fail(err)
+	}
+	cfg.Region = region
+
+	return diffCertificate(ctx, cfg)
+}
+
+func diffCertificate(ctx context.Context, cfg DiffConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+
+	local, err := readInput(ctx, awsCfg, cfg.CertFile, "certificate")
+	if err != nil {
+		return err
+	}
+	if cfg.ChainFile != "" {
+		chain, err := readInput(ctx, awsCfg, cfg.ChainFile, "certificate chain")
+		if err != nil {
+			return err
+		}
+		local = append(local, chain...)
+	}
+
+	out, err := acm.NewFromConfig(awsCfg).GetCertificate(ctx, &acm.GetCertificateInput{CertificateArn: aws.String(cfg.CertificateArn)})
+	if err != nil {
+		return fmt.Errorf("failed to get certificate: %w", err)
+	}
+	remote, err := certimport.ParseCertificates([]byte(aws.ToString(out.Certificate)), "ACM certificate")
+	if err != nil {
+		return err
+	}
+	if chain := aws.ToString(out.CertificateChain); chain != "" {
+		remoteChain, err := certimport.ParseCertificates([]byte(chain), "ACM certificate chain")
+		if err != nil {
+			return err
+		}
+		remote = append(remote, remoteChain...)
+	}
+
+	result := diffResult{CertificateArn: cfg.CertificateArn, Fields: compareCertificates(local, remote)}
+	result.Identical = !slices.ContainsFunc(result.Fields, func(f diffField) bool { return !f.Same })
+
+	if err := printDiff(cfg.Output, result); err != nil {
+		return err
+	}
+	if !result.Identical {
+		return errors.New("the local certificate differs from the one in ACM")
+	}
+	return nil
+}
+
+// readInput reads and parses the certificates in an input.
+func readInput(ctx context.Context, awsCfg aws.Config, path, what string) ([]*x509.Certificate, error) {
+	data, err := input{Path: path}.read(ctx, awsCfg)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := certimport.ParseCertificates(data, what)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no %s found in %s", what, path)
+	}
+	return certs, nil
+}
+
+// compareCertificates compares the leaf certificates, the first of each
+// list, and the chains that follow them. Chains are compared regardless of
+// order, since ACM stores them as imported.
+func compareCertificates(local, remote []*x509.Certificate) []diffField {
+	field := func(name, l, r string) diffField {
+		return diffField{Name: name, Local: l, ACM: r, Same: l == r}
+	}
+	names := func(c *x509.Certificate) string {
+		sans := slices.Clone(c.DNSNames)
+		slices.Sort(sans)
+		return strings.Join(sans, ", ")
+	}
+	chain := func(certs []*x509.Certificate) string {
+		var parts []string
+		for _, c := range certs {
+			parts = append(parts, c.Subject.CommonName+" ("+certimport.Fingerprint(c)[:16]+")")
+		}
+		slices.Sort(parts)
+		return strings.Join(parts, ", ")
+	}
+
+	l, r := local[0], remote[0]
+	return []diffField{
+		field("Fingerprint", certimport.Fingerprint(l), certimport.Fingerprint(r)),
+		field("Serial", l.SerialNumber.Text(16), r.SerialNumber.Text(16)),
+		field("Subject", l.Subject.String(), r.Subject.String()),
+		field("SANs", names(l), names(r)),
+		field("Issuer", l.Issuer.String(), r.Issuer.String()),
+		field("Not before", l.NotBefore.UTC().Format(time.RFC3339), r.NotBefore.UTC().Format(time.RFC3339)),
+		field("Not after", l.NotAfter.UTC().Format(time.RFC3339), r.NotAfter.UTC().Format(time.RFC3339)),
+		field("Chain", chain(local[1:]), chain(remote[1:])),
+	}
+}
+
+func printDiff(format string, result diffResult) error {
+	if format == "json" {
+		return printJSON(result)
+	}
+	if progress.Quiet() {
+		return nil
+	}
+
+	for _, f := range result.Fields {
+		if f.Same {
+			fmt.Printf("  %-11s %s\n", f.Name+":", f.Local)
+			continue
+		}
+		fmt.Printf("- %-11s %s (ACM)\n", f.Name+":", f.ACM)
+		fmt.Printf("+ %-11s %s (local)\n", f.Name+":", f.Local)
+	}
+	if result.Identical {
+		stdout.Printf("✅ The local certificate is identical to %s, no re-import needed\n", result.CertificateArn)
+	} else {
+		stdout.Printf("✗ The local certificate differs from %s\n", result.CertificateArn)
+	}
+	return nil
+}
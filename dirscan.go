@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// scanExtensions are the file names considered by -dir: the usual PEM and
+// DER names such as fullchain.pem/privkey.pem (certbot) or name.crt/name.key.
+var scanExtensions = map[string]bool{
+	".pem": true, ".crt": true, ".cer": true, ".cert": true, ".key": true, ".der": true,
+}
+
+// foundCertificate is a leaf certificate found by -dir together with its
+// private key and any intermediates found alongside it.
+type foundCertificate struct {
+	CertFile string
+	KeyFile  string
+	Material *certimport.Material
+}
+
+type scannedKey struct {
+	file string
+	key  crypto.Signer
+}
+
+// scanDir walks dir for certificates and private keys and pairs each leaf
+// certificate with the key whose public key matches. CA certificates found
+// anywhere in the tree are offered as the chain; only those that issued the
+// leaf are used.
+func scanDir(dir string, passphrase certimport.PassphraseFunc) ([]foundCertificate, error) {
+	var leaves []*x509.Certificate
+	var cas []*x509.Certificate
+	var keys []scannedKey
+	certFiles := make(map[*x509.Certificate]string)
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !scanExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() || info.Size() > certimport.MaxMaterialSize {
+			return nil
+		}
+		data, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+
+		certs, fileKeys := scanFile(rel, data, passphrase)
+		for _, cert := range certs {
+			if seen[certimport.Fingerprint(cert)] {
+				continue
+			}
+			seen[certimport.Fingerprint(cert)] = true
+			if isIssuer(cert) {
+				cas = append(cas, cert)
+			} else {
+				leaves = append(leaves, cert)
+				certFiles[cert] = rel
+			}
+		}
+		for _, key := range fileKeys {
+			keys = append(keys, scannedKey{file: rel, key: key})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	var found []foundCertificate
+	for _, leaf := range leaves {
+		var match *scannedKey
+		for i := range keys {
+			if certimport.VerifyKeyMatch(leaf, keys[i].key) == nil {
+				match = &keys[i]
+				break
+			}
+		}
+		if match == nil {
+			progress.Warnf("No private key found for %s (%s), skipping", leaf.Subject, certFiles[leaf])
+			continue
+		}
+
+		found = append(found, foundCertificate{
+			CertFile: certFiles[leaf],
+			KeyFile:  match.file,
+			Material: &certimport.Material{
+				Leaf:  leaf,
+				Key:   match.key,
+				Chain: certimport.OrderChain(leaf, cas).Intermediates(),
+			},
+		})
+	}
+	return newestByNames(found), nil
+}
+
+// newestByNames keeps only the certificate that expires last among those
+// covering the same names. Trees such as certbot's archive directory hold
+// every version of a certificate; importing them all in file name order
+// could leave an older version in ACM (cert9.pem sorts after cert10.pem).
+func newestByNames(found []foundCertificate) []foundCertificate {
+	newest := make(map[string]int)
+	var order []string
+	for i, f := range found {
+		key := nameKey(f.Material.Leaf)
+		j, ok := newest[key]
+		if !ok {
+			order = append(order, key)
+			newest[key] = i
+			continue
+		}
+		if f.Material.Leaf.NotAfter.After(found[j].Material.Leaf.NotAfter) {
+			newest[key] = i
+		}
+	}
+
+	for i, f := range found {
+		if kept := found[newest[nameKey(f.Material.Leaf)]]; newest[nameKey(f.Material.Leaf)] != i {
+			progress.Warnf("Skipping %s (%s): %s covers the same names and expires later", f.Material.Leaf.Subject, f.CertFile, kept.CertFile)
+		}
+	}
+
+	kept := make([]foundCertificate, 0, len(order))
+	for _, key := range order {
+		kept = append(kept, found[newest[key]])
+	}
+	return kept
+}
+
+// nameKey returns the certificate's lower-cased, sorted domain name and SANs
+// for grouping certificates that cover the same names.
+func nameKey(cert *x509.Certificate) string {
+	set := make(map[string]bool)
+	if cert.Subject.CommonName != "" {
+		set[strings.ToLower(cert.Subject.CommonName)] = true
+	}
+	for _, name := range cert.DNSNames {
+		set[strings.ToLower(name)] = true
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// isIssuer reports whether cert is a CA certificate allowed to sign others.
+// Self-signed certificates made by "openssl req -x509" are marked as CAs but
+// lack the certificate signing key usage, so they are treated as leaves.
+func isIssuer(cert *x509.Certificate) bool {
+	return cert.IsCA && cert.KeyUsage&x509.KeyUsageCertSign != 0
+}
+
+// scanFile returns the certificates and private keys in one file. Files that
+// are neither are ignored; keys that cannot be decrypted are skipped with a
+// warning.
+func scanFile(name string, data []byte, passphrase certimport.PassphraseFunc) ([]*x509.Certificate, []crypto.Signer) {
+	if certimport.IsDER(data) {
+		if certs, err := x509.ParseCertificates(data); err == nil {
+			return certs, nil
+		}
+		if key, err := certimport.ParsePrivateKey(data, passphrase); err == nil {
+			return nil, []crypto.Signer{key}
+		}
+		return nil, nil
+	}
+
+	var certs []*x509.Certificate
+	var keys []crypto.Signer
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE":
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				certs = append(certs, cert)
+			}
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			key, err := certimport.ParsePrivateKey(pem.EncodeToMemory(block), passphrase)
+			if err != nil {
+				progress.Warnf("Skipping private key in %s: %v", name, err)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	return certs, keys
+}
+
+// importDir imports every certificate found by scanning cfg.Dir.
+func importDir(ctx context.Context, cfg CertImportConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	if cfg.AllRegions {
+		var err error
+		cfg.Regions, err = enabledRegions(ctx, cfg.Region, cfg.Profile)
+		if err != nil {
+			return err
+		}
+	}
+
+	progress.Printf("Scanning %s for certificates...\n", cfg.Dir)
+
+	// Only use a passphrase that was given; prompting for every key is not
+	// practical when scanning
+	var passphrase certimport.PassphraseFunc
+	if cfg.KeyPassphrase != "" || cfg.KeyPassphraseFile != "" {
+		passphrase = keyPassphraseFunc(cfg.KeyPassphrase, cfg.KeyPassphraseFile)
+	}
+	found, err := scanDir(cfg.Dir, passphrase)
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("no certificate and private key pairs found in %s", cfg.Dir)
+	}
+
+	progress.Printf("✓ Found %d certificates\n", len(found))
+	for _, f := range found {
+		progress.Printf("  %s: %s + %s (%d chain certificates)\n", f.Material.Leaf.Subject, f.CertFile, f.KeyFile, len(f.Material.Chain))
+	}
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := preflight(ctx, awsCfg, progress); err != nil {
+		return err
+	}
+
+	var results []batchResult
+	spin := progress.Spin("Importing certificates", len(found))
+	for _, f := range found {
+		logger := prefixLogger{prefix: "[" + f.CertFile + "] ", progressLogger: progress}
+		results = append(results, importMaterial(ctx, awsCfg, logger, f.CertFile, cfg, f.Material))
+		spin.Step()
+	}
+	spin.Stop()
+
+	if err := printBatchResults(cfg.Output, results); err != nil {
+		return err
+	}
+
+	if err := batchSummary(results, "certificates"); err != nil {
+		return err
+	}
+	if cfg.DryRun {
+		progress.Printf("✅ Dry run complete, no changes made\n")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// dryRun prints what would be imported after checking that AWS credentials
+// resolve, without calling ImportCertificate. Each region is searched the way
+// a real import would, so the ARN that would be reused or replaced is shown.
+func dryRun(ctx context.Context, cfg CertImportConfig, awsCfg aws.Config, importer *certimport.Importer, prepared *certimport.Prepared, regions []string) error {
+	// The credentials were checked before reading the certificate; this
+	// only looks up the account for the result
+	identity, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("failed to verify AWS credentials: %w", err)
+	}
+
+	if len(regions) == 0 {
+		regions = []string{awsCfg.Region}
+	}
+
+	progress.Printf("Looking up existing certificates...\n")
+	plan := importer.Plan(ctx, prepared, regions)
+	var planErr error
+	if len(plan.Regions) == 1 {
+		planErr = plan.Regions[0].Err
+	} else if failed := plan.Failed(); failed > 0 {
+		planErr = fmt.Errorf("lookup failed in %d of %d regions", failed, len(plan.Regions))
+	}
+
+	leaf := prepared.Leaf
+
+	if cfg.Output == "json" {
+		result := newImportResult(plan)
+		result.DryRun = true
+		result.Account = aws.ToString(identity.Account)
+		for i, r := range plan.Regions {
+			if r.Err != nil {
+				result.Regions[i].setError(r.Err)
+			}
+		}
+		if err := printImportResult(cfg.Output, result); err != nil {
+			return err
+		}
+		return planErr
+	}
+
+	if progress.Quiet() {
+		return planErr
+	}
+
+	fmt.Printf("Dry run: the following would be imported\n")
+	fmt.Printf("  Subject:      %s\n", leaf.Subject)
+	if len(leaf.DNSNames) > 0 {
+		fmt.Printf("  SANs:         %s\n", strings.Join(leaf.DNSNames, ", "))
+	}
+	fmt.Printf("  Issuer:       %s\n", leaf.Issuer)
+	fmt.Printf("  Serial:       %s\n", leaf.SerialNumber.Text(16))
+	fmt.Printf("  Key:          %s\n", certimport.PublicKeyDescription(leaf.PublicKey))
+	fmt.Printf("  Not After:    %s (%d days)\n", leaf.NotAfter.Format(time.RFC3339), daysUntil(leaf.NotAfter))
+	fmt.Printf("  Chain:        %d certificates\n", len(prepared.Chain))
+	for _, r := range plan.Regions {
+		stdout.Printf("  %-13s %s\n", r.Region+":", plannedOutcome(r))
+	}
+	for _, target := range cfg.PublishArn {
+		fmt.Printf("  Publish ARN:  %s\n", target)
+	}
+	for _, listener := range cfg.AttachListeners {
+		fmt.Printf("  Attach to:    %s\n", listener)
+	}
+	for _, id := range cfg.AttachDistributions {
+		fmt.Printf("  Attach to:    CloudFront distribution %s\n", id)
+	}
+	for _, name := range cfg.AttachAPIDomains {
+		fmt.Printf("  Attach to:    API Gateway custom domain %s\n", name)
+	}
+	if len(prepared.Tags) > 0 {
+		var tags []string
+		for _, tag := range prepared.Tags {
+			tags = append(tags, aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+		}
+		sort.Strings(tags)
+		fmt.Printf("  Tags:         %s\n", strings.Join(tags, ", "))
+	}
+
+	if planErr != nil {
+		return planErr
+	}
+	progress.Printf("✅ Dry run complete, no changes made\n")
+	return nil
+}
+
+// plannedOutcome describes what importing into a region would do.
+func plannedOutcome(r certimport.RegionResult) string {
+	switch {
+	case r.Err != nil:
+		return "✗ " + errorHint(r.Err).Error()
+	case r.Existing:
+		return "identical certificate already imported, would reuse " + r.CertificateArn
+	case r.Reimported:
+		return "would re-import over " + r.CertificateArn
+	}
+	return "would import a new certificate"
+}
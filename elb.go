@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+)
+
+type AttachELBConfig struct {
+	ListenerArns   []string
+	CertificateArn string
+	SNI            bool
+	Profile        string
+	Region         string
+	Timeout        time.Duration
+}
+
+func runAttachELB(ctx context.Context, args []string) error {
+	var cfg AttachELBConfig
+	var listeners string
+
+	fs := flag.NewFlagSet("attach-elb", flag.ExitOnError)
+	fs.StringVar(&listeners, "listener-arn", "", "ARN of the ALB/NLB listener (comma-separated for several) - REQUIRED")
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to attach - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.BoolVar(&cfg.SNI, "sni", false, "Add the certificate to the listener's SNI certificate list instead of replacing the default certificate")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s attach-elb -listener-arn <arn> -arn <arn> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Make a certificate the default certificate of an ALB/NLB listener, or add it for SNI.\n")
+		fmt.Fprintf(os.Stderr, "The listener's region is taken from its ARN.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s attach-elb -listener-arn arn:aws:elasticloadbalancing:us-east-1:123456789012:listener/app/web/abc/def -arn arn:aws:acm:us-east-1:123456789012:certificate/abc\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -attach-listener arn:aws:elasticloadbalancing:us-east-1:123456789012:listener/app/web/abc/def\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	cfg.ListenerArns = cliutil.SplitList(listeners)
+	if len(cfg.ListenerArns) == 0 || cfg.CertificateArn == "" {
+		fmt.Fprintf(os.Stderr, "Error: -listener-arn and -arn are required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	for _, listener := range cfg.ListenerArns {
+		if _, err := arnRegion(listener); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+	}
+
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	for _, listener := range cfg.ListenerArns {
+		if err := attachListener(ctx, awsCfg, listener, cfg.CertificateArn, cfg.SNI); err != nil {
+			return err
+		}
+	}
+
+	if !progress.Quiet() {
+		stdout.Printf("✅ Certificate attached to %d listener(s)\n", len(cfg.ListenerArns))
+	}
+	return nil
+}
+
+// arnRegion returns the region of a regional resource ARN.
+func arnRegion(value string) (string, error) {
+	parsed, err := arn.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid ARN %q: %w", value, err)
+	}
+	if parsed.Region == "" {
+		return "", fmt.Errorf("ARN %q has no region", value)
+	}
+	return parsed.Region, nil
+}
+
+// arnAccount returns the account ID in an ARN, or "" if it has none.
+func arnAccount(value string) string {
+	parsed, err := arn.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return parsed.AccountID
+}
+
+// attachListener makes certificateArn the default certificate of a listener
+// or, with sni, adds it to the listener's additional certificates. Both are
+// no-ops when the certificate is already attached.
+func attachListener(ctx context.Context, awsCfg aws.Config, listenerArn, certificateArn string, sni bool) error {
+	region, err := arnRegion(listenerArn)
+	if err != nil {
+		return err
+	}
+	cfg := awsCfg.Copy()
+	cfg.Region = region
+	client := elb.NewFromConfig(cfg)
+	certs := []elbtypes.Certificate{{CertificateArn: aws.String(certificateArn)}}
+
+	if sni {
+		_, err := client.AddListenerCertificates(ctx, &elb.AddListenerCertificatesInput{
+			ListenerArn:  aws.String(listenerArn),
+			Certificates: certs,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add certificate to listener %s: %w", listenerArn, err)
+		}
+		progress.Printf("✓ Added %s to listener %s\n", certificateArn, listenerArn)
+		return nil
+	}
+
+	out, err := client.DescribeListeners(ctx, &elb.DescribeListenersInput{ListenerArns: []string{listenerArn}})
+	if err != nil {
+		return fmt.Errorf("failed to describe listener %s: %w", listenerArn, err)
+	}
+	if len(out.Listeners) == 0 {
+		return fmt.Errorf("listener %s not found", listenerArn)
+	}
+	listener := out.Listeners[0]
+	if listener.Protocol != elbtypes.ProtocolEnumHttps && listener.Protocol != elbtypes.ProtocolEnumTls {
+		return fmt.Errorf("listener %s uses %s, certificates can only be attached to HTTPS and TLS listeners", listenerArn, listener.Protocol)
+	}
+	for _, c := range listener.Certificates {
+		if aws.ToString(c.CertificateArn) == certificateArn {
+			progress.Printf("✓ Listener %s already uses %s\n", listenerArn, certificateArn)
+			return nil
+		}
+	}
+
+	_, err = client.ModifyListener(ctx, &elb.ModifyListenerInput{
+		ListenerArn:  aws.String(listenerArn),
+		Certificates: certs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set the default certificate of listener %s: %w", listenerArn, err)
+	}
+	progress.Printf("✓ Listener %s now uses %s\n", listenerArn, certificateArn)
+	return nil
+}
+
+// replaceOnLoadBalancer swaps oldArn for newArn on every listener of a load
+// balancer, both as the default certificate and in the SNI certificate list.
+// It returns the number of listeners changed.
+func replaceOnLoadBalancer(ctx context.Context, awsCfg aws.Config, loadBalancerArn, oldArn, newArn string) (int, error) {
+	region, err := arnRegion(loadBalancerArn)
+	if err != nil {
+		return 0, err
+	}
+	cfg := awsCfg.Copy()
+	cfg.Region = region
+	client := elb.NewFromConfig(cfg)
+
+	var listeners []string
+	paginator := elb.NewDescribeListenersPaginator(client, &elb.DescribeListenersInput{LoadBalancerArn: aws.String(loadBalancerArn)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list listeners of %s: %w", loadBalancerArn, err)
+		}
+		for _, l := range page.Listeners {
+			listeners = append(listeners, aws.ToString(l.ListenerArn))
+		}
+	}
+
+	changed := 0
+	for _, listener := range listeners {
+		var certs []elbtypes.Certificate
+		input := &elb.DescribeListenerCertificatesInput{ListenerArn: aws.String(listener)}
+		for {
+			out, err := client.DescribeListenerCertificates(ctx, input)
+			if err != nil {
+				return changed, fmt.Errorf("failed to list certificates of listener %s: %w", listener, err)
+			}
+			certs = append(certs, out.Certificates...)
+			if out.NextMarker == nil {
+				break
+			}
+			input.Marker = out.NextMarker
+		}
+
+		for _, c := range certs {
+			if aws.ToString(c.CertificateArn) != oldArn {
+				continue
+			}
+			if aws.ToBool(c.IsDefault) {
+				err = attachListener(ctx, cfg, listener, newArn, false)
+			} else {
+				err = replaceListenerSNI(ctx, client, listener, oldArn, newArn)
+			}
+			if err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// replaceListenerSNI adds newArn to a listener's SNI certificates before
+// removing oldArn, so clients are served throughout.
+func replaceListenerSNI(ctx context.Context, client *elb.Client, listenerArn, oldArn, newArn string) error {
+	_, err := client.AddListenerCertificates(ctx, &elb.AddListenerCertificatesInput{
+		ListenerArn:  aws.String(listenerArn),
+		Certificates: []elbtypes.Certificate{{CertificateArn: aws.String(newArn)}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add certificate to listener %s: %w", listenerArn, err)
+	}
+	_, err = client.RemoveListenerCertificates(ctx, &elb.RemoveListenerCertificatesInput{
+		ListenerArn:  aws.String(listenerArn),
+		Certificates: []elbtypes.Certificate{{CertificateArn: aws.String(oldArn)}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove the old certificate from listener %s: %w", listenerArn, err)
+	}
+	progress.Printf("✓ Listener %s now serves %s instead of %s\n", listenerArn, newArn, oldArn)
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type CheckEndpointConfig struct {
+	Host           string
+	ServerName     string
+	CertFile       string
+	CertificateArn string
+	Output         string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+// endpointCertificate describes a certificate compared by check-endpoint.
+type endpointCertificate struct {
+	Fingerprint string    `json:"fingerprint"`
+	Subject     string    `json:"subject"`
+	SANs        []string  `json:"subjectAlternativeNames"`
+	NotAfter    time.Time `json:"notAfter"`
+}
+
+// endpointResult is the JSON representation of check-endpoint.
+type endpointResult struct {
+	Host        string              `json:"host"`
+	ServerName  string              `json:"serverName"`
+	Served      endpointCertificate `json:"served"`
+	Expected    endpointCertificate `json:"expected"`
+	Match       bool                `json:"match"`
+	Differences []string            `json:"differences"`
+}
+
+func runCheckEndpoint(ctx context.Context, args []string) error {
+	var cfg CheckEndpointConfig
+
+	fs := flag.NewFlagSet("check-endpoint", flag.ExitOnError)
+	fs.StringVar(&cfg.Host, "host", "", "Endpoint to connect to, as host or host:port (default port 443) - REQUIRED")
+	fs.StringVar(&cfg.ServerName, "servername", "", "Server name to send for SNI (default: the host)")
+	fs.StringVar(&cfg.CertFile, "cert", "", "Expected certificate: a file, - for stdin, or an ssm://, s3:// or vault:// URI")
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "Expected certificate: an ACM certificate ARN, instead of -cert")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check-endpoint -host <host[:port]> (-cert <file> | -arn <arn>) [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compare the certificate a live endpoint serves with a local file or an ACM certificate\n")
+		fmt.Fprintf(os.Stderr, "(fingerprint, expiry and SANs). Exits non-zero if they differ.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s check-endpoint -host example.com -cert cert.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s check-endpoint -host my-alb-123.us-east-1.elb.amazonaws.com:443 -servername www.example.com -arn arn:aws:acm:us-east-1:123456789012:certificate/abc\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if cfg.Host == "" {
+		fail(errors.New("-host is required"))
+	}
+	if countSet(cfg.CertFile, cfg.CertificateArn) != 1 {
+		fail(errors.New("exactly one of -cert and -arn is required"))
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fail(errors.New("-output must be text or json"))
+	}
+	if cfg.CertificateArn != "" {
+		region, err := arnRegion(cfg.CertificateArn)
+		if err != nil {
+			fail(err)
+		}
+		cfg.Region = region
+	}
+
+	return checkEndpoint(ctx, cfg)
+}
+
+func checkEndpoint(ctx context.Context, cfg CheckEndpointConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	expected, err := expectedCertificate(ctx, awsCfg, cfg)
+	if err != nil {
+		return err
+	}
+
+	address := cfg.Host
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "443")
+	}
+	serverName := cfg.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(address)
+	}
+	served, err := servedCertificate(ctx, address, serverName)
+	if err != nil {
+		return err
+	}
+
+	result := endpointResult{
+		Host:        address,
+		ServerName:  serverName,
+		Served:      describeEndpointCertificate(served),
+		Expected:    describeEndpointCertificate(expected),
+		Differences: []string{},
+	}
+	result.Match = result.Served.Fingerprint == result.Expected.Fingerprint
+	if !result.Match {
+		result.Differences = append(result.Differences, "fingerprint")
+		if !served.NotAfter.Equal(expected.NotAfter) {
+			result.Differences = append(result.Differences, "expiry")
+		}
+		if !slices.Equal(result.Served.SANs, result.Expected.SANs) {
+			result.Differences = append(result.Differences, "SANs")
+		}
+	}
+
+	if err := printEndpointResult(cfg.Output, result); err != nil {
+		return err
+	}
+	if !result.Match {
+		return fmt.Errorf("%s serves a different certificate (%s differ)", address, strings.Join(result.Differences, ", "))
+	}
+	return nil
+}
+
+// expectedCertificate loads the leaf certificate to compare against from
+// -cert or -arn.
+func expectedCertificate(ctx context.Context, awsCfg aws.Config, cfg CheckEndpointConfig) (*x509.Certificate, error) {
+	var data []byte
+	if cfg.CertificateArn != "" {
+		out, err := acm.NewFromConfig(awsCfg).GetCertificate(ctx, &acm.GetCertificateInput{CertificateArn: aws.String(cfg.CertificateArn)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get certificate: %w", err)
+		}
+		data = []byte(aws.ToString(out.Certificate))
+	} else {
+		var err error
+		data, err = input{Path: cfg.CertFile}.read(ctx, awsCfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	certs, err := certimport.ParseCertificates(data, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs[0], nil
+}
+
+// servedCertificate connects to address and returns the leaf certificate the
+// server presents for serverName. The chain is not verified, so endpoints
+// serving a broken or self-signed certificate can still be compared.
+func servedCertificate(ctx context.Context, address, serverName string) (*x509.Certificate, error) {
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s did not present a certificate", address)
+	}
+	progress.Debugf("Connected to %s (SNI %s)\n", address, serverName)
+	return certs[0], nil
+}
+
+func describeEndpointCertificate(cert *x509.Certificate) endpointCertificate {
+	sans := slices.Clone(cert.DNSNames)
+	slices.Sort(sans)
+	return endpointCertificate{
+		Fingerprint: certimport.Fingerprint(cert),
+		Subject:     cert.Subject.String(),
+		SANs:        sans,
+		NotAfter:    cert.NotAfter,
+	}
+}
+
+func printEndpointResult(format string, result endpointResult) error {
+	if format == "json" {
+		return printJSON(result)
+	}
+	if progress.Quiet() {
+		return nil
+	}
+
+	if result.Match {
+		stdout.Printf("✅ %s serves the expected certificate\n", result.Host)
+		fmt.Printf("Fingerprint: %s\n", result.Served.Fingerprint)
+		fmt.Printf("Expires: %s (%d days)\n", result.Served.NotAfter.Format("2006-01-02"), daysUntil(result.Served.NotAfter))
+		return nil
+	}
+
+	stdout.Printf("✗ %s serves a different certificate\n", result.Host)
+	fmt.Printf("  Served:   %s, expires %s, SANs %s\n", result.Served.Fingerprint, result.Served.NotAfter.Format("2006-01-02"), strings.Join(result.Served.SANs, ", "))
+	fmt.Printf("  Expected: %s, expires %s, SANs %s\n", result.Expected.Fingerprint, result.Expected.NotAfter.Format("2006-01-02"), strings.Join(result.Expected.SANs, ", "))
+	return nil
+}
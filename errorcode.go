@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/smithy-go"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// Error codes, reported with errors in JSON output so that scripts can tell
+// failures apart without parsing messages. Keep the README list in sync.
+const (
+	codeError             = "ERROR"
+	codeInterrupted       = "INTERRUPTED"
+	codeTimeout           = "TIMEOUT"
+	codeExpired           = "CERTIFICATE_EXPIRED"
+	codeRevoked           = "CERTIFICATE_REVOKED"
+	codeNotCovered        = "NAMES_NOT_COVERED"
+	codeAmbiguousMatch    = "AMBIGUOUS_MATCH"
+	codeKeyMismatch       = "KEY_MISMATCH"
+	codeKeyEncrypted      = "KEY_ENCRYPTED"
+	codeKeyPolicy         = "KEY_POLICY"
+	codeKeyWeak           = "KEY_WEAK"
+	codeKeyUnsupported    = "KEY_UNSUPPORTED"
+	codeChainUnrelated    = "CHAIN_UNRELATED"
+	codeChainIncomplete   = "CHAIN_INCOMPLETE"
+	codeChainInvalid      = "CHAIN_INVALID"
+	codeInvalidInput      = "INVALID_INPUT"
+	codeLimitExceeded     = "LIMIT_EXCEEDED"
+	codeResourceInUse     = "RESOURCE_IN_USE"
+	codeNotFound          = "NOT_FOUND"
+	codeTooManyTags       = "TOO_MANY_TAGS"
+	codeTagPolicy         = "TAG_POLICY"
+	codeAccessDenied      = "ACCESS_DENIED"
+	codeNoCredentials     = "NO_CREDENTIALS"
+	codeThrottled         = "THROTTLED"
+	codeRequestInProgress = "REQUEST_IN_PROGRESS"
+)
+
+// jsonErrors is set by parseFlags for commands given -output json, so that
+// the error they fail with is printed as JSON as well.
+var jsonErrors bool
+
+// errorInfo is an error as reported to the user: a stable code, the message
+// without the SDK's request details, and what to do about it, if known.
+type errorInfo struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// printErrorJSON prints the error a command failed with to stderr as a line
+// of JSON, leaving stdout to any results printed before the failure.
+func printErrorJSON(info errorInfo) {
+	data, err := json.Marshal(struct {
+		Error errorInfo `json:"error"`
+	}{info})
+	if err != nil {
+		log.Printf("Error: %s", info.text())
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
+
+// text returns the message followed by the hint, as printed on stderr.
+func (info errorInfo) text() string {
+	if info.Hint == "" {
+		return info.Message
+	}
+	return info.Message + " (" + info.Hint + ")"
+}
+
+// describeError classifies err. Validation errors from certimport point at
+// the flag that overrides or resolves them; errors returned by AWS are
+// matched on their error code, and for ACM's ValidationException on the
+// message.
+func describeError(err error, interrupted bool) errorInfo {
+	// Errors reworded by errorHint keep their description, with the
+	// context they were wrapped in since
+	var hinted *hintedError
+	if errors.As(err, &hinted) {
+		info := hinted.info
+		info.Message = strings.Replace(err.Error(), hinted.Error(), info.Message, 1)
+		info.ExitCode = exitCode(err, interrupted)
+		return info
+	}
+
+	info := errorInfo{Message: errorMessage(err), ExitCode: exitCode(err, interrupted)}
+	info.Code, info.Hint = classifyError(err, interrupted, info.ExitCode)
+	return info
+}
+
+func classifyError(err error, interrupted bool, exit int) (string, string) {
+	var apiErr smithy.APIError
+	switch {
+	case interrupted && errors.Is(err, context.Canceled):
+		return codeInterrupted, ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return codeTimeout, "see -timeout"
+	case errors.Is(err, certimport.ErrExpired):
+		return codeExpired, "use -allow-expired to import anyway"
+	case errors.Is(err, certimport.ErrRevoked):
+		return codeRevoked, "use -allow-revoked to import anyway"
+	case errors.Is(err, certimport.ErrNotCovered):
+		return codeNotCovered, "check the names given with -must-cover against the certificate's SANs"
+	case errors.Is(err, certimport.ErrAmbiguousMatch):
+		return codeAmbiguousMatch, "use -certificate-arn to pick one"
+	case errors.Is(err, certimport.ErrKeyMismatch):
+		return codeKeyMismatch, "check that the key is the one the certificate was issued for, not the key of an earlier certificate"
+	case errors.Is(err, certimport.ErrEncryptedKey):
+		return codeKeyEncrypted, "use -key-passphrase or -key-passphrase-file"
+	case errors.Is(err, certimport.ErrKeyPolicy):
+		return codeKeyPolicy, "see -min-rsa-bits, -allowed-algos and -intended-use"
+	case errors.Is(err, certimport.ErrWeakKey):
+		return codeKeyWeak, "generate a new key and have the certificate reissued"
+	case errors.Is(err, certimport.ErrUnrelatedChain):
+		return codeChainUnrelated, "use -fix-chain to drop them"
+	case errors.Is(err, certimport.ErrMissingIssuer):
+		return codeChainIncomplete, "use -fetch-chain to download the intermediates, or give the issuer's chain with -chain"
+	case errors.Is(err, certimport.ErrInvalidChain):
+		return codeChainInvalid, "check the chain with verify-chain"
+	case errors.As(err, &apiErr):
+		return classifyAPIError(apiErr, exit)
+	case exit == exitAuth:
+		return codeNoCredentials, "configure AWS credentials, e.g. with aws configure, AWS_PROFILE or -profile"
+	}
+	return codeError, ""
+}
+
+// classifyAPIError classifies an error returned by AWS.
+func classifyAPIError(apiErr smithy.APIError, exit int) (string, string) {
+	message := strings.ToLower(apiErr.ErrorMessage())
+	switch apiErr.ErrorCode() {
+	case "ValidationException":
+		switch {
+		case strings.Contains(message, "chain"):
+			return codeChainInvalid, "ACM rejected the chain: check its order and completeness with verify-chain, and use -fix-chain or -fetch-chain"
+		case strings.Contains(message, "key") && (strings.Contains(message, "length") || strings.Contains(message, "size") ||
+			strings.Contains(message, "algorithm") || strings.Contains(message, "not supported")):
+			return codeKeyUnsupported, "ACM imports RSA keys of 1024, 2048, 3072 or 4096 bits and ECDSA P-256, P-384 or P-521 keys; have the certificate reissued with one"
+		}
+		return codeInvalidInput, ""
+	case "InvalidArnException", "InvalidParameterException", "InvalidTagException", "InvalidDomainValidationOptionsException":
+		return codeInvalidInput, ""
+	case "LimitExceededException":
+		return codeLimitExceeded, "ACM limits the certificates, and the imports per year, of each account and region: delete unused ones with prune, or request a quota increase in Service Quotas"
+	case "ResourceInUseException":
+		return codeResourceInUse, "describe lists the resources using the certificate under inUseBy; move them to another certificate first, e.g. with rotate"
+	case "ResourceNotFoundException":
+		return codeNotFound, "check the ARN, and that -region and -profile match its region and account"
+	case "TooManyTagsException":
+		return codeTooManyTags, "ACM allows 50 tags per certificate"
+	case "TagPolicyException":
+		return codeTagPolicy, "the tags do not comply with a tag policy of your AWS Organization"
+	case "RequestInProgressException":
+		return codeRequestInProgress, "ACM is still processing the certificate, try again in a few seconds"
+	}
+	switch exit {
+	case exitAuth:
+		return codeAccessDenied, "check that the credentials are for the right account and that their IAM policy allows the action"
+	case exitThrottled:
+		return codeThrottled, "try again later, or raise -max-retries"
+	}
+	return codeError, ""
+}
+
+// errorMessage returns the message of err with the SDK's description of a
+// failed AWS request, which includes the HTTP status and request ID, shortened
+// to the service, operation and AWS's own message.
+func errorMessage(err error) string {
+	message := err.Error()
+	var opErr *smithy.OperationError
+	var apiErr smithy.APIError
+	if !errors.As(err, &opErr) || !errors.As(err, &apiErr) {
+		return message
+	}
+	awsMessage := apiErr.ErrorMessage()
+	if awsMessage == "" {
+		awsMessage = apiErr.ErrorCode()
+	}
+	return strings.Replace(message, opErr.Error(), fmt.Sprintf("%s %s: %s", opErr.Service(), opErr.Operation(), awsMessage), 1)
+}
+
+// hintedError is an error reworded by errorHint. It still wraps the
+// original error, so that exit codes and errors.Is work as before.
+type hintedError struct {
+	info errorInfo
+	err  error
+}
+
+func (e *hintedError) Error() string { return e.info.text() }
+func (e *hintedError) Unwrap() error { return e.err }
+
+// errorHint rewords err as describeError does, with the remediation hint
+// appended.
+func errorHint(err error) error {
+	if err == nil {
+		return nil
+	}
+	var hinted *hintedError
+	if errors.As(err, &hinted) {
+		return err
+	}
+	return &hintedError{info: describeError(err, false), err: err}
+}
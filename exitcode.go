@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// Exit codes, so that scripts can branch on the reason for a failure without
+// parsing stderr. Keep the README table in sync.
+const (
+	exitFailure     = 1   // any other error
+	exitUsage       = 2   // invalid flags or arguments
+	exitInvalid     = 3   // the certificate was rejected before import
+	exitAuth        = 4   // missing, expired or insufficient AWS credentials
+	exitThrottled   = 5   // AWS throttled the requests beyond the retries
+	exitKeyMismatch = 6   // the private key does not belong to the certificate
+	exitPartial     = 7   // some regions or certificates failed, others succeeded
+	exitInterrupted = 130 // cancelled with Ctrl-C or SIGTERM
+)
+
+// authErrorCodes are the AWS error codes for credentials that are missing,
+// expired or lack permissions.
+var authErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"InvalidClientTokenId":        true,
+	"InvalidSignatureException":   true,
+	"SignatureDoesNotMatch":       true,
+	"UnauthorizedOperation":       true,
+	"UnrecognizedClientException": true,
+}
+
+// exitCodeError sets the exit code of an error that exitCode cannot classify
+// from the error itself.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// partialFailure marks err as a failure of some, but not all, of the regions
+// or certificates an operation covered.
+func partialFailure(err error) error {
+	return &exitCodeError{code: exitPartial, err: err}
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error, interrupted bool) int {
+	var codeErr *exitCodeError
+	var apiErr smithy.APIError
+	var signErr *v4.SigningError
+
+	switch {
+	case errors.As(err, &codeErr):
+		return codeErr.code
+	case interrupted && errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, certimport.ErrKeyMismatch):
+		return exitKeyMismatch
+	case errors.Is(err, certimport.ErrExpired),
+		errors.Is(err, certimport.ErrRevoked),
+		errors.Is(err, certimport.ErrWeakKey),
+		errors.Is(err, certimport.ErrUnrelatedChain),
+		errors.Is(err, certimport.ErrMissingIssuer),
+		errors.Is(err, certimport.ErrInvalidChain),
+		errors.Is(err, certimport.ErrEncryptedKey),
+		errors.Is(err, certimport.ErrKeyPolicy),
+		errors.Is(err, certimport.ErrNotCovered):
+		return exitInvalid
+	case errors.As(err, &signErr), strings.Contains(err.Error(), "failed to refresh cached credentials"):
+		// The SDK reports credentials it could not load as plain errors from
+		// the credentials cache, or as signing errors
+		return exitAuth
+	case errors.As(err, &apiErr) && authErrorCodes[apiErr.ErrorCode()]:
+		return exitAuth
+	case retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary:
+		return exitThrottled
+	}
+	return exitFailure
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/inventory"
+)
+
+type ExpiryConfig struct {
+	Days       int
+	Type       string
+	Regions    []string
+	AllRegions bool
+	Output     string
+	Region     string
+	Profile    string
+	Timeout    time.Duration
+}
+
+// expiringCertificate is a certificate flagged by check-expiry.
+type expiringCertificate = inventory.ExpiringCertificate
+
+func runCheckExpiry(ctx context.Context, args []string) error {
+	var cfg ExpiryConfig
+	var regions string
+
+	fs := flag.NewFlagSet("check-expiry", flag.ExitOnError)
+	fs.IntVar(&cfg.Days, "days", 30, "Flag certificates expiring within this many days")
+	fs.StringVar(&cfg.Type, "type", "", "Only check certificates of this type (IMPORTED, AMAZON_ISSUED, PRIVATE)")
+	fs.StringVar(&regions, "regions", "", "Check several regions, e.g. 'us-east-1,eu-west-1'")
+	fs.BoolVar(&cfg.AllRegions, "all-regions", false, "Check every region enabled for the account")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check-expiry [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Report certificates that are expired or expire soon. Exits non-zero if any are found.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s check-expiry -days 14\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s check-expiry -type IMPORTED -all-regions -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	if cfg.Days < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -days must not be negative\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text or json\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Regions = cliutil.SplitList(regions)
+	if len(cfg.Regions) > 0 && cfg.AllRegions {
+		fmt.Fprintf(os.Stderr, "Error: -regions and -all-regions are mutually exclusive\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	cfg.Type = strings.ToUpper(cfg.Type)
+	if err := inventory.CheckType(cfg.Type); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -type: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return checkExpiry(ctx, cfg)
+}
+
+func checkExpiry(ctx context.Context, cfg ExpiryConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	regions := cfg.Regions
+	if cfg.AllRegions {
+		var err error
+		regions, err = enabledRegions(ctx, cfg.Region, cfg.Profile)
+		if err != nil {
+			return err
+		}
+	}
+	if len(regions) == 0 {
+		regions = []string{cfg.Region}
+	}
+
+	expiring, checked, err := inventory.CheckExpiry(ctx, regions, func(ctx context.Context, region string) (*acm.Client, error) {
+		awsCfg, err := loadAWSConfig(ctx, region, cfg.Profile)
+		if err != nil {
+			return nil, err
+		}
+		progress.Printf("Checking certificates in %s...\n", awsCfg.Region)
+		return acm.NewFromConfig(awsCfg), nil
+	}, inventory.Filter{Type: cfg.Type}, cfg.Days)
+	if err != nil {
+		return err
+	}
+
+	if err := printExpiring(cfg, expiring, checked); err != nil {
+		return err
+	}
+	if len(expiring) > 0 {
+		return fmt.Errorf("%d of %d certificates expire within %d days", len(expiring), checked, cfg.Days)
+	}
+	return nil
+}
+
+func printExpiring(cfg ExpiryConfig, expiring []expiringCertificate, checked int) error {
+	if cfg.Output == "json" {
+		return printJSON(expiring)
+	}
+
+	// Quiet mode prints one ARN per line for use in shell pipelines
+	if progress.Quiet() {
+		for _, c := range expiring {
+			fmt.Println(c.CertificateArn)
+		}
+		return nil
+	}
+
+	if len(expiring) == 0 {
+		stdout.Printf("✅ No certificates expire within %d days (%d checked)\n", cfg.Days, checked)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "REGION\tDOMAIN\tTYPE\tEXPIRES\tDAYS\tIN USE\tARN\n")
+	for _, c := range expiring {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%t\t%s\n",
+			c.Region, c.DomainName, c.Type, c.NotAfter.Format("2006-01-02"), c.DaysUntilExpiry, c.InUse, c.CertificateArn)
+	}
+	return w.Flush()
+}
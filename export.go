@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+)
+
+type ExportConfig struct {
+	CertificateArn string
+	OutDir         string
+	ExportKey      bool
+	Passphrase     string
+	PassphraseFile string
+	Force          bool
+	Output         string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+// exportResult is the JSON representation of an export.
+type exportResult struct {
+	CertificateArn string   `json:"certificateArn"`
+	Files          []string `json:"files"`
+}
+
+// exportFile is a PEM file written by export.
+type exportFile struct {
+	name string
+	data string
+	mode os.FileMode
+}
+
+func runExport(ctx context.Context, args []string) error {
+	var cfg ExportConfig
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to export - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.StringVar(&cfg.OutDir, "out-dir", ".", "Directory to write cert.pem, chain.pem and fullchain.pem (and privkey.pem) to")
+	fs.BoolVar(&cfg.ExportKey, "export-key", false, "Also export the private key of an exportable certificate, encrypted with -passphrase")
+	fs.StringVar(&cfg.Passphrase, "passphrase", "", "Passphrase to encrypt the exported private key with (at least 4 characters)")
+	fs.StringVar(&cfg.PassphraseFile, "passphrase-file", "", "File containing the passphrase to encrypt the exported private key with")
+	fs.BoolVar(&cfg.Force, "force", false, "Overwrite existing files")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export -arn <arn> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Download a certificate and its chain from ACM as PEM files. Private keys can only be\n")
+		fmt.Fprintf(os.Stderr, "exported for certificates issued by ACM Private CA or requested as exportable.\n")
+		fmt.Fprintf(os.Stderr, "The region is taken from -arn.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s export -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -out-dir ./tls\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s export -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -export-key -passphrase-file key.pass\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if cfg.CertificateArn == "" {
+		fail(errors.New("-arn is required"))
+	}
+	region, err := arnRegion(cfg.CertificateArn)
+	if err != nil {
+		fail(err)
+	}
+	cfg.Region = region
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fail(errors.New("-output must be text or json"))
+	}
+	if !cfg.ExportKey && (cfg.Passphrase != "" || cfg.PassphraseFile != "") {
+		fail(errors.New("-passphrase and -passphrase-file require -export-key"))
+	}
+	if cfg.ExportKey {
+		cfg.Passphrase, err = readPassphrase(cfg.Passphrase, cfg.PassphraseFile)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Passphrase) < 4 {
+			fail(errors.New("-export-key needs a -passphrase or -passphrase-file of at least 4 characters"))
+		}
+	}
+
+	return exportCertificate(ctx, cfg)
+}
+
+func exportCertificate(ctx context.Context, cfg ExportConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	client := acm.NewFromConfig(awsCfg)
+
+	var certPEM, chainPEM, keyPEM string
+	if cfg.ExportKey {
+		// ExportCertificate returns the certificate and chain along with
+		// the key, encrypted with the passphrase
+		progress.Printf("Exporting %s...\n", cfg.CertificateArn)
+		out, err := client.ExportCertificate(ctx, &acm.ExportCertificateInput{
+			CertificateArn: aws.String(cfg.CertificateArn),
+			Passphrase:     []byte(cfg.Passphrase),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export certificate: %w", err)
+		}
+		certPEM, chainPEM, keyPEM = aws.ToString(out.Certificate), aws.ToString(out.CertificateChain), aws.ToString(out.PrivateKey)
+	} else {
+		progress.Printf("Downloading %s...\n", cfg.CertificateArn)
+		out, err := client.GetCertificate(ctx, &acm.GetCertificateInput{CertificateArn: aws.String(cfg.CertificateArn)})
+		if err != nil {
+			return fmt.Errorf("failed to get certificate: %w", err)
+		}
+		certPEM, chainPEM = aws.ToString(out.Certificate), aws.ToString(out.CertificateChain)
+	}
+
+	files := []exportFile{
+		{"cert.pem", certPEM, 0o644},
+		{"chain.pem", chainPEM, 0o644},
+		{"fullchain.pem", withNewline(certPEM) + chainPEM, 0o644},
+	}
+	if keyPEM != "" {
+		files = append(files, exportFile{"privkey.pem", keyPEM, 0o600})
+	}
+
+	if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", cfg.OutDir, err)
+	}
+	result := exportResult{CertificateArn: cfg.CertificateArn, Files: []string{}}
+	for _, f := range files {
+		path := filepath.Join(cfg.OutDir, f.name)
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if cfg.Force {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		if err := writeNewFile(path, flags, []byte(withNewline(f.data)), f.mode); err != nil {
+			return err
+		}
+		result.Files = append(result.Files, path)
+	}
+
+	if cfg.Output == "json" {
+		return printJSON(result)
+	}
+	if progress.Quiet() {
+		return nil
+	}
+	stdout.Printf("✅ Certificate exported successfully!\n")
+	for _, path := range result.Files {
+		fmt.Printf("  %s\n", path)
+	}
+	return nil
+}
+
+// writeNewFile writes data to path, refusing to replace an existing file
+// unless flags allow it.
+func writeNewFile(path string, flags int, data []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(path, flags, mode)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists (use -force to overwrite)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// withNewline makes sure PEM data ends in a newline so files can be
+// concatenated.
+func withNewline(data string) string {
+	if data == "" || data[len(data)-1] == '\n' {
+		return data
+	}
+	return data + "\n"
+}
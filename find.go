@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+)
+
+func runFind(ctx context.Context, args []string) error {
+	var cfg ListConfig
+
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	addFindFilterFlags(fs, &cfg)
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text (one ARN per line) or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s find [-tag key=value]... [-domain <glob>] [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Print the ARNs of the certificates matching every filter, one per line, and exit non-zero\n")
+		fmt.Fprintf(os.Stderr, "if none match. Commands that take -arn accept the same filters with -arn-from-find\n")
+		fmt.Fprintf(os.Stderr, "instead, as long as they match exactly one certificate.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s find -tag Environment=prod -domain '*.example.com'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s describe -arn-from-find '-tag Environment=prod -domain *.example.com -status ISSUED'\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	if !cfg.filtered() {
+		fmt.Fprintf(os.Stderr, "Error: at least one of -tag, -domain, -type and -status is required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text or json\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return findArns(ctx, cfg)
+}
+
+// addFindFilterFlags registers the filters of find, which -arn-from-find
+// parses too.
+func addFindFilterFlags(fs *flag.FlagSet, cfg *ListConfig) {
+	fs.Func("tag", "Only match certificates with this tag, in format 'key=value' (may be repeated)", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid tag %q, expected key=value", value)
+		}
+		if cfg.Tags == nil {
+			cfg.Tags = map[string]string{}
+		}
+		cfg.Tags[key] = val
+		return nil
+	})
+	fs.StringVar(&cfg.DomainGlob, "domain", "", "Only match certificates whose domain or SANs match this glob, e.g. '*.example.com'")
+	fs.Func("type", "Only match certificates of this type (IMPORTED, AMAZON_ISSUED, PRIVATE)", func(value string) error {
+		cfg.Type = strings.ToUpper(value)
+		return nil
+	})
+	fs.Func("status", "Only match certificates with these statuses, e.g. 'ISSUED'", func(value string) error {
+		cfg.Statuses = cliutil.SplitList(strings.ToUpper(value))
+		return nil
+	})
+}
+
+// filtered reports whether cfg has a filter, so that find never matches
+// every certificate by accident.
+func (cfg ListConfig) filtered() bool {
+	return len(cfg.Tags) > 0 || cfg.DomainGlob != "" || cfg.Type != "" || len(cfg.Statuses) > 0
+}
+
+func findArns(ctx context.Context, cfg ListConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	certs, err := findCertificates(ctx, acm.NewFromConfig(awsCfg), cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == "json" {
+		if certs == nil {
+			certs = []listedCertificate{}
+		}
+		if err := printJSON(certs); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range certs {
+			fmt.Println(c.CertificateArn)
+		}
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificates match (region: %s)", awsCfg.Region)
+	}
+	progress.Debugf("%d certificates match\n", len(certs))
+	return nil
+}
+
+// arnFinder fills in the -arn of a command from the find filters given
+// with -arn-from-find.
+type arnFinder struct {
+	fs    *flag.FlagSet
+	arn   *string
+	query string
+}
+
+// addArnFromFindFlag registers -arn-from-find as an alternative to the -arn
+// flag stored in arn.
+func addArnFromFindFlag(fs *flag.FlagSet, arn *string) *arnFinder {
+	f := &arnFinder{fs: fs, arn: arn}
+	fs.StringVar(&f.query, "arn-from-find", "", "Use the one certificate matching these find filters instead of -arn, e.g. '-tag Environment=prod -domain *.example.com'")
+	return f
+}
+
+// resolve looks up the certificate matching the -arn-from-find filters in
+// region and sets -arn to it. Like parseFlags it exits on invalid flags;
+// finding no certificate or more than one is an error.
+func (f *arnFinder) resolve(ctx context.Context, region, profile string) error {
+	if f.query == "" {
+		return nil
+	}
+	cfg, err := parseFindQuery(f.query)
+	if err == nil && *f.arn != "" {
+		err = errors.New("-arn and -arn-from-find cannot be combined")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		f.fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	awsCfg, err := loadAWSConfig(ctx, region, profile)
+	if err != nil {
+		return err
+	}
+	certs, err := findCertificates(ctx, acm.NewFromConfig(awsCfg), cfg)
+	if err != nil {
+		return err
+	}
+	switch len(certs) {
+	case 0:
+		return fmt.Errorf("no certificate matches -arn-from-find %q (region: %s)", f.query, awsCfg.Region)
+	case 1:
+		*f.arn = certs[0].CertificateArn
+		progress.Printf("✓ Found %s (%s)\n", certs[0].CertificateArn, certs[0].DomainName)
+		return nil
+	}
+	arns := make([]string, len(certs))
+	for i, c := range certs {
+		arns[i] = c.CertificateArn
+	}
+	return fmt.Errorf("%d certificates match -arn-from-find %q, narrow it down to one:\n  %s", len(certs), f.query, strings.Join(arns, "\n  "))
+}
+
+// parseFindQuery parses the value of -arn-from-find, the filters of find
+// separated by spaces.
+func parseFindQuery(query string) (ListConfig, error) {
+	var cfg ListConfig
+	fs := flag.NewFlagSet("-arn-from-find", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addFindFilterFlags(fs, &cfg)
+	if err := fs.Parse(strings.Fields(query)); err != nil {
+		return cfg, fmt.Errorf("invalid -arn-from-find %q: %w", query, err)
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("invalid -arn-from-find %q: unexpected %q", query, fs.Arg(0))
+	}
+	if !cfg.filtered() {
+		return cfg, fmt.Errorf("-arn-from-find %q has no filters", query)
+	}
+	return cfg, nil
+}
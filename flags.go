@@ -0,0 +1,12 @@
+package main
+
+// countSet returns how many of values are non-empty.
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// hooks are shell commands run around an import: the pre-hook once the
+// certificate has been validated, where a failure stops the import, and the
+// post-hook once it has been imported. The zero value runs nothing.
+type hooks struct {
+	Pre  string
+	Post string
+}
+
+// Hook phases, passed to the hooks in CERT_HOOK.
+const (
+	preHook  = "pre"
+	postHook = "post"
+)
+
+// addHookFlags registers -pre-hook and -post-hook.
+func addHookFlags(fs *flag.FlagSet, h *hooks) {
+	fs.StringVar(&h.Pre, "pre-hook", "", "Shell command to run once the certificate is validated, before importing; a non-zero exit stops the import")
+	fs.StringVar(&h.Post, "post-hook", "", "Shell command to run after an import or re-import, e.g. to deploy or invalidate caches (not run when ACM already had the certificate)")
+}
+
+// hooksHelp describes the environment of the hooks, for usage messages.
+const hooksHelp = `Hooks run with sh -c (cmd /C on Windows), with their output on stderr and these variables set:
+  CERT_HOOK         pre or post
+  CERT_EVENT        imported or reimported (post-hook)
+  CERT_NAME         the manifest entry or certbot lineage, if any
+  CERT_DOMAIN       the certificate's common name
+  CERT_SANS         its DNS names, comma-separated
+  CERT_FINGERPRINT  its SHA-256 fingerprint
+  CERT_NOT_AFTER    its expiry, in RFC 3339 format
+  CERT_DAYS_LEFT    the days until it expires
+  CERT_REGIONS      the regions, comma-separated
+  CERT_ARN          the ARN in the first region (pre-hook: only when re-importing over -certificate-arn)
+  CERT_ARNS         the ARNs in every region, comma-separated
+`
+
+// hookRegions returns the regions an import goes to: regions, or the
+// region of awsCfg when there is only one.
+func hookRegions(awsCfg aws.Config, regions []string) []string {
+	if len(regions) == 0 {
+		return []string{awsCfg.Region}
+	}
+	return regions
+}
+
+// runPre runs the pre-hook for leaf, which is about to be imported into
+// regions. certificateArn is the certificate being re-imported over, if
+// known. Dry runs only log the hook.
+func (h hooks) runPre(ctx context.Context, logger certimport.Logger, name string, leaf *x509.Certificate, regions []string, certificateArn string, dryRun bool) error {
+	if h.Pre == "" {
+		return nil
+	}
+	if dryRun {
+		logger.Printf("Would run the pre-hook\n")
+		return nil
+	}
+	targets := make([]regionResult, len(regions))
+	for i, region := range regions {
+		targets[i] = regionResult{Region: region, CertificateArn: certificateArn}
+	}
+	return runHook(ctx, logger, preHook, h.Pre, hookEnv(preHook, "", name, leaf, targets))
+}
+
+// runPost runs the post-hook for the regions of r that were imported or
+// re-imported. It is skipped for dry runs and when every region already
+// had the certificate.
+func (h hooks) runPost(ctx context.Context, logger certimport.Logger, leaf *x509.Certificate, r batchResult) error {
+	if h.Post == "" || r.DryRun {
+		return nil
+	}
+	event := eventImported
+	var changed []regionResult
+	for _, region := range r.Regions {
+		if region.Error != "" || region.Existing {
+			continue
+		}
+		if region.Reimported {
+			event = eventReimported
+		}
+		changed = append(changed, region)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return runHook(ctx, logger, postHook, h.Post, hookEnv(postHook, event, r.Name, leaf, changed))
+}
+
+// hookEnv returns the CERT_* variables describing the certificate to a
+// hook. They are not AWS_CERTS_* variables, which set flag defaults, so that
+// a hook can run aws-certs itself.
+func hookEnv(phase, event, name string, leaf *x509.Certificate, regions []regionResult) []string {
+	var names, arns []string
+	for _, r := range regions {
+		names = append(names, r.Region)
+		if r.CertificateArn != "" {
+			arns = append(arns, r.CertificateArn)
+		}
+	}
+	first := ""
+	if len(arns) > 0 {
+		first = arns[0]
+	}
+	return []string{
+		"CERT_HOOK=" + phase,
+		"CERT_EVENT=" + event,
+		"CERT_NAME=" + name,
+		"CERT_DOMAIN=" + leaf.Subject.CommonName,
+		"CERT_SANS=" + strings.Join(leaf.DNSNames, ","),
+		"CERT_FINGERPRINT=" + certimport.Fingerprint(leaf),
+		"CERT_NOT_AFTER=" + leaf.NotAfter.UTC().Format(time.RFC3339),
+		"CERT_DAYS_LEFT=" + strconv.Itoa(daysUntil(leaf.NotAfter)),
+		"CERT_REGIONS=" + strings.Join(names, ","),
+		"CERT_ARN=" + first,
+		"CERT_ARNS=" + strings.Join(arns, ","),
+	}
+}
+
+// runHook runs command in the shell. Its output goes to stderr, since
+// stdout carries the results of the import.
+func runHook(ctx context.Context, logger certimport.Logger, phase, command string, env []string) error {
+	shell, arg := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, arg = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, arg, command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	logger.Printf("Running the %s-hook...\n", phase)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s-hook failed: %w", phase, err)
+	}
+	logger.Printf("✓ %s-hook finished\n", strings.ToUpper(phase[:1])+phase[1:])
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// iamNameChars matches the characters IAM does not allow in a server
+// certificate name.
+var iamNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// iamResult is the JSON representation of an uploaded server certificate.
+type iamResult struct {
+	ServerCertificateName string    `json:"serverCertificateName"`
+	ServerCertificateId   string    `json:"serverCertificateId,omitempty"`
+	Arn                   string    `json:"arn,omitempty"`
+	Path                  string    `json:"path"`
+	DomainName            string    `json:"domainName"`
+	NotAfter              time.Time `json:"notAfter"`
+	DaysUntilExpiry       int       `json:"daysUntilExpiry"`
+	DryRun                bool      `json:"dryRun,omitempty"`
+	Warnings              []string  `json:"warnings"`
+}
+
+// validateIAM checks the options of an -iam upload. IAM is global, and
+// server certificates cannot be replaced in place.
+func (cfg *CertImportConfig) validateIAM() error {
+	if !cfg.IAM {
+		if cfg.IAMName != "" || cfg.IAMPath != "" {
+			return errors.New("-iam-name and -iam-path require -iam")
+		}
+		return nil
+	}
+	if len(cfg.Regions) > 0 || cfg.AllRegions || cfg.CertificateArn != "" || cfg.Upsert || cfg.Dir != "" || cfg.TargetsFile != "" {
+		return errors.New("-iam cannot be combined with -regions, -all-regions, -certificate-arn, -upsert, -dir or -targets")
+	}
+	if cfg.IAMPath != "" && (!strings.HasPrefix(cfg.IAMPath, "/") || !strings.HasSuffix(cfg.IAMPath, "/")) {
+		return errors.New("-iam-path must begin and end with /, e.g. /cloudfront/")
+	}
+	return nil
+}
+
+// iamCertificateName derives a server certificate name from the domain and
+// expiry date, e.g. wildcard.example.com-2025-06-30.
+func iamCertificateName(leaf *x509.Certificate) string {
+	domain := leaf.Subject.CommonName
+	if domain == "" && len(leaf.DNSNames) > 0 {
+		domain = leaf.DNSNames[0]
+	}
+	domain = strings.Replace(domain, "*", "wildcard", 1)
+	name := iamNameChars.ReplaceAllString(domain, "_") + "-" + leaf.NotAfter.Format("2006-01-02")
+	if len(name) > 128 {
+		name = name[len(name)-128:]
+	}
+	return name
+}
+
+// uploadServerCertificate validates material like an ACM import and uploads
+// it as an IAM server certificate.
+func uploadServerCertificate(ctx context.Context, cfg CertImportConfig, awsCfg aws.Config, material *certimport.Material) error {
+	// IAM keeps every upload, so the identical-certificate lookup in ACM
+	// does not apply
+	cfg.Force = true
+	_, prepared, err := prepareImport(ctx, awsCfg, progress, cfg, material, nil)
+	if err != nil {
+		return err
+	}
+	defer prepared.Clear()
+	progress.Printf("✓ Fingerprint (SHA-256): %s\n", certimport.Fingerprint(prepared.Leaf))
+
+	result := iamResult{
+		ServerCertificateName: cfg.IAMName,
+		Path:                  cfg.IAMPath,
+		DomainName:            prepared.Leaf.Subject.CommonName,
+		NotAfter:              prepared.Leaf.NotAfter,
+		DaysUntilExpiry:       daysUntil(prepared.Leaf.NotAfter),
+		DryRun:                cfg.DryRun,
+	}
+	if result.ServerCertificateName == "" {
+		result.ServerCertificateName = iamCertificateName(prepared.Leaf)
+	}
+	if result.Path == "" {
+		result.Path = "/"
+	}
+
+	if !cfg.DryRun {
+		input := &iam.UploadServerCertificateInput{
+			ServerCertificateName: aws.String(result.ServerCertificateName),
+			Path:                  aws.String(result.Path),
+			CertificateBody:       aws.String(string(prepared.Input.Certificate)),
+			PrivateKey:            aws.String(string(prepared.Input.PrivateKey)),
+		}
+		if len(prepared.Input.CertificateChain) > 0 {
+			input.CertificateChain = aws.String(string(prepared.Input.CertificateChain))
+		}
+		for _, tag := range prepared.Tags {
+			input.Tags = append(input.Tags, iamtypes.Tag{Key: tag.Key, Value: tag.Value})
+		}
+
+		progress.Printf("Uploading IAM server certificate %s...\n", result.ServerCertificateName)
+		out, err := iam.NewFromConfig(awsCfg).UploadServerCertificate(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to upload IAM server certificate: %w", certimport.RedactError(err, prepared.Input.PrivateKey))
+		}
+		result.Arn = aws.ToString(out.ServerCertificateMetadata.Arn)
+		result.ServerCertificateId = aws.ToString(out.ServerCertificateMetadata.ServerCertificateId)
+	}
+
+	if cfg.Output == "json" {
+		result.Warnings = progress.Warnings()
+		return printJSON(result)
+	}
+	if progress.Quiet() {
+		if result.Arn != "" {
+			fmt.Println(result.Arn)
+		}
+		return nil
+	}
+	if cfg.DryRun {
+		fmt.Printf("Dry run: would upload IAM server certificate %s%s\n", result.Path, result.ServerCertificateName)
+		progress.Printf("✅ Dry run complete, no changes made\n")
+		return nil
+	}
+	stdout.Printf("✅ IAM server certificate uploaded successfully!\n")
+	fmt.Printf("Server Certificate ARN: %s\n", result.Arn)
+	fmt.Printf("Server Certificate ID:  %s\n", result.ServerCertificateId)
+	fmt.Printf("Expires: %s (%d days)\n", result.NotAfter.Format("2006-01-02"), result.DaysUntilExpiry)
+	return nil
+}
@@ -0,0 +1,762 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type CertImportConfig struct {
+	CertFile            string
+	PrivateKeyFile      string
+	ChainFile           string
+	PKCS12File          string
+	BundleFile          string
+	SecretDir           string
+	FromWindowsStore    bool
+	Thumbprint          string
+	StoreLocation       string
+	FromKeychain        bool
+	KeychainName        string
+	Dir                 string
+	CertEnv             string
+	KeyEnv              string
+	ChainEnv            string
+	CertSecret          string
+	KeySecret           string
+	ChainSecret         string
+	CertData            string
+	KeyData             string
+	ChainData           string
+	Passphrase          string
+	PassphraseFile      string
+	KeyPassphrase       string
+	KeyPassphraseFile   string
+	KeyKMSEncrypted     bool
+	KMSKeyID            string
+	NoPrompt            bool
+	CertificateArn      string
+	FixChain            bool
+	FetchChain          bool
+	Region              string
+	Regions             []string
+	AllRegions          bool
+	DryRun              bool
+	AllowExpired        bool
+	CheckRevocation     bool
+	AllowRevoked        bool
+	CheckCT             bool
+	DebianBlocklists    []string
+	StrictKeyFiles      bool
+	DeleteKey           bool
+	AutoTags            bool
+	Force               bool
+	Upsert              bool
+	Wait                bool
+	UpsertTags          map[string]string
+	PublishArn          []publishTarget
+	AttachListeners     []string
+	AttachDistributions []string
+	AttachAPIDomains    []string
+	TargetsFile         string
+	IAM                 bool
+	IAMName             string
+	IAMPath             string
+	Concurrency         int
+	MinRSABits          int
+	KeyAlgorithms       []string
+	IntendedUses        []string
+	MustCover           []string
+	Daemon              bool
+	Notify              notifier
+	Hooks               hooks
+	Pins                pins
+	Interval            time.Duration
+	Output              string
+	Profile             string
+	Timeout             time.Duration
+	Tags                map[string]string
+}
+
+func runImport(ctx context.Context, args []string) error {
+	var cfg CertImportConfig
+	var interactive bool
+	var tagString, tagsFile, regions, publishArn, attachListeners, attachDistributions, attachAPIDomains, keyAlgorithms, intendedUses, mustCover, debianBlocklists string
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+
+	// Define command line flags
+	addInputFlags(fs, &cfg)
+	fs.StringVar(&cfg.Dir, "dir", "", "Scan a directory tree for certificate and key pairs and import them all")
+	fs.StringVar(&cfg.CertificateArn, "certificate-arn", "", "ARN of an existing imported certificate to replace in-place - OPTIONAL")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+	fs.StringVar(&regions, "regions", "", "Import into several regions concurrently, e.g. 'us-east-1,eu-west-1'")
+	fs.BoolVar(&cfg.AllRegions, "all-regions", false, "Import into every region enabled for the account")
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.BoolVar(&cfg.AutoTags, "auto-tags", false, "Add tags describing the certificate and the import (Domain, Issuer, NotAfter, ImportedBy, ...)")
+	fs.StringVar(&tagsFile, "tags-file", "", "JSON or YAML file with a map of tag keys to values")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text, json, terraform (aws_acm_certificate resources and terraform import commands) or cfn-params (CloudFormation parameter file)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Validate everything and show what would be imported without importing")
+	fs.BoolVar(&cfg.AllowExpired, "allow-expired", false, "Import certificates that are expired or not yet valid")
+	fs.BoolVar(&cfg.CheckRevocation, "check-revocation", false, "Ask the CA via OCSP (or the CRL) whether the certificate was revoked and refuse revoked certificates")
+	fs.BoolVar(&cfg.AllowRevoked, "allow-revoked", false, "Import revoked certificates with a warning (with -check-revocation)")
+	fs.BoolVar(&cfg.CheckCT, "check-ct", false, "Warn when the certificate has no embedded SCTs and is not found in Certificate Transparency logs (crt.sh)")
+	fs.BoolVar(&cfg.Upsert, "upsert", false, "Re-import over an existing imported certificate with the same domain and SANs")
+	fs.BoolVar(&cfg.Wait, "wait", false, "After importing, wait until ACM reports the certificate as ISSUED and verify it matches the file")
+	fs.BoolVar(&cfg.Force, "force", false, "Import even if an identical certificate already exists in ACM")
+	fs.BoolVar(&cfg.DeleteKey, "delete-key-after-import", false, "Overwrite and remove the local private key file once the import succeeded")
+	fs.StringVar(&publishArn, "publish-arn", "", "After importing, write the ARN to 'ssm:/path/to/param' or 'secretsmanager:name' (comma-separated for several)")
+	fs.StringVar(&attachListeners, "attach-listener", "", "After importing, make the certificate the default of these ALB/NLB listener ARNs (comma-separated)")
+	fs.StringVar(&attachDistributions, "attach-distribution", "", "After importing into us-east-1, use the certificate for these CloudFront distribution IDs (comma-separated)")
+	fs.StringVar(&attachAPIDomains, "attach-apigw", "", "After importing, use the certificate for these API Gateway custom domain names in -region (comma-separated)")
+	fs.StringVar(&cfg.TargetsFile, "targets", "", "YAML or JSON file listing accounts (profile and/or roleArn) and regions to import into")
+	fs.BoolVar(&cfg.IAM, "iam", false, "Upload as an IAM server certificate instead of importing into ACM")
+	fs.StringVar(&cfg.IAMName, "iam-name", "", "Name of the IAM server certificate (default: <domain>-<expiry date>)")
+	fs.StringVar(&cfg.IAMPath, "iam-path", "", "Path of the IAM server certificate, e.g. /cloudfront/ (default: /)")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 4, "Number of -targets to import into at the same time")
+	addRateFlags(fs)
+	fs.IntVar(&cfg.MinRSABits, "min-rsa-bits", certimport.DefaultMinRSABits, "Reject RSA keys shorter than this many bits")
+	fs.StringVar(&keyAlgorithms, "allowed-algos", "", "Only accept these key algorithms, e.g. 'RSA,ECDSA_P256' ("+certimport.KeyAlgorithms+")")
+	fs.StringVar(&mustCover, "must-cover", "", "Fail unless the certificate covers these hostnames, e.g. 'example.com,www.example.com'")
+	fs.StringVar(&debianBlocklists, "debian-blocklist", "", "openssl-blacklist files of Debian weak keys to refuse (default: "+debianBlocklistGlob+")")
+	fs.StringVar(&intendedUses, "intended-use", "", "Reject keys these services cannot use: elb, cloudfront (implied by -attach-listener and -attach-distribution)")
+	addNotifyFlags(fs, &cfg.Notify)
+	addHookFlags(fs, &cfg.Hooks)
+	addPinFlags(fs, &cfg.Pins)
+	fs.BoolVar(&cfg.Daemon, "daemon", false, "Keep running and re-import whenever the certificate changes (checked every -interval and when local files change)")
+	fs.DurationVar(&cfg.Interval, "interval", 24*time.Hour, "How often -daemon re-reads the certificate sources")
+	fs.BoolVar(&interactive, "interactive", false, "Choose the files (with a preview), regions and tags step by step and confirm a summary before importing")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "AWS Certificate Manager Import CLI\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s import [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Import SSL/TLS certificates into AWS Certificate Manager\n\n")
+		fmt.Fprintf(os.Stderr, "Required Options:\n")
+		fmt.Fprintf(os.Stderr, "  -cert string    Path to certificate file (PEM or DER format)\n")
+		fmt.Fprintf(os.Stderr, "  -key string     Path to private key file (PEM or DER format)\n")
+		fmt.Fprintf(os.Stderr, "  or\n")
+		fmt.Fprintf(os.Stderr, "  -pkcs12 string  Path to PKCS#12 bundle (.pfx/.p12)\n")
+		fmt.Fprintf(os.Stderr, "  or\n")
+		fmt.Fprintf(os.Stderr, "  -bundle string  Path to PEM bundle with certificate, chain and key\n")
+		fmt.Fprintf(os.Stderr, "  or\n")
+		fmt.Fprintf(os.Stderr, "  -from-secret-dir string  Directory with tls.crt, tls.key and ca.crt (cert-manager)\n")
+		fmt.Fprintf(os.Stderr, "  or\n")
+		fmt.Fprintf(os.Stderr, "  -from-windows-store -thumbprint string  Certificate in the Windows certificate store\n")
+		fmt.Fprintf(os.Stderr, "  or\n")
+		fmt.Fprintf(os.Stderr, "  -from-keychain -name string  Certificate in the macOS Keychain\n")
+		fmt.Fprintf(os.Stderr, "  or\n")
+		fmt.Fprintf(os.Stderr, "  -dir string     Directory to scan for certificate and key pairs\n\n")
+		fmt.Fprintf(os.Stderr, "Optional Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\n%s", hooksHelp)
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key private-key.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -interactive\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -chain chain.pem -region us-west-2\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -tags 'Environment=prod,Application=web'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -tags-file tags.yaml -tag 'Owner=team-a'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -chain chain.pem -regions us-east-1,eu-west-1\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -pkcs12 bundle.pfx -passphrase-file pfx.pass\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -bundle combined.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem.kms -key-kms-encrypted -kms-key-id alias/tls-keys\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -dir /etc/letsencrypt/live -upsert\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -from-secret-dir /var/run/secrets/tls -upsert\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -from-windows-store -thumbprint 3B1EFD3A66EA28B16697394703A72CA340A05BD5 -fetch-chain\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -from-keychain -name 'www.example.com'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert-secret prod/tls#cert -key-secret prod/tls#key -chain-secret prod/tls#chain\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert ssm:///tls/cert -key ssm:///tls/key -chain ssm:///tls/chain\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert s3://certs-bucket/example.com/cert.pem -key s3://certs-bucket/example.com/key.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert vault://secret/data/tls#certificate -key vault://secret/data/tls#private_key\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -bundle 'vault://pki/issue/web?common_name=www.example.com'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -upsert -post-hook 'systemctl reload nginx'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  vault read -field=certificate secret/tls | %s import -cert - -key-env TLS_KEY\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -chain chain.pem -upsert\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -force\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -wait\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -publish-arn ssm:/certs/example.com/arn\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -pins-file network_security_config.xml -backup-pin next-key.pub\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -chain chain.pem -iam -iam-path /cloudfront/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert wildcard.pem -key wildcard.key -targets accounts.yaml -upsert\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert cert.pem -key key.pem -certificate-arn arn:aws:acm:us-east-1:123456789012:certificate/abc\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import -cert /etc/letsencrypt/live/example.com/cert.pem -key /etc/letsencrypt/live/example.com/privkey.pem -daemon -interval 6h\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	cfg.Regions = cliutil.SplitList(regions)
+	cfg.AttachListeners = cliutil.SplitList(attachListeners)
+	cfg.AttachDistributions = cliutil.SplitList(attachDistributions)
+	cfg.AttachAPIDomains = cliutil.SplitList(attachAPIDomains)
+	cfg.KeyAlgorithms = cliutil.SplitList(strings.ToUpper(keyAlgorithms))
+	cfg.IntendedUses = cliutil.SplitList(strings.ToLower(intendedUses))
+	cfg.MustCover = cliutil.SplitList(mustCover)
+	cfg.DebianBlocklists = cliutil.SplitList(debianBlocklists)
+
+	var err error
+	cfg.PublishArn, err = parsePublishTargets(publishArn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	// Merge tags from the file and flags; inline flags override the file
+	var fileTags, inlineTags map[string]string
+	if tagsFile != "" {
+		fileTags, err = loadTagsFile(tagsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Tags = mergeTags(fileTags, inlineTags, tagList)
+
+	if interactive {
+		if err := cfg.runWizard(); err != nil {
+			if errors.Is(err, errCancelled) {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if cfg.Daemon {
+		return watchImport(ctx, cfg)
+	}
+	if cfg.Dir != "" {
+		return importDir(ctx, cfg)
+	}
+	if cfg.TargetsFile != "" {
+		return importToTargets(ctx, cfg)
+	}
+
+	// Import the certificate
+	if err := importCertificate(ctx, cfg); err != nil {
+		return fmt.Errorf("failed to import certificate: %w", err)
+	}
+	return nil
+}
+
+// addInputFlags registers the flags naming the certificate, key and chain to
+// import and how to decrypt them.
+func addInputFlags(fs *flag.FlagSet, cfg *CertImportConfig) {
+	fs.StringVar(&cfg.CertFile, "cert", "", "Path to certificate file (PEM or DER format), - for stdin, or an ssm://, s3:// or vault:// URI - REQUIRED")
+	fs.StringVar(&cfg.PrivateKeyFile, "key", "", "Path to private key file (PEM or DER format), - for stdin, or an ssm://, s3:// or vault:// URI - REQUIRED")
+	fs.StringVar(&cfg.ChainFile, "chain", "", "Path to certificate chain file (PEM or DER format), - for stdin, or an ssm://, s3:// or vault:// URI - OPTIONAL")
+	fs.StringVar(&cfg.CertEnv, "cert-env", "", "Environment variable holding the certificate, instead of -cert")
+	fs.StringVar(&cfg.KeyEnv, "key-env", "", "Environment variable holding the private key, instead of -key")
+	fs.StringVar(&cfg.ChainEnv, "chain-env", "", "Environment variable holding the certificate chain, instead of -chain")
+	fs.StringVar(&cfg.CertSecret, "cert-secret", "", "Secrets Manager secret name or ARN holding the certificate ('name#jsonKey' selects a JSON field)")
+	fs.StringVar(&cfg.KeySecret, "key-secret", "", "Secrets Manager secret name or ARN holding the private key ('name#jsonKey' selects a JSON field)")
+	fs.StringVar(&cfg.ChainSecret, "chain-secret", "", "Secrets Manager secret name or ARN holding the certificate chain ('name#jsonKey' selects a JSON field)")
+	fs.StringVar(&cfg.CertData, "cert-data", "", "Base64-encoded certificate, instead of -cert, e.g. \"$(base64 -w0 cert.pem)\"")
+	fs.StringVar(&cfg.KeyData, "key-data", "", "Base64-encoded private key, instead of -key (visible to other local users in the process list; prefer -key-env for secrets)")
+	fs.StringVar(&cfg.ChainData, "chain-data", "", "Base64-encoded certificate chain, instead of -chain")
+	fs.StringVar(&cfg.BundleFile, "bundle", "", "Path to a single PEM file containing the certificate, chain and private key, - for stdin, or an ssm://, s3:// or vault:// URI")
+	fs.StringVar(&cfg.SecretDir, "from-secret-dir", "", "Directory with tls.crt (leaf and intermediates), tls.key and optional ca.crt, as written by cert-manager or a mounted Kubernetes TLS secret")
+	fs.BoolVar(&cfg.FromWindowsStore, "from-windows-store", false, "Export the certificate and its exportable private key from the Windows personal certificate store (Windows only)")
+	fs.StringVar(&cfg.Thumbprint, "thumbprint", "", "SHA-1 thumbprint of the certificate to export with -from-windows-store")
+	fs.StringVar(&cfg.StoreLocation, "store-location", "", "Store to search with -from-windows-store: user or machine (default: user, then machine)")
+	fs.BoolVar(&cfg.FromKeychain, "from-keychain", false, "Export the certificate named by -name and its private key from the login or System keychain (macOS only)")
+	fs.StringVar(&cfg.KeychainName, "name", "", "Common name of the Keychain certificate to export with -from-keychain (the latest expiring one if several)")
+	fs.StringVar(&cfg.KeyPassphrase, "key-passphrase", "", "Passphrase for an encrypted private key (prompted for if omitted)")
+	fs.StringVar(&cfg.KeyPassphraseFile, "key-passphrase-file", "", "File containing the passphrase for an encrypted private key")
+	fs.BoolVar(&cfg.KeyKMSEncrypted, "key-kms-encrypted", false, "The private key input is a KMS ciphertext (raw or base64, as from 'aws kms encrypt'), decrypted with -kms-key-id at import time")
+	fs.StringVar(&cfg.KMSKeyID, "kms-key-id", "", "ID, ARN or alias of the KMS key that encrypted the private key, for -key-kms-encrypted")
+	fs.StringVar(&cfg.PKCS12File, "pkcs12", "", "Path to a PKCS#12 (.pfx/.p12) bundle, instead of -cert and -key")
+	fs.StringVar(&cfg.Passphrase, "passphrase", "", "Passphrase for the PKCS#12 bundle")
+	fs.StringVar(&cfg.PassphraseFile, "passphrase-file", "", "File containing the passphrase for the PKCS#12 bundle")
+	fs.BoolVar(&cfg.StrictKeyFiles, "strict", false, "Fail instead of warning when a private key file is readable by every user, owned by another user or in a world-writable directory")
+	fs.BoolVar(&cfg.FixChain, "fix-chain", false, "Strip the root CA and unrelated certificates from the chain")
+	fs.BoolVar(&cfg.FetchChain, "fetch-chain", false, "Download missing intermediates using the certificate's AIA extension")
+}
+
+// validate checks that the inputs and options in cfg can be combined. It is
+// shared by the import flags and import-batch manifest entries.
+func (cfg *CertImportConfig) validate() error {
+	if countSet(cfg.CertFile, cfg.CertEnv, cfg.CertSecret, cfg.CertData) > 1 ||
+		countSet(cfg.PrivateKeyFile, cfg.KeyEnv, cfg.KeySecret, cfg.KeyData) > 1 ||
+		countSet(cfg.ChainFile, cfg.ChainEnv, cfg.ChainSecret, cfg.ChainData) > 1 {
+		return errors.New("-cert, -key and -chain cannot be combined with their -env, -secret or -data variants")
+	}
+
+	// stdin can only be read once
+	stdinInputs := 0
+	for _, path := range []string{cfg.CertFile, cfg.PrivateKeyFile, cfg.ChainFile, cfg.BundleFile, cfg.PKCS12File} {
+		if path == "-" {
+			stdinInputs++
+		}
+	}
+	if stdinInputs > 1 {
+		return errors.New("only one input can be read from stdin (use -bundle - for a combined PEM)")
+	}
+
+	hasCert := countSet(cfg.CertFile, cfg.CertEnv, cfg.CertSecret, cfg.CertData) > 0
+	hasKey := countSet(cfg.PrivateKeyFile, cfg.KeyEnv, cfg.KeySecret, cfg.KeyData) > 0
+
+	switch {
+	case cfg.Dir != "":
+		if hasCert || hasKey || cfg.PKCS12File != "" || cfg.BundleFile != "" || cfg.SecretDir != "" || cfg.FromWindowsStore || cfg.FromKeychain || countSet(cfg.ChainFile, cfg.ChainEnv, cfg.ChainSecret, cfg.ChainData) > 0 {
+			return errors.New("-dir cannot be combined with -cert, -key, -chain, -pkcs12, -bundle, -from-secret-dir, -from-windows-store or -from-keychain")
+		}
+	case cfg.FromWindowsStore || cfg.FromKeychain:
+		if hasCert || hasKey || cfg.PKCS12File != "" || cfg.BundleFile != "" || cfg.SecretDir != "" || (cfg.FromWindowsStore && cfg.FromKeychain) {
+			return errors.New("-from-windows-store and -from-keychain cannot be combined with each other or with -cert, -key, -pkcs12, -bundle or -from-secret-dir")
+		}
+	case cfg.PKCS12File != "" || cfg.BundleFile != "" || cfg.SecretDir != "":
+		if hasCert || hasKey || countSet(cfg.PKCS12File, cfg.BundleFile, cfg.SecretDir) > 1 {
+			return errors.New("-pkcs12, -bundle, -from-secret-dir and -cert/-key are mutually exclusive")
+		}
+	case !hasCert || !hasKey:
+		return errors.New("both -cert and -key are required")
+	}
+
+	if cfg.KeyKMSEncrypted != (cfg.KMSKeyID != "") {
+		return errors.New("-key-kms-encrypted and -kms-key-id must be used together")
+	}
+	if err := cfg.validateWindowsStore(); err != nil {
+		return err
+	}
+	if err := cfg.validateKeychain(); err != nil {
+		return err
+	}
+	if cfg.KeyKMSEncrypted && !hasKey {
+		return errors.New("-key-kms-encrypted applies to -key, -key-env, -key-secret or -key-data, not -pkcs12, -bundle, -from-secret-dir or -dir")
+	}
+
+	return cfg.validateOptions()
+}
+
+// validateOptions checks the options that apply however the certificate is
+// obtained.
+func (cfg *CertImportConfig) validateOptions() error {
+	if cfg.Output != "text" && cfg.Output != "json" && cfg.Output != "terraform" && cfg.Output != "cfn-params" {
+		return errors.New("-output must be text, json, terraform or cfn-params")
+	}
+	if cfg.Output == "cfn-params" && (cfg.IAM || cfg.DryRun) {
+		return errors.New("-output cfn-params cannot be combined with -iam or -dry-run")
+	}
+	if cfg.Output == "terraform" && (cfg.Dir != "" || cfg.TargetsFile != "" || cfg.IAM || cfg.DryRun || cfg.Daemon) {
+		return errors.New("-output terraform cannot be combined with -dir, -targets, -iam, -dry-run or -daemon")
+	}
+
+	if len(cfg.Regions) > 0 && cfg.AllRegions {
+		return errors.New("-regions and -all-regions are mutually exclusive")
+	}
+	if (len(cfg.Regions) > 1 || cfg.AllRegions) && cfg.CertificateArn != "" {
+		return errors.New("-certificate-arn cannot be used when importing into multiple regions")
+	}
+	if cfg.Upsert && cfg.CertificateArn != "" {
+		return errors.New("-upsert and -certificate-arn are mutually exclusive")
+	}
+	if cfg.Dir != "" && (cfg.CertificateArn != "" || len(cfg.PublishArn) > 0) {
+		return errors.New("-certificate-arn and -publish-arn refer to a single certificate and cannot be used with -dir")
+	}
+	if cfg.TargetsFile != "" && (cfg.Dir != "" || cfg.CertificateArn != "" || cfg.AllRegions) {
+		return errors.New("-targets cannot be combined with -dir, -certificate-arn or -all-regions")
+	}
+	if cfg.TargetsFile != "" && expectAccount != "" {
+		return errors.New("-expect-account cannot be used with -targets, whose accounts differ")
+	}
+	if cfg.TargetsFile != "" && cfg.Concurrency < 1 {
+		return errors.New("-concurrency must be at least 1")
+	}
+	if cfg.MinRSABits < 0 {
+		return errors.New("-min-rsa-bits must not be negative")
+	}
+	if err := certimport.CheckAlgorithms(cfg.KeyAlgorithms); err != nil {
+		return err
+	}
+	if err := certimport.CheckUses(cfg.IntendedUses); err != nil {
+		return err
+	}
+	if err := cfg.Notify.validate(); err != nil {
+		return err
+	}
+	if err := cfg.validateDaemon(); err != nil {
+		return err
+	}
+	if err := cfg.validateIAM(); err != nil {
+		return err
+	}
+	if err := cfg.validateAttach(); err != nil {
+		return err
+	}
+	if err := cfg.validateKeyFiles(); err != nil {
+		return err
+	}
+	if err := cfg.validatePins(); err != nil {
+		return err
+	}
+
+	return certimport.ValidateTags(cfg.Tags)
+}
+
+func importCertificate(ctx context.Context, cfg CertImportConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, regions, err := importTargets(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+
+	// Certificate material may itself be stored in AWS, so it is read after
+	// the AWS configuration is loaded
+	progress.Printf("Reading certificate files...\n")
+
+	material, err := loadMaterial(ctx, cfg, awsCfg, progress)
+	if err != nil {
+		notifyFailure(ctx, awsCfg, cfg.Notify, err)
+		return err
+	}
+
+	if cfg.IAM {
+		err = uploadServerCertificate(ctx, cfg, awsCfg, material)
+	} else {
+		err = importLoaded(ctx, cfg, awsCfg, regions, material)
+	}
+	if err != nil || !cfg.DeleteKey {
+		return err
+	}
+	return deleteKeyFiles(cfg)
+}
+
+// importTargets resolves the regions to import into and loads the AWS
+// configuration. A single region becomes cfg.Region, so the returned list is
+// empty unless several regions were requested.
+func importTargets(ctx context.Context, cfg *CertImportConfig) (aws.Config, []string, error) {
+	regions := cfg.Regions
+	if cfg.AllRegions {
+		var err error
+		regions, err = enabledRegions(ctx, cfg.Region, cfg.Profile)
+		if err != nil {
+			return aws.Config{}, nil, err
+		}
+	}
+	if len(regions) == 1 {
+		cfg.Region, regions = regions[0], nil
+	}
+
+	// Load AWS configuration
+	progress.Printf("Initializing AWS client...\n")
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return aws.Config{}, nil, err
+	}
+	if _, err := preflight(ctx, awsCfg, progress); err != nil {
+		return aws.Config{}, nil, err
+	}
+
+	if len(regions) == 0 {
+		progress.Printf("✓ AWS ACM client initialized (region: %s)\n", awsCfg.Region)
+	} else {
+		progress.Printf("✓ AWS ACM clients initialized (regions: %s)\n", strings.Join(regions, ", "))
+	}
+	return awsCfg, regions, nil
+}
+
+// importLoaded validates and imports material that has already been read,
+// publishes the ARNs and prints the result.
+func importLoaded(ctx context.Context, cfg CertImportConfig, awsCfg aws.Config, regions []string, material *certimport.Material) error {
+	importer, prepared, err := prepareImport(ctx, awsCfg, progress, cfg, material, regions)
+	if err != nil {
+		notifyFailure(ctx, awsCfg, cfg.Notify, err)
+		return err
+	}
+	defer prepared.Clear()
+	progress.Printf("✓ Fingerprint (SHA-256): %s\n", certimport.Fingerprint(prepared.Leaf))
+
+	if err := cfg.Hooks.runPre(ctx, progress, "", prepared.Leaf, hookRegions(awsCfg, regions), cfg.CertificateArn, cfg.DryRun); err != nil {
+		notifyFailure(ctx, awsCfg, cfg.Notify, err)
+		return err
+	}
+
+	if cfg.DryRun {
+		return dryRun(ctx, cfg, awsCfg, importer, prepared, regions)
+	}
+
+	label := "Importing certificate"
+	if len(regions) > 1 {
+		label = fmt.Sprintf("Importing into %d regions", len(regions))
+	}
+	spin := progress.Spin(label, 0)
+	result := importer.ImportPrepared(ctx, prepared, regions)
+	spin.Stop()
+	imported := batchResult{
+		DomainName:  result.DomainName,
+		Fingerprint: result.Fingerprint,
+		NotAfter:    &result.NotAfter,
+		Regions:     newImportResult(result).Regions,
+	}
+	notifyImport(ctx, awsCfg, progress, cfg.Notify, imported)
+
+	// A single region keeps the plain error behaviour
+	if len(result.Regions) == 1 && result.Regions[0].Err != nil {
+		return result.Regions[0].Err
+	}
+
+	if cfg.Wait {
+		if err := waitForImported(ctx, awsCfg, result, prepared.Leaf); err != nil {
+			return err
+		}
+	}
+
+	// The certificates are imported either way, so a failure to publish or
+	// attach them is only reported once the ARNs have been printed
+	postErr := publishArns(ctx, awsCfg, progress, cfg.PublishArn, result)
+	if postErr == nil {
+		postErr = attachImported(ctx, awsCfg, cfg, result)
+	}
+	if postErr == nil && result.Failed() < len(result.Regions) {
+		postErr = cfg.Pins.write(prepared.Leaf)
+	}
+	if postErr == nil {
+		postErr = cfg.Hooks.runPost(ctx, progress, prepared.Leaf, imported)
+	}
+
+	if cfg.Output == "terraform" {
+		err = printTerraform(cfg, prepared, result)
+	} else {
+		err = printImportResult(cfg.Output, newImportResult(result))
+	}
+	if err != nil {
+		return err
+	}
+	if postErr != nil {
+		return postErr
+	}
+
+	if len(result.Regions) > 1 {
+		failed := result.Failed()
+		progress.Printf("Summary: %d succeeded, %d failed\n", len(result.Regions)-failed, failed)
+		if failed == len(result.Regions) {
+			// Keep the first error so the exit code reflects its cause
+			return fmt.Errorf("import failed in all %d regions: %w", failed, result.Regions[0].Err)
+		}
+		if failed > 0 {
+			return partialFailure(fmt.Errorf("import failed in %d of %d regions", failed, len(result.Regions)))
+		}
+		progress.Printf("✅ Certificate imported successfully!\n")
+	}
+	return nil
+}
+
+// prepareImport adds the -auto-tags tags to cfg's tags and validates the
+// material for importing into regions with the settings in cfg.
+func prepareImport(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, cfg CertImportConfig, material *certimport.Material, regions []string) (*certimport.Importer, *certimport.Prepared, error) {
+	// Derived tags come first so that explicit tags override them
+	tags := cfg.Tags
+	if cfg.AutoTags {
+		tags = mergeTags(certimport.CertificateTags(material.Leaf), importMetadataTags(ctx, awsCfg), cfg.Tags)
+	}
+
+	blocklist, err := loadDebianBlocklist(cfg.DebianBlocklists)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	importer := certimport.New(awsCfg, certimport.WithLogger(logger))
+	prepared, err := importer.Prepare(ctx, material, certimport.ImportOptions{
+		CertificateArn:  cfg.CertificateArn,
+		Tags:            tags,
+		FixChain:        cfg.FixChain,
+		FetchChain:      cfg.FetchChain,
+		Regions:         regions,
+		AllowExpired:    cfg.AllowExpired,
+		Force:           cfg.Force,
+		Upsert:          cfg.Upsert,
+		UpsertTags:      cfg.UpsertTags,
+		Policy:          cfg.keyPolicy(),
+		MustCover:       cfg.MustCover,
+		CheckRevocation: cfg.CheckRevocation,
+		AllowRevoked:    cfg.AllowRevoked,
+		CheckCT:         cfg.CheckCT,
+		DebianBlocklist: blocklist,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return importer, prepared, nil
+}
+
+// debianBlocklistGlob matches the blocklists installed by Debian's
+// openssl-blacklist package.
+const debianBlocklistGlob = "/usr/share/openssl-blacklist/blacklist.RSA-*"
+
+// loadDebianBlocklist reads the Debian weak key blocklists in paths, or the
+// installed ones when no paths are given.
+func loadDebianBlocklist(paths []string) (certimport.DebianBlocklist, error) {
+	if len(paths) == 0 {
+		paths, _ = filepath.Glob(debianBlocklistGlob)
+	}
+	blocklist := certimport.DebianBlocklist{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Debian weak key blocklist: %w", err)
+		}
+		err = certimport.LoadDebianBlocklist(blocklist, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Debian weak key blocklist %s: %w", path, err)
+		}
+	}
+	return blocklist, nil
+}
+
+// keyPolicy returns the key policy of an import. Attaching the certificate
+// implies the services it is attached to.
+func (cfg CertImportConfig) keyPolicy() certimport.KeyPolicy {
+	uses := slices.Clone(cfg.IntendedUses)
+	if len(cfg.AttachListeners) > 0 && !slices.Contains(uses, "elb") {
+		uses = append(uses, "elb")
+	}
+	if len(cfg.AttachDistributions) > 0 && !slices.Contains(uses, "cloudfront") {
+		uses = append(uses, "cloudfront")
+	}
+	return certimport.KeyPolicy{MinRSABits: cfg.MinRSABits, Algorithms: cfg.KeyAlgorithms, Uses: uses}
+}
+
+// importResult is the outcome of an import, printed as text or JSON.
+type importResult struct {
+	// CertificateArn and Region are set when importing into a single region
+	CertificateArn  string         `json:"certificateArn,omitempty"`
+	Region          string         `json:"region,omitempty"`
+	DomainName      string         `json:"domainName"`
+	Fingerprint     string         `json:"fingerprint"`
+	SANs            []string       `json:"subjectAlternativeNames,omitempty"`
+	NotAfter        time.Time      `json:"notAfter"`
+	DaysUntilExpiry int            `json:"daysUntilExpiry"`
+	Reimported      bool           `json:"reimported"`
+	Existing        bool           `json:"existing,omitempty"`
+	DryRun          bool           `json:"dryRun,omitempty"`
+	Account         string         `json:"account,omitempty"`
+	Regions         []regionResult `json:"regions"`
+	Warnings        []string       `json:"warnings"`
+}
+
+// regionResult is the outcome of importing into a single region.
+type regionResult struct {
+	Region         string `json:"region"`
+	CertificateArn string `json:"certificateArn,omitempty"`
+	Reimported     bool   `json:"reimported,omitempty"`
+	Existing       bool   `json:"existing,omitempty"`
+	Resumed        bool   `json:"resumed,omitempty"`
+	Error          string `json:"error,omitempty"`
+	ErrorCode      string `json:"errorCode,omitempty"`
+
+	// err is the error behind Error, for callers that report it themselves
+	err error
+}
+
+// setError records err, with its remediation hint, and its error code.
+func (r *regionResult) setError(err error) {
+	info := describeError(err, false)
+	r.Error, r.ErrorCode, r.err = info.text(), info.Code, err
+}
+
+func newImportResult(result *certimport.Result) importResult {
+	out := importResult{
+		DomainName:      result.DomainName,
+		Fingerprint:     result.Fingerprint,
+		SANs:            result.SANs,
+		NotAfter:        result.NotAfter,
+		DaysUntilExpiry: daysUntil(result.NotAfter),
+		Reimported:      result.Reimported,
+	}
+	for _, r := range result.Regions {
+		region := regionResult{
+			Region:         r.Region,
+			CertificateArn: r.CertificateArn,
+			Reimported:     r.Reimported,
+			Existing:       r.Existing,
+		}
+		if r.Err != nil {
+			region.setError(fmt.Errorf("failed to import certificate: %w", r.Err))
+		}
+		out.Regions = append(out.Regions, region)
+	}
+	if len(out.Regions) == 1 {
+		out.Region = out.Regions[0].Region
+		out.CertificateArn = out.Regions[0].CertificateArn
+		out.Existing = out.Regions[0].Existing
+	}
+	return out
+}
+
+func printImportResult(format string, result importResult) error {
+	if format == "json" {
+		result.Warnings = progress.Warnings()
+		return printJSON(result)
+	}
+	if format == "cfn-params" {
+		return printCfnParams([]batchResult{{Regions: result.Regions}})
+	}
+
+	// Quiet mode prints nothing but the ARNs so they can be captured directly
+	if progress.Quiet() {
+		for _, r := range result.Regions {
+			if r.CertificateArn != "" {
+				fmt.Println(r.CertificateArn)
+			}
+		}
+		return nil
+	}
+
+	if len(result.Regions) > 1 {
+		for _, r := range result.Regions {
+			if r.Error != "" {
+				stdout.Printf("✗ %s: %s\n", r.Region, r.Error)
+				continue
+			}
+			if r.Existing {
+				stdout.Printf("✓ %s: %s (already imported)\n", r.Region, r.CertificateArn)
+				continue
+			}
+			if r.Reimported {
+				stdout.Printf("✓ %s: %s (re-imported)\n", r.Region, r.CertificateArn)
+				continue
+			}
+			stdout.Printf("✓ %s: %s\n", r.Region, r.CertificateArn)
+		}
+		fmt.Printf("Expires: %s (%d days)\n", result.NotAfter.Format("2006-01-02"), result.DaysUntilExpiry)
+		return nil
+	}
+
+	if result.Existing {
+		stdout.Printf("✅ Identical certificate already imported, skipping (use -force to import anyway)\n")
+	} else if result.Reimported {
+		stdout.Printf("✅ Certificate re-imported successfully!\n")
+	} else {
+		stdout.Printf("✅ Certificate imported successfully!\n")
+	}
+	fmt.Printf("Certificate ARN: %s\n", result.CertificateArn)
+	fmt.Printf("Expires: %s (%d days)\n", result.NotAfter.Format("2006-01-02"), result.DaysUntilExpiry)
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/bldmgr/aws-certs.git/internal/s3object"
+)
+
+// input is a source of certificate material: a file path, "-" for stdin, an
+// ssm://, s3:// or vault:// URI, the name of an environment variable holding
+// the PEM data, a Secrets Manager secret, or the data itself base64 encoded.
+// When KMSKeyID is set the input holds a KMS ciphertext of the data rather
+// than the data itself.
+type input struct {
+	Path     string
+	Env      string
+	Secret   string
+	Data     string
+	KMSKeyID string
+}
+
+func (in input) IsSet() bool {
+	return in.Path != "" || in.Env != "" || in.Secret != "" || in.Data != ""
+}
+
+func (in input) String() string {
+	switch {
+	case in.Env != "":
+		return "environment variable " + in.Env
+	case in.Secret != "":
+		return "secret " + in.Secret
+	case in.Data != "":
+		return "base64 data"
+	case in.Path == "-":
+		return "stdin"
+	}
+	return in.Path
+}
+
+// read returns the contents of the input, decrypted if it is KMS encrypted.
+// awsCfg is only used for inputs stored in or encrypted by AWS.
+func (in input) read(ctx context.Context, awsCfg aws.Config) ([]byte, error) {
+	data, err := in.fetch(ctx, awsCfg)
+	if err != nil || in.KMSKeyID == "" {
+		return data, err
+	}
+	return decryptKMS(ctx, awsCfg, in.KMSKeyID, in, data)
+}
+
+func (in input) fetch(ctx context.Context, awsCfg aws.Config) ([]byte, error) {
+	switch {
+	case in.Env != "":
+		value := os.Getenv(in.Env)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set or empty", in.Env)
+		}
+		return []byte(value), nil
+	case in.Secret != "":
+		return readSecret(ctx, awsCfg, in.Secret)
+	case in.Data != "":
+		return decodeData(in.Data)
+	case in.Path == "-":
+		return readStdin()
+	case strings.HasPrefix(in.Path, "ssm://"):
+		return readParameter(ctx, awsCfg, strings.TrimPrefix(in.Path, "ssm://"))
+	case strings.HasPrefix(in.Path, "s3://"):
+		return readObject(ctx, awsCfg, in.Path)
+	case strings.HasPrefix(in.Path, "vault://"):
+		return readVault(ctx, in.Path)
+	}
+	return readFile(in.Path)
+}
+
+// decodeData decodes the base64 value of a -data flag, as the AWS CLI takes
+// blobs. Line breaks, as added by base64 without -w0, are ignored.
+func decodeData(value string) ([]byte, error) {
+	value = strings.Join(strings.Fields(value), "")
+	if strings.HasPrefix(value, "-----BEGIN") {
+		return nil, errors.New("the -data flags take base64-encoded PEM, not PEM itself: encode it with base64 -w0, or pass PEM with the -env flags or - for stdin")
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 data: %w", err)
+	}
+	return data, nil
+}
+
+func readStdin() ([]byte, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return data, nil
+}
+
+// readSecret fetches a Secrets Manager secret by name or ARN. A "#key"
+// suffix selects one field of a JSON secret, e.g. "prod/tls#certificate".
+// Secrets given by ARN are read from the ARN's region.
+func readSecret(ctx context.Context, awsCfg aws.Config, ref string) ([]byte, error) {
+	id, jsonKey, _ := strings.Cut(ref, "#")
+
+	client := secretsmanager.NewFromConfig(awsCfg, func(o *secretsmanager.Options) {
+		if parsed, err := arn.Parse(id); err == nil && parsed.Region != "" {
+			o.Region = parsed.Region
+		}
+	})
+
+	output, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s: %w", id, err)
+	}
+	progress.Debugf("Read secret %s (version %s)\n", aws.ToString(output.ARN), aws.ToString(output.VersionId))
+
+	if jsonKey == "" {
+		if output.SecretString != nil {
+			return []byte(aws.ToString(output.SecretString)), nil
+		}
+		return output.SecretBinary, nil
+	}
+
+	if output.SecretString == nil {
+		return nil, fmt.Errorf("secret %s is binary, cannot select JSON key %q", id, jsonKey)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(aws.ToString(output.SecretString)), &fields); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object, cannot select key %q: %w", id, jsonKey, err)
+	}
+	value, ok := fields[jsonKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no string field %q", id, jsonKey)
+	}
+	return []byte(value), nil
+}
+
+// decryptKMS decrypts a ciphertext blob produced by KMS Encrypt, raw or
+// base64 encoded as printed by the AWS CLI, with the KMS key keyID. Naming
+// the key stops a ciphertext under some other key being accepted. Keys given
+// by ARN are used in the ARN's region.
+func decryptKMS(ctx context.Context, awsCfg aws.Config, keyID string, in input, ciphertext []byte) ([]byte, error) {
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(ciphertext))); err == nil {
+		ciphertext = decoded
+	}
+
+	client := kms.NewFromConfig(awsCfg, func(o *kms.Options) {
+		if parsed, err := arn.Parse(keyID); err == nil && parsed.Region != "" {
+			o.Region = parsed.Region
+		}
+	})
+
+	output, err := client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: ciphertext,
+		KeyId:          aws.String(keyID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with KMS key %s: %w", in, keyID, err)
+	}
+	progress.Debugf("Decrypted %s with KMS key %s\n", in, aws.ToString(output.KeyId))
+	return output.Plaintext, nil
+}
+
+// readParameter fetches an SSM Parameter Store parameter, decrypting
+// SecureString values. Hierarchical names may omit the leading slash, so
+// ssm://tls/cert and ssm:///tls/cert both read /tls/cert.
+func readParameter(ctx context.Context, awsCfg aws.Config, name string) ([]byte, error) {
+	if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "arn:") {
+		name = "/" + name
+	}
+
+	client := ssm.NewFromConfig(awsCfg, func(o *ssm.Options) {
+		if parsed, err := arn.Parse(name); err == nil && parsed.Region != "" {
+			o.Region = parsed.Region
+		}
+	})
+
+	output, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameter %s: %w", name, err)
+	}
+	progress.Debugf("Read parameter %s (version %d)\n", name, output.Parameter.Version)
+	return []byte(aws.ToString(output.Parameter.Value)), nil
+}
+
+// readObject downloads an S3 object given as s3://bucket/key from whichever
+// region the bucket is in.
+func readObject(ctx context.Context, awsCfg aws.Config, uri string) ([]byte, error) {
+	bucket, key, err := s3object.SplitURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	return s3object.Read(ctx, awsCfg, progress, "", bucket, key)
+}
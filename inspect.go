@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type InspectConfig struct {
+	CertFile  string
+	ChainFile string
+	Output    string
+}
+
+// inspectedCertificate is the JSON representation of a certificate shown by
+// the inspect subcommand.
+type inspectedCertificate struct {
+	// Role is leaf, intermediate, root or unused
+	Role               string    `json:"role"`
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	Serial             string    `json:"serial"`
+	SANs               []string  `json:"subjectAlternativeNames,omitempty"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	DaysLeft           int       `json:"daysLeft"`
+	KeyAlgorithm       string    `json:"keyAlgorithm"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	IsCA               bool      `json:"isCA"`
+	SHA256             string    `json:"sha256Fingerprint"`
+	SHA1               string    `json:"sha1Fingerprint"`
+}
+
+// inspectResult is the JSON representation of inspect.
+type inspectResult struct {
+	Certificates []inspectedCertificate `json:"certificates"`
+	// Complete is set when the chain ends in a root CA
+	Complete  bool `json:"complete"`
+	Reordered bool `json:"reordered"`
+}
+
+func runInspect(ctx context.Context, args []string) error {
+	var cfg InspectConfig
+
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.StringVar(&cfg.CertFile, "cert", "", "Certificate file (PEM or DER), or - for stdin; further certificates in it are the chain - REQUIRED")
+	fs.StringVar(&cfg.ChainFile, "chain", "", "Certificate chain file, if not in the -cert file")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s inspect -cert <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show the subject, issuer, names, validity, key, signature algorithm, fingerprints and\n")
+		fmt.Fprintf(os.Stderr, "chain structure of local certificate files. Nothing is sent to AWS.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s inspect -cert fullchain.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s inspect -cert cert.pem -chain chain.pem -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	// A positional argument is treated as the certificate file
+	if cfg.CertFile == "" && fs.NArg() > 0 {
+		cfg.CertFile = fs.Arg(0)
+	}
+	if cfg.CertFile == "" {
+		fail(errors.New("-cert is required"))
+	}
+	if cfg.CertFile == "-" && cfg.ChainFile == "-" {
+		fail(errors.New("only one input can be read from stdin"))
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fail(errors.New("-output must be text or json"))
+	}
+
+	return inspectCertificates(cfg)
+}
+
+func inspectCertificates(cfg InspectConfig) error {
+	certs, err := readLocalCertificates(cfg.CertFile, "certificate")
+	if err != nil {
+		return err
+	}
+	if cfg.ChainFile != "" {
+		chain, err := readLocalCertificates(cfg.ChainFile, "certificate chain")
+		if err != nil {
+			return err
+		}
+		certs = append(certs, chain...)
+	}
+
+	result := newInspectResult(certs)
+	if cfg.Output == "json" {
+		return printJSON(result)
+	}
+	printInspectResult(result)
+	return nil
+}
+
+// readLocalCertificates reads and parses the certificates in a file or
+// stdin. Unlike readInput it never reads from AWS.
+func readLocalCertificates(path, what string) ([]*x509.Certificate, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = readStdin()
+	} else {
+		data, err = readFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	certs, err := certimport.ParseCertificates(data, what)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no %s found in %s", what, path)
+	}
+	return certs, nil
+}
+
+// findLeaf returns the first certificate that is not a CA, so that a chain
+// in any order, or a chain file on its own, is read the right way up.
+func findLeaf(certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs {
+		if !cert.IsCA {
+			return cert
+		}
+	}
+	return certs[0]
+}
+
+// newInspectResult orders certs from the leaf up to the root.
+func newInspectResult(certs []*x509.Certificate) inspectResult {
+	leaf := findLeaf(certs)
+	chain := certimport.OrderChain(leaf, certs)
+
+	result := inspectResult{Complete: chain.Root != nil, Reordered: chain.Reordered}
+	result.Certificates = append(result.Certificates, newInspectedCertificate(leaf, "leaf"))
+	for _, cert := range chain.Certs {
+		role := "intermediate"
+		if cert == chain.Root {
+			role = "root"
+		}
+		result.Certificates = append(result.Certificates, newInspectedCertificate(cert, role))
+	}
+	for _, cert := range chain.Unused {
+		result.Certificates = append(result.Certificates, newInspectedCertificate(cert, "unused"))
+	}
+	// A self-signed leaf is its own root
+	if chain.Root == nil && len(chain.Certs) == 0 && bytes.Equal(leaf.RawIssuer, leaf.RawSubject) &&
+		leaf.CheckSignature(leaf.SignatureAlgorithm, leaf.RawTBSCertificate, leaf.Signature) == nil {
+		result.Complete = true
+	}
+	return result
+}
+
+func newInspectedCertificate(cert *x509.Certificate, role string) inspectedCertificate {
+	sans := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sha1Sum := sha1.Sum(cert.Raw)
+
+	return inspectedCertificate{
+		Role:               role,
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		Serial:             colonHex(cert.SerialNumber.Bytes()),
+		SANs:               sans,
+		NotBefore:          cert.NotBefore.UTC(),
+		NotAfter:           cert.NotAfter.UTC(),
+		DaysLeft:           daysUntil(cert.NotAfter),
+		KeyAlgorithm:       certimport.PublicKeyDescription(cert.PublicKey),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		SHA256:             certimport.Fingerprint(cert),
+		SHA1:               hex.EncodeToString(sha1Sum[:]),
+	}
+}
+
+// colonHex formats bytes as colon separated hex, as openssl and ACM show
+// serial numbers.
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(parts, ":")
+}
+
+func printInspectResult(result inspectResult) {
+	for i, cert := range result.Certificates {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Certificate %d (%s)\n", i+1, cert.Role)
+		fmt.Printf("Subject:              %s\n", cert.Subject)
+		fmt.Printf("Issuer:               %s\n", cert.Issuer)
+		fmt.Printf("Serial:               %s\n", cert.Serial)
+		if len(cert.SANs) > 0 {
+			fmt.Printf("SANs:                 %s\n", strings.Join(cert.SANs, ", "))
+		}
+		fmt.Printf("Not Before:           %s\n", cert.NotBefore.Format(time.RFC3339))
+		fmt.Printf("Not After:            %s (%s)\n", cert.NotAfter.Format(time.RFC3339), validityNote(cert))
+		fmt.Printf("Key:                  %s\n", cert.KeyAlgorithm)
+		fmt.Printf("Signature Algorithm:  %s\n", cert.SignatureAlgorithm)
+		fmt.Printf("SHA-256 Fingerprint:  %s\n", cert.SHA256)
+		fmt.Printf("SHA-1 Fingerprint:    %s\n", cert.SHA1)
+	}
+
+	fmt.Printf("\nChain:\n")
+	depth := 0
+	for _, cert := range result.Certificates {
+		if cert.Role == "unused" {
+			continue
+		}
+		prefix := "  "
+		if depth > 0 {
+			prefix += strings.Repeat("   ", depth-1) + "└─ "
+		}
+		fmt.Printf("%s%s (%s)\n", prefix, certLabel(cert), cert.Role)
+		depth++
+	}
+	for _, cert := range result.Certificates {
+		if cert.Role == "unused" {
+			stdout.Printf("⚠ %s is not part of the chain\n", certLabel(cert))
+		}
+	}
+	if result.Reordered {
+		stdout.Printf("⚠ The chain is not in issuing order\n")
+	}
+	if !result.Complete {
+		last := result.Certificates[0]
+		for _, cert := range result.Certificates {
+			if cert.Role == "intermediate" {
+				last = cert
+			}
+		}
+		stdout.Printf("⚠ The chain ends at %s, issued by %s which is not included\n", certLabel(last), last.Issuer)
+	}
+}
+
+// certLabel names a certificate by its subject's common name, or the whole
+// subject when it has none.
+func certLabel(cert inspectedCertificate) string {
+	if cn, ok := strings.CutPrefix(cert.Subject, "CN="); ok {
+		if name, _, _ := strings.Cut(cn, ","); name != "" {
+			return name
+		}
+	}
+	return cert.Subject
+}
+
+func validityNote(cert inspectedCertificate) string {
+	switch {
+	case time.Now().Before(cert.NotBefore):
+		return "not yet valid"
+	case cert.DaysLeft < 0:
+		return fmt.Sprintf("expired %d days ago", -cert.DaysLeft)
+	}
+	return fmt.Sprintf("%d days", cert.DaysLeft)
+}
@@ -0,0 +1,16 @@
+// Package cliutil parses settings the same way in the aws-certs commands,
+// from flags, and in the Lambda function, from environment variables.
+package cliutil
+
+import "strings"
+
+// SplitList splits a comma separated value, dropping empty entries.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
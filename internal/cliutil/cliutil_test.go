@@ -0,0 +1,23 @@
+package cliutil
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"us-east-1", []string{"us-east-1"}},
+		{" us-east-1 , eu-west-1,", []string{"us-east-1", "eu-west-1"}},
+		{",,", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitList(tt.value); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitList(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+)
+
+// ExpiringCertificate is a certificate found by CheckExpiry.
+type ExpiringCertificate struct {
+	Region string `json:"region"`
+	Certificate
+	DaysUntilExpiry int `json:"daysUntilExpiry"`
+}
+
+// ClientFunc returns the ACM client for a region.
+type ClientFunc func(ctx context.Context, region string) (*acm.Client, error)
+
+// CheckExpiry lists the certificates matching filter in each of regions
+// and returns those that expire within days, or have expired, the first to
+// expire first, along with the number of certificates checked.
+func CheckExpiry(ctx context.Context, regions []string, client ClientFunc, filter Filter, days int) ([]ExpiringCertificate, int, error) {
+	now := time.Now()
+	expiring := []ExpiringCertificate{}
+	checked := 0
+	for _, region := range regions {
+		c, err := client(ctx, region)
+		if err != nil {
+			return nil, 0, err
+		}
+		// An empty region is the default one of the client's configuration
+		region = c.Options().Region
+		certs, err := List(ctx, c, filter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", region, err)
+		}
+		found, n := expiringWithin(region, certs, days, now)
+		expiring = append(expiring, found...)
+		checked += n
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].NotAfter.Before(*expiring[j].NotAfter)
+	})
+	return expiring, checked, nil
+}
+
+// expiringWithin returns the certificates of region that expire within days
+// of now, and how many certificates were checked. Certificates pending
+// validation have no expiry date yet and are not counted.
+func expiringWithin(region string, certs []Certificate, days int, now time.Time) ([]ExpiringCertificate, int) {
+	deadline := now.AddDate(0, 0, days)
+	var expiring []ExpiringCertificate
+	checked := 0
+	for _, c := range certs {
+		if c.NotAfter == nil {
+			continue
+		}
+		checked++
+		if c.NotAfter.After(deadline) {
+			continue
+		}
+		expiring = append(expiring, ExpiringCertificate{
+			Region:          region,
+			Certificate:     c,
+			DaysUntilExpiry: DaysUntil(*c.NotAfter, now),
+		})
+	}
+	return expiring, checked
+}
+
+// DaysUntil returns the number of whole days from now until t, negative
+// once t has passed.
+func DaysUntil(t, now time.Time) int {
+	return int(t.Sub(now).Hours() / 24)
+}
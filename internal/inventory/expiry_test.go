@@ -0,0 +1,43 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiringWithin(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time {
+		t := now.AddDate(0, 0, days)
+		return &t
+	}
+	certs := []Certificate{
+		{CertificateArn: "expired", NotAfter: at(-3)},
+		{CertificateArn: "soon", NotAfter: at(10)},
+		{CertificateArn: "deadline", NotAfter: at(30)},
+		{CertificateArn: "later", NotAfter: at(31)},
+		{CertificateArn: "pending"},
+	}
+
+	expiring, checked := expiringWithin("eu-west-1", certs, 30, now)
+	if checked != 4 {
+		t.Errorf("checked = %d, want 4", checked)
+	}
+	want := map[string]int{"expired": -3, "soon": 10, "deadline": 30}
+	if len(expiring) != len(want) {
+		t.Fatalf("expiringWithin() = %d certificates, want %d", len(expiring), len(want))
+	}
+	for _, c := range expiring {
+		days, ok := want[c.CertificateArn]
+		if !ok {
+			t.Errorf("%s is not expiring", c.CertificateArn)
+			continue
+		}
+		if c.DaysUntilExpiry != days {
+			t.Errorf("%s: DaysUntilExpiry = %d, want %d", c.CertificateArn, c.DaysUntilExpiry, days)
+		}
+		if c.Region != "eu-west-1" {
+			t.Errorf("%s: Region = %q, want eu-west-1", c.CertificateArn, c.Region)
+		}
+	}
+}
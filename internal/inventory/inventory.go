@@ -0,0 +1,168 @@
+// Package inventory lists the certificates in ACM and finds those expiring
+// soon. It is shared by the aws-certs commands and the Lambda function, so
+// that both agree on what is listed and what counts as expiring.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// Certificate is a certificate returned by List, as printed by the list
+// command.
+type Certificate struct {
+	CertificateArn string            `json:"certificateArn"`
+	DomainName     string            `json:"domainName"`
+	SANs           []string          `json:"subjectAlternativeNames,omitempty"`
+	Status         string            `json:"status"`
+	Type           string            `json:"type"`
+	KeyAlgorithm   string            `json:"keyAlgorithm"`
+	NotAfter       *time.Time        `json:"notAfter,omitempty"`
+	ImportedAt     *time.Time        `json:"importedAt,omitempty"`
+	InUse          bool              `json:"inUse"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	// TruncatedSANs is set when ListCertificates left out some of the SANs,
+	// as it does for certificates with many; DescribeCertificate has them
+	// all.
+	TruncatedSANs bool `json:"-"`
+}
+
+// Filter selects the certificates returned by List. The zero value selects
+// every certificate.
+type Filter struct {
+	Statuses      []string
+	KeyAlgorithms []string
+	Type          string
+	DomainGlob    string
+	Tags          map[string]string
+}
+
+// List pages through ListCertificates and returns the certificates matching
+// filter. Status and key algorithm are filtered server side; type, domain
+// and tags are filtered locally.
+func List(ctx context.Context, client *acm.Client, filter Filter) ([]Certificate, error) {
+	input := &acm.ListCertificatesInput{
+		Includes: &types.Filters{},
+	}
+	for _, status := range filter.Statuses {
+		input.CertificateStatuses = append(input.CertificateStatuses, types.CertificateStatus(status))
+	}
+
+	// ListCertificates only returns RSA_1024 and RSA_2048 certificates unless
+	// key types are requested explicitly.
+	if len(filter.KeyAlgorithms) > 0 {
+		for _, algo := range filter.KeyAlgorithms {
+			input.Includes.KeyTypes = append(input.Includes.KeyTypes, types.KeyAlgorithm(algo))
+		}
+	} else {
+		input.Includes.KeyTypes = types.KeyAlgorithm("").Values()
+	}
+
+	var certs []Certificate
+	paginator := acm.NewListCertificatesPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list certificates: %w", err)
+		}
+
+		for _, summary := range page.CertificateSummaryList {
+			if filter.Type != "" && string(summary.Type) != filter.Type {
+				continue
+			}
+			if filter.DomainGlob != "" && !matchesDomain(summary, filter.DomainGlob) {
+				continue
+			}
+
+			cert := Certificate{
+				CertificateArn: aws.ToString(summary.CertificateArn),
+				DomainName:     aws.ToString(summary.DomainName),
+				SANs:           summary.SubjectAlternativeNameSummaries,
+				Status:         string(summary.Status),
+				Type:           string(summary.Type),
+				KeyAlgorithm:   string(summary.KeyAlgorithm),
+				NotAfter:       summary.NotAfter,
+				ImportedAt:     summary.ImportedAt,
+				InUse:          aws.ToBool(summary.InUse),
+				TruncatedSANs:  aws.ToBool(summary.HasAdditionalSubjectAlternativeNames),
+			}
+
+			if len(filter.Tags) > 0 {
+				tags, err := Tags(ctx, client, cert.CertificateArn)
+				if err != nil {
+					return nil, err
+				}
+				if !matchesTags(tags, filter.Tags) {
+					continue
+				}
+				cert.Tags = tags
+			}
+
+			certs = append(certs, cert)
+		}
+	}
+
+	return certs, nil
+}
+
+// CheckType returns an error unless certType is empty, for any type, or a
+// certificate type known to ACM.
+func CheckType(certType string) error {
+	if certType == "" {
+		return nil
+	}
+	var known []string
+	for _, t := range types.CertificateType("").Values() {
+		if string(t) == certType {
+			return nil
+		}
+		known = append(known, string(t))
+	}
+	return fmt.Errorf("unknown certificate type %q, expected one of %s", certType, strings.Join(known, ", "))
+}
+
+// matchesDomain reports whether the certificate's domain name or any of its
+// SANs match the glob pattern.
+func matchesDomain(summary types.CertificateSummary, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	names := append([]string{aws.ToString(summary.DomainName)}, summary.SubjectAlternativeNameSummaries...)
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, strings.ToLower(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTags reports whether every wanted tag is present with the same value.
+func matchesTags(tags, want map[string]string) bool {
+	for key, value := range want {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Tags returns the tags of a certificate.
+func Tags(ctx context.Context, client *acm.Client, arn string) (map[string]string, error) {
+	result, err := client.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{
+		CertificateArn: aws.String(arn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %w", arn, err)
+	}
+
+	tags := make(map[string]string, len(result.Tags))
+	for _, tag := range result.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
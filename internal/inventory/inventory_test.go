@@ -0,0 +1,62 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+func TestMatchesDomain(t *testing.T) {
+	summary := types.CertificateSummary{
+		DomainName:                      aws.String("example.com"),
+		SubjectAlternativeNameSummaries: []string{"example.com", "*.Example.com"},
+	}
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.COM", true},
+		{"*.example.com", true},
+		{"*.example.org", false},
+		{"www.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := matchesDomain(summary, tt.pattern); got != tt.want {
+			t.Errorf("matchesDomain(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesTags(t *testing.T) {
+	tags := map[string]string{"Environment": "prod", "Owner": "team-a"}
+	tests := []struct {
+		name string
+		want map[string]string
+		ok   bool
+	}{
+		{name: "no filter", ok: true},
+		{name: "subset", want: map[string]string{"Environment": "prod"}, ok: true},
+		{name: "other value", want: map[string]string{"Environment": "dev"}},
+		{name: "missing key", want: map[string]string{"Team": "a"}},
+	}
+	for _, tt := range tests {
+		if got := matchesTags(tags, tt.want); got != tt.ok {
+			t.Errorf("%s: matchesTags() = %v, want %v", tt.name, got, tt.ok)
+		}
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	for _, certType := range []string{"", "IMPORTED", "AMAZON_ISSUED", "PRIVATE"} {
+		if err := CheckType(certType); err != nil {
+			t.Errorf("CheckType(%q) = %v", certType, err)
+		}
+	}
+	for _, certType := range []string{"IMPRTED", "imported", "ALL"} {
+		if err := CheckType(certType); err == nil {
+			t.Errorf("CheckType(%q) = nil, want an error", certType)
+		}
+	}
+}
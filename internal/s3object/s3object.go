@@ -0,0 +1,65 @@
+// Package s3object downloads certificate material stored in S3, for the
+// s3:// inputs of the aws-certs commands and for the Lambda function.
+package s3object
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// SplitURI splits s3://bucket/key into the bucket and key.
+func SplitURI(uri string) (string, string, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(uri, "s3://"), "/")
+	if !strings.HasPrefix(uri, "s3://") || !ok || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 URI %s, expected s3://bucket/key", uri)
+	}
+	return bucket, key, nil
+}
+
+// Read downloads the object key of bucket, which is in region. When region
+// is empty it is looked up first, since buckets outside the region of
+// awsCfg answer with a redirect. Objects encrypted with SSE-S3 or SSE-KMS
+// are decrypted by S3, provided the caller may use the KMS key.
+func Read(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, region, bucket, key string) ([]byte, error) {
+	uri := "s3://" + bucket + "/" + key
+	client := s3.NewFromConfig(awsCfg)
+	if region == "" {
+		var err error
+		region, err = manager.GetBucketRegion(ctx, client, bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the region of bucket %s: %w", bucket, err)
+		}
+	}
+	if region != awsCfg.Region {
+		logger.Debugf("Bucket %s is in %s\n", bucket, region)
+		client = s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+			o.Region = region
+		})
+	}
+
+	output, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(output.Body, certimport.MaxMaterialSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	if len(data) > certimport.MaxMaterialSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes and is unlikely to be certificate material", uri, certimport.MaxMaterialSize)
+	}
+	logger.Debugf("Downloaded %s (%d bytes, encryption: %s)\n", uri, len(data), output.ServerSideEncryption)
+	return data, nil
+}
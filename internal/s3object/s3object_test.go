@@ -0,0 +1,28 @@
+package s3object
+
+import "testing"
+
+func TestSplitURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		bucket  string
+		key     string
+		wantErr bool
+	}{
+		{uri: "s3://certs/example.com/bundle.pem", bucket: "certs", key: "example.com/bundle.pem"},
+		{uri: "s3://certs/", wantErr: true},
+		{uri: "s3://certs", wantErr: true},
+		{uri: "s3:///key", wantErr: true},
+		{uri: "certs/key", wantErr: true},
+	}
+	for _, tt := range tests {
+		bucket, key, err := SplitURI(tt.uri)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitURI(%q) error = %v, want error %v", tt.uri, err, tt.wantErr)
+			continue
+		}
+		if bucket != tt.bucket || key != tt.key {
+			t.Errorf("SplitURI(%q) = %q, %q, want %q, %q", tt.uri, bucket, key, tt.bucket, tt.key)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+)
+
+// validateKeychain checks the options of -from-keychain.
+func (cfg *CertImportConfig) validateKeychain() error {
+	if cfg.FromKeychain != (cfg.KeychainName != "") {
+		return errors.New("-from-keychain and -name must be used together")
+	}
+	return nil
+}
+
+// pickKeychainCertificate returns the index of the certificate whose common
+// name is name, preferring the one that expires last when a renewed
+// certificate sits next to the old one. It returns -1 if none matches.
+func pickKeychainCertificate(certs []*x509.Certificate, name string) int {
+	best := -1
+	for i, cert := range certs {
+		if cert == nil || cert.Subject.CommonName != name {
+			continue
+		}
+		if best < 0 || cert.NotAfter.After(certs[best].NotAfter) {
+			best = i
+		}
+	}
+	return best
+}
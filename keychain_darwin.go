@@ -0,0 +1,136 @@
+//go:build darwin && cgo
+
+package main
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+
+// copyIdentities returns every certificate with a private key in the
+// Keychain search list, which holds the login and System keychains.
+static OSStatus copyIdentities(CFArrayRef *identities) {
+	const void *keys[] = {kSecClass, kSecMatchLimit, kSecReturnRef};
+	const void *values[] = {kSecClassIdentity, kSecMatchLimitAll, kCFBooleanTrue};
+	CFDictionaryRef query = CFDictionaryCreate(kCFAllocatorDefault, keys, values, 3,
+		&kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	OSStatus status = SecItemCopyMatching(query, (CFTypeRef *)identities);
+	CFRelease(query);
+	return status;
+}
+
+// copyCertificateData returns the DER certificate of identity i, or NULL.
+static CFDataRef copyCertificateData(CFArrayRef identities, CFIndex i) {
+	SecCertificateRef cert;
+	SecIdentityRef identity = (SecIdentityRef)CFArrayGetValueAtIndex(identities, i);
+	if (SecIdentityCopyCertificate(identity, &cert) != errSecSuccess) {
+		return NULL;
+	}
+	CFDataRef data = SecCertificateCopyData(cert);
+	CFRelease(cert);
+	return data;
+}
+
+// exportIdentity exports identity i with its private key as PKCS#12.
+static OSStatus exportIdentity(CFArrayRef identities, CFIndex i, const char *passphrase, CFDataRef *pkcs12) {
+	SecItemImportExportKeyParameters params = {0};
+	params.version = SEC_KEY_IMPORT_EXPORT_PARAMS_VERSION;
+	CFStringRef pass = CFStringCreateWithCString(kCFAllocatorDefault, passphrase, kCFStringEncodingUTF8);
+	params.passphrase = pass;
+	OSStatus status = SecItemExport(CFArrayGetValueAtIndex(identities, i), kSecFormatPKCS12, 0, &params, pkcs12);
+	CFRelease(pass);
+	return status;
+}
+
+// errorMessage describes status; the caller frees the result.
+static char *errorMessage(OSStatus status) {
+	CFStringRef msg = SecCopyErrorMessageString(status, NULL);
+	if (msg == NULL) {
+		return NULL;
+	}
+	CFIndex size = CFStringGetMaximumSizeForEncoding(CFStringGetLength(msg), kCFStringEncodingUTF8) + 1;
+	char *buf = malloc(size);
+	if (!CFStringGetCString(msg, buf, size, kCFStringEncodingUTF8)) {
+		free(buf);
+		buf = NULL;
+	}
+	CFRelease(msg);
+	return buf;
+}
+*/
+import "C"
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"unsafe"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// loadKeychain exports the certificate named name and its private key from
+// the login or System keychain. macOS asks the user to allow the export
+// unless the tool is already trusted by the key's access list. The export is
+// a PKCS#12 blob kept in memory, never a file.
+func loadKeychain(logger certimport.Logger, name string) (*certimport.Material, error) {
+	var identities C.CFArrayRef
+	status := C.copyIdentities(&identities)
+	if status == C.errSecItemNotFound {
+		return nil, fmt.Errorf("no certificate with a private key named %q in the Keychain", name)
+	}
+	if status != C.errSecSuccess {
+		return nil, fmt.Errorf("failed to search the Keychain: %w", keychainError(status))
+	}
+	defer C.CFRelease(C.CFTypeRef(identities))
+
+	certs := make([]*x509.Certificate, C.CFArrayGetCount(identities))
+	for i := range certs {
+		data := C.copyCertificateData(identities, C.CFIndex(i))
+		if data == 0 {
+			continue
+		}
+		der := C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(data)), C.int(C.CFDataGetLength(data)))
+		C.CFRelease(C.CFTypeRef(data))
+		certs[i], _ = x509.ParseCertificate(der)
+	}
+	i := pickKeychainCertificate(certs, name)
+	if i < 0 {
+		return nil, fmt.Errorf("no certificate with a private key named %q in the Keychain", name)
+	}
+
+	// The blob only lives until it is parsed, but PKCS#12 needs a password
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	password := hex.EncodeToString(secret)
+	cpassword := C.CString(password)
+	defer C.free(unsafe.Pointer(cpassword))
+
+	var pkcs12 C.CFDataRef
+	if status := C.exportIdentity(identities, C.CFIndex(i), cpassword, &pkcs12); status != C.errSecSuccess {
+		return nil, fmt.Errorf("failed to export %q from the Keychain: %w", name, keychainError(status))
+	}
+	data := C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(pkcs12)), C.int(C.CFDataGetLength(pkcs12)))
+	C.CFRelease(C.CFTypeRef(pkcs12))
+
+	material, err := certimport.LoadPKCS12(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the certificate exported from the Keychain: %w", err)
+	}
+	logger.Printf("✓ Certificate exported from the Keychain (subject: %s, %d CA certificates)\n", material.Leaf.Subject, len(material.Chain))
+	return material, nil
+}
+
+// keychainError turns a Security framework status into an error.
+func keychainError(status C.OSStatus) error {
+	msg := C.errorMessage(status)
+	if msg == nil {
+		return fmt.Errorf("OSStatus %d", int(status))
+	}
+	defer C.free(unsafe.Pointer(msg))
+	return fmt.Errorf("%s (OSStatus %d)", C.GoString(msg), int(status))
+}
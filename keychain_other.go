@@ -0,0 +1,15 @@
+//go:build !darwin || !cgo
+
+package main
+
+import (
+	"errors"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// loadKeychain needs the macOS Security framework, which is reached through
+// cgo.
+func loadKeychain(logger certimport.Logger, name string) (*certimport.Material, error) {
+	return nil, errors.New("-from-keychain is only supported on macOS builds with cgo enabled")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// keyFiles returns the local files of cfg that hold a private key. Keys read
+// from stdin, the environment or AWS are not files the tool can check.
+func keyFiles(cfg CertImportConfig) []string {
+	var files []string
+	for _, path := range []string{cfg.PrivateKeyFile, cfg.BundleFile, cfg.PKCS12File} {
+		if path != "" && path != "-" && !strings.Contains(path, "://") {
+			files = append(files, path)
+		}
+	}
+	if cfg.SecretDir != "" {
+		files = append(files, filepath.Join(cfg.SecretDir, secretKeyFile))
+	}
+	return files
+}
+
+// validateKeyFiles checks the options that -delete-key-after-import cannot
+// be combined with.
+func (cfg *CertImportConfig) validateKeyFiles() error {
+	if !cfg.DeleteKey {
+		return nil
+	}
+	if cfg.Dir != "" || cfg.SecretDir != "" || cfg.Daemon || cfg.DryRun {
+		return errors.New("-delete-key-after-import cannot be combined with -dir, -from-secret-dir, -daemon or -dry-run")
+	}
+	if len(keyFiles(*cfg)) == 0 {
+		return errors.New("-delete-key-after-import needs the private key in a local file")
+	}
+	return nil
+}
+
+// checkKeyFiles warns about private key files that other users can read or
+// replace, or fails with -strict.
+func checkKeyFiles(cfg CertImportConfig, logger certimport.Logger) error {
+	// A KMS ciphertext is useless without access to the KMS key
+	if cfg.KeyKMSEncrypted {
+		return nil
+	}
+	for _, path := range keyFiles(cfg) {
+		for _, problem := range keyFileProblems(path) {
+			if cfg.StrictKeyFiles {
+				return fmt.Errorf("private key file %s %s (-strict)", path, problem)
+			}
+			logger.Warnf("Private key file %s %s", path, problem)
+		}
+	}
+	return nil
+}
+
+// deleteKeyFiles overwrites the private key files of cfg and removes them,
+// once the key is safely in ACM.
+func deleteKeyFiles(cfg CertImportConfig) error {
+	for _, path := range keyFiles(cfg) {
+		if err := shred(path); err != nil {
+			return fmt.Errorf("the certificate was imported but the private key file could not be deleted: %w", err)
+		}
+		progress.Printf("✓ Deleted private key file %s\n", path)
+	}
+	return nil
+}
+
+// shred overwrites a file with zeros before removing it. On copy-on-write
+// and journaling file systems the old blocks may survive; the overwrite only
+// keeps the key from being trivially recovered.
+func shred(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		_, err = f.Write(make([]byte, info.Size()))
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to overwrite %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// keyFileProblems is not implemented where file permissions are not Unix
+// modes.
+func keyFileProblems(path string) []string {
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// keyFileProblems describes why other users could read or replace the
+// private key file at path.
+func keyFileProblems(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		// Reading the key reports the error
+		return nil
+	}
+
+	var problems []string
+	if info.Mode().Perm()&0o004 != 0 {
+		problems = append(problems, fmt.Sprintf("is readable by every user (mode %04o, use chmod 600)", info.Mode().Perm()))
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		problems = append(problems, fmt.Sprintf("is owned by user %d, not the current user", stat.Uid))
+	}
+	dir := filepath.Dir(path)
+	if dirInfo, err := os.Stat(dir); err == nil && dirInfo.Mode().Perm()&0o002 != 0 {
+		problems = append(problems, fmt.Sprintf("is in %s, which every user can write to", dir))
+	}
+	return problems
+}
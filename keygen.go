@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+)
+
+// keyTypes are the key types accepted by -key-type. All of them can be
+// imported into ACM.
+const keyTypes = "ec256, ec384, rsa2048, rsa3072 or rsa4096"
+
+// keyGenerators creates a new private key for each -key-type.
+var keyGenerators = map[string]func() (crypto.Signer, error){
+	"ec256":   func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) },
+	"ec384":   func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) },
+	"rsa2048": func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) },
+	"rsa3072": func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 3072) },
+	"rsa4096": func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 4096) },
+}
+
+// checkKeyType reports an error for an unknown -key-type.
+func checkKeyType(keyType string) error {
+	if _, ok := keyGenerators[keyType]; !ok {
+		return fmt.Errorf("unknown -key-type %q, expected %s", keyType, keyTypes)
+	}
+	return nil
+}
+
+// generateKey creates a new private key of keyType.
+func generateKey(keyType string) (crypto.Signer, error) {
+	if err := checkKeyType(keyType); err != nil {
+		return nil, err
+	}
+	key, err := keyGenerators[keyType]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s key: %w", keyType, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/inventory"
+)
+
+type ListConfig struct {
+	Statuses      []string
+	KeyAlgorithms []string
+	Type          string
+	DomainGlob    string
+	Tags          map[string]string
+	Output        string
+	Region        string
+	Profile       string
+	Timeout       time.Duration
+}
+
+// listedCertificate is the JSON representation of a certificate returned by
+// the list subcommand.
+type listedCertificate = inventory.Certificate
+
+func runList(ctx context.Context, args []string) error {
+	var cfg ListConfig
+	var statuses, keyAlgorithms, tagString string
+
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.StringVar(&statuses, "status", "", "Only list certificates with these statuses, e.g. 'ISSUED,EXPIRED'")
+	fs.StringVar(&keyAlgorithms, "key-algorithm", "", "Only list certificates with these key algorithms, e.g. 'RSA_2048,EC_prime256v1'")
+	fs.StringVar(&cfg.Type, "type", "", "Only list certificates of this type (IMPORTED, AMAZON_ISSUED, PRIVATE)")
+	fs.StringVar(&cfg.DomainGlob, "domain", "", "Only list certificates whose domain or SANs match this glob, e.g. '*.example.com'")
+	fs.StringVar(&tagString, "tags", "", "Only list certificates with these tags, in format 'key1=value1,key2=value2'")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s list [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List certificates in AWS Certificate Manager\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s list -type IMPORTED\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list -status ISSUED -domain '*.example.com' -output json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list -tags 'Environment=prod'\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text or json\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Statuses = cliutil.SplitList(strings.ToUpper(statuses))
+	cfg.KeyAlgorithms = cliutil.SplitList(keyAlgorithms)
+	cfg.Type = strings.ToUpper(cfg.Type)
+	if tagString != "" {
+		cfg.Tags = parseTags(tagString)
+	}
+
+	return listCertificates(ctx, cfg)
+}
+
+func listCertificates(ctx context.Context, cfg ListConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	client := acm.NewFromConfig(awsCfg)
+
+	certs, err := findCertificates(ctx, client, cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == "json" {
+		if certs == nil {
+			certs = []listedCertificate{}
+		}
+		return printJSON(certs)
+	}
+
+	// Quiet mode prints one ARN per line for use in shell pipelines
+	if progress.Quiet() {
+		for _, c := range certs {
+			fmt.Println(c.CertificateArn)
+		}
+		return nil
+	}
+
+	if len(certs) == 0 {
+		fmt.Printf("No certificates found (region: %s)\n", awsCfg.Region)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "DOMAIN\tSTATUS\tTYPE\tKEY\tEXPIRES\tIN USE\tARN\n")
+	for _, c := range certs {
+		expires := "-"
+		if c.NotAfter != nil {
+			expires = c.NotAfter.Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%s\n",
+			c.DomainName, c.Status, c.Type, c.KeyAlgorithm, expires, c.InUse, c.CertificateArn)
+	}
+	return w.Flush()
+}
+
+// findCertificates returns the certificates matching the filters of cfg.
+func findCertificates(ctx context.Context, client *acm.Client, cfg ListConfig) ([]listedCertificate, error) {
+	return inventory.List(ctx, client, inventory.Filter{
+		Statuses:      cfg.Statuses,
+		KeyAlgorithms: cfg.KeyAlgorithms,
+		Type:          cfg.Type,
+		DomainGlob:    cfg.DomainGlob,
+		Tags:          cfg.Tags,
+	})
+}
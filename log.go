@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"golang.org/x/term"
+)
+
+// progressLogger writes human progress messages to stderr so that stdout only
+// carries command results. Warnings are also recorded so they can be included
+// in machine-readable output.
+//
+// In quiet mode only results are printed; in verbose mode debug messages and
+// AWS SDK request and retry logs are printed as well.
+//
+// On a terminal, long operations show a spinner on the last line. When
+// stderr is piped, e.g. into a log aggregator, messages are plain lines
+// with the status symbols spelled out.
+type progressLogger struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	quiet    bool
+	verbose  bool
+	warnings []string
+	// status is the spinner line redrawn below the messages, if any
+	status string
+}
+
+var progress = &progressLogger{out: os.Stderr, tty: term.IsTerminal(int(os.Stderr.Fd()))}
+
+// display holds the -no-color and -ascii flags, which apply to results on
+// stdout as well as to progress messages.
+var display struct {
+	noColor bool
+	ascii   bool
+}
+
+// plainSymbols spells out the status symbols for output that is not read on
+// a terminal, or with -ascii.
+var plainSymbols = strings.NewReplacer("✅ ", "OK: ", "✓ ", "OK: ", "⚠ ", "WARNING: ", "✗ ", "FAILED: ")
+
+// colorSymbols colors the status symbols and their spelled out forms.
+var colorSymbols = strings.NewReplacer(
+	"✓ ", "\033[32m✓\033[0m ", "OK: ", "\033[32mOK:\033[0m ",
+	"⚠ ", "\033[33m⚠\033[0m ", "WARNING: ", "\033[33mWARNING:\033[0m ",
+	"✗ ", "\033[31m✗\033[0m ", "FAILED: ", "\033[31mFAILED:\033[0m ",
+)
+
+// colorEnabled reports whether terminals may be sent colors: not with
+// -no-color, NO_COLOR (https://no-color.org) or a dumb terminal.
+func colorEnabled() bool {
+	return !display.noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// styleSymbols adapts the status symbols in msg to where it is written.
+func styleSymbols(msg string, tty bool) string {
+	if display.ascii || !tty {
+		msg = plainSymbols.Replace(msg)
+	}
+	if tty && colorEnabled() {
+		msg = colorSymbols.Replace(msg)
+	}
+	return msg
+}
+
+// resultWriter prints command results to stdout with the status symbols
+// styled like progress messages.
+type resultWriter struct {
+	tty bool
+}
+
+var stdout = resultWriter{tty: term.IsTerminal(int(os.Stdout.Fd()))}
+
+func (w resultWriter) Printf(format string, args ...interface{}) {
+	fmt.Print(styleSymbols(fmt.Sprintf(format, args...), w.tty))
+}
+
+// addLogFlags registers the -quiet and -verbose flags shared by every
+// subcommand.
+func addLogFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&progress.quiet, "quiet", false, "Only print the result (e.g. the certificate ARN)")
+	fs.BoolVar(&progress.verbose, "verbose", false, "Print debug messages and AWS SDK request/retry logs")
+	fs.BoolVar(&display.noColor, "no-color", false, "Do not color the output (also set by NO_COLOR)")
+	fs.BoolVar(&display.ascii, "ascii", false, "Print OK, WARNING and FAILED instead of the ✓, ⚠ and ✗ symbols")
+	fs.Func("output-template", "Print the result with this Go template instead, e.g. '{{.CertificateArn}} {{.NotAfter}}' (fields as in -output json, by their Go names; applied to each item of a list)", parseOutputTemplate)
+}
+
+// Quiet reports whether only results should be printed.
+func (l *progressLogger) Quiet() bool {
+	return l.quiet && !l.verbose
+}
+
+// Verbose reports whether debug output is enabled.
+func (l *progressLogger) Verbose() bool {
+	return l.verbose
+}
+
+// Printf writes a progress message unless quiet mode is enabled.
+func (l *progressLogger) Printf(format string, args ...interface{}) {
+	if l.Quiet() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(fmt.Sprintf(format, args...))
+}
+
+// Debugf writes a message only in verbose mode.
+func (l *progressLogger) Debugf(format string, args ...interface{}) {
+	if !l.verbose {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(fmt.Sprintf("[debug] "+format, args...))
+}
+
+// Warnf writes a warning and records it for structured output. The message
+// should not include a trailing newline.
+func (l *progressLogger) Warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, msg)
+	if !l.Quiet() {
+		l.write("⚠ " + msg + "\n")
+	}
+}
+
+// write prints msg above the spinner, if one is shown. The caller holds
+// l.mu.
+func (l *progressLogger) write(msg string) {
+	msg = styleSymbols(certimport.RedactPrivateKeys(msg), l.tty)
+	if l.status == "" {
+		fmt.Fprint(l.out, msg)
+		return
+	}
+	fmt.Fprint(l.out, "\r\033[K"+msg)
+	if strings.HasSuffix(msg, "\n") {
+		fmt.Fprint(l.out, l.status)
+	}
+}
+
+// Warnings returns the warnings recorded so far.
+func (l *progressLogger) Warnings() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.warnings...)
+}
+
+// Write lets the logger act as the destination for the AWS SDK logger.
+func (l *progressLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(string(p))
+	return len(p), nil
+}
+
+// spinnerFrames are drawn in turn while an operation is running.
+var spinnerFrames, asciiSpinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏"), []rune(`-\|/`)
+
+// spinner shows that a long operation is running, and how many of its total
+// steps are done, on the last line of a terminal. On anything else it does
+// nothing, leaving the operation's own messages as the log.
+type spinner struct {
+	l     *progressLogger
+	label string
+	total int
+	done  int
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// Spin starts a spinner labelled label. A total of 0 shows no count.
+func (l *progressLogger) Spin(label string, total int) *spinner {
+	s := &spinner{l: l, label: label, total: total}
+	if !l.tty || l.Quiet() || l.verbose {
+		return s
+	}
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+func (s *spinner) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	frames := spinnerFrames
+	if display.ascii {
+		frames = asciiSpinnerFrames
+	}
+	for frame := 0; ; frame++ {
+		s.l.mu.Lock()
+		s.l.status = string(frames[frame%len(frames)]) + " " + s.label
+		if s.total > 0 {
+			s.l.status += fmt.Sprintf(" (%d/%d)", s.done, s.total)
+		}
+		fmt.Fprint(s.l.out, "\r\033[K"+s.l.status)
+		s.l.mu.Unlock()
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step counts one step as done.
+func (s *spinner) Step() {
+	s.l.mu.Lock()
+	defer s.l.mu.Unlock()
+	s.done++
+}
+
+// Stop removes the spinner.
+func (s *spinner) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+	s.l.mu.Lock()
+	defer s.l.mu.Unlock()
+	fmt.Fprint(s.l.out, "\r\033[K")
+	s.l.status = ""
+}
+
+// prefixLogger prefixes every message, e.g. with the name of the manifest
+// entry when several certificates are imported concurrently.
+type prefixLogger struct {
+	prefix string
+	*progressLogger
+}
+
+func (l prefixLogger) Printf(format string, args ...interface{}) {
+	l.progressLogger.Printf(l.prefix+format, args...)
+}
+
+func (l prefixLogger) Debugf(format string, args ...interface{}) {
+	l.progressLogger.Debugf(l.prefix+format, args...)
+}
+
+func (l prefixLogger) Warnf(format string, args ...interface{}) {
+	l.progressLogger.Warnf(l.prefix+format, args...)
+}
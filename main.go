@@ -2,202 +2,150 @@ package main
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"runtime/debug"
 	"strings"
+	"syscall"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/acm"
-	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
 )
 
-type CertImportConfig struct {
-	CertFile       string
-	PrivateKeyFile string
-	ChainFile      string
-	Region         string
-	Profile        string
-	Tags           map[string]string
+// command is a single aws-certs subcommand. Run receives the arguments that
+// follow the subcommand name and is responsible for parsing its own flags.
+// The context is cancelled on SIGINT or SIGTERM.
+type command struct {
+	Name    string
+	Summary string
+	Run     func(ctx context.Context, args []string) error
 }
 
-func main() {
-	var cfg CertImportConfig
-	var tagString string
-
-	// Define command line flags
-	flag.StringVar(&cfg.CertFile, "cert", "", "Path to certificate file (PEM format) - REQUIRED")
-	flag.StringVar(&cfg.PrivateKeyFile, "key", "", "Path to private key file (PEM format) - REQUIRED")
-	flag.StringVar(&cfg.ChainFile, "chain", "", "Path to certificate chain file (PEM format) - OPTIONAL")
-	flag.StringVar(&cfg.Region, "region", "", "AWS region (defaults to AWS_REGION or us-east-1)")
-	flag.StringVar(&cfg.Profile, "profile", "", "AWS profile to use (defaults to default profile)")
-	flag.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
-
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "AWS Certificate Manager Import CLI\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Import SSL/TLS certificates into AWS Certificate Manager\n\n")
-		fmt.Fprintf(os.Stderr, "Required Options:\n")
-		fmt.Fprintf(os.Stderr, "  -cert string    Path to certificate file (PEM format)\n")
-		fmt.Fprintf(os.Stderr, "  -key string     Path to private key file (PEM format)\n\n")
-		fmt.Fprintf(os.Stderr, "Optional Options:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  %s -cert cert.pem -key private-key.pem\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s -cert cert.pem -key key.pem -chain chain.pem -region us-west-2\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s -cert cert.pem -key key.pem -tags 'Environment=prod,Application=web'\n", os.Args[0])
-	}
-
-	flag.Parse()
-
-	// Validate required arguments
-	if cfg.CertFile == "" || cfg.PrivateKeyFile == "" {
-		fmt.Fprintf(os.Stderr, "Error: Both -cert and -key are required\n\n")
-		flag.Usage()
-		os.Exit(1)
-	}
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+var version = ""
 
-	// Parse tags if provided
-	if tagString != "" {
-		cfg.Tags = parseTags(tagString)
+// toolVersion returns the build version, falling back to the module version
+// recorded by "go install".
+func toolVersion() string {
+	if version != "" {
+		return version
 	}
-
-	// Import the certificate
-	if err := importCertificate(cfg); err != nil {
-		log.Fatalf("Failed to import certificate: %v", err)
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
 	}
+	return "dev"
 }
 
-func parseTags(tagString string) map[string]string {
-	tags := make(map[string]string)
-	pairs := strings.Split(tagString, ",")
-
-	for _, pair := range pairs {
-		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
-		if len(kv) == 2 {
-			key := strings.TrimSpace(kv[0])
-			value := strings.TrimSpace(kv[1])
-			if key != "" && value != "" {
-				tags[key] = value
-			}
-		}
-	}
-
-	return tags
+// defaultCommand is run when no subcommand is given, which keeps the original
+// flat invocation (aws-certs -cert ... -key ...) working.
+const defaultCommand = "import"
+
+var commands = []command{
+	{Name: "import", Summary: "Import a certificate into ACM (default)", Run: runImport},
+	{Name: "import-batch", Summary: "Import many certificates described by a manifest", Run: runImportBatch},
+	{Name: "acme", Summary: "Obtain a certificate via ACME (DNS-01 in Route 53) and import it", Run: runAcme},
+	{Name: "request", Summary: "Request an ACM certificate and create its DNS validation records", Run: runRequest},
+	{Name: "issue-pca", Summary: "Issue a certificate from ACM Private CA and import it", Run: runIssuePCA},
+	{Name: "gen-selfsigned", Summary: "Create a self-signed certificate for testing, optionally importing it", Run: runGenSelfSigned},
+	{Name: "certbot", Summary: "Import or re-import certbot lineages (deploy-hook friendly)", Run: runCertbot},
+	{Name: "migrate-iam", Summary: "Move IAM server certificates to ACM and re-point their listeners", Run: runMigrateIAM},
+	{Name: "copy", Summary: "Copy an imported certificate to other regions, with its tags", Run: runCopy},
+	{Name: "copy-account", Summary: "Copy an imported certificate to another account through IAM roles", Run: runCopyAccount},
+	{Name: "attach-elb", Summary: "Attach a certificate to an ALB/NLB listener", Run: runAttachELB},
+	{Name: "attach-cloudfront", Summary: "Use a certificate for a CloudFront distribution", Run: runAttachCloudFront},
+	{Name: "attach-apigw", Summary: "Use a certificate for an API Gateway custom domain", Run: runAttachAPIGateway},
+	{Name: "rotate", Summary: "Replace a certificate on every resource that uses it", Run: runRotate},
+	{Name: "list", Summary: "List certificates with optional filters", Run: runList},
+	{Name: "find", Summary: "Print the ARNs of certificates matching tags and a domain glob", Run: runFind},
+	{Name: "describe", Summary: "Describe a certificate by ARN or domain", Run: runDescribe},
+	{Name: "inspect", Summary: "Show the details of local certificate files without calling AWS", Run: runInspect},
+	{Name: "verify-chain", Summary: "Check a chain against the system and Amazon Trust Services roots", Run: runVerifyChain},
+	{Name: "export", Summary: "Download a certificate and chain (and exportable keys) as PEM", Run: runExport},
+	{Name: "delete", Summary: "Delete a certificate that is no longer in use", Run: runDelete},
+	{Name: "prune", Summary: "Delete expired and superseded imported certificates", Run: runPrune},
+	{Name: "tag", Summary: "Add or update tags on a certificate", Run: runTag},
+	{Name: "untag", Summary: "Remove tags from a certificate", Run: runUntag},
+	{Name: "report", Summary: "Inventory certificates across regions and accounts as CSV, JSON or HTML", Run: runReport},
+	{Name: "check-expiry", Summary: "Report certificates expiring soon (non-zero exit if any)", Run: runCheckExpiry},
+	{Name: "deploy-monitor", Summary: "Deploy a scheduled Lambda that alerts on expiring certificates", Run: runDeployMonitor},
+	{Name: "diff", Summary: "Compare a local certificate with its copy in ACM", Run: runDiff},
+	{Name: "check-endpoint", Summary: "Compare the certificate a live endpoint serves with a file or ARN", Run: runCheckEndpoint},
+	{Name: "advise", Summary: "Check whether ACM could issue and renew a certificate instead", Run: runAdvise},
 }
 
-func readFile(filename string) ([]byte, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
-	}
-	return data, nil
-}
+func main() {
+	args := os.Args[1:]
 
-func validatePEMFormat(data []byte, fileType string) error {
-	content := string(data)
-	if !strings.Contains(content, "BEGIN") || !strings.Contains(content, "END") {
-		return fmt.Errorf("%s file does not appear to be in PEM format", fileType)
+	if len(args) == 0 {
+		usage()
+		os.Exit(exitUsage)
 	}
-	return nil
-}
 
-func importCertificate(cfg CertImportConfig) error {
-	fmt.Printf("Reading certificate files...\n")
-
-	// Read certificate file
-	certData, err := readFile(cfg.CertFile)
-	if err != nil {
-		return err
-	}
-	if err := validatePEMFormat(certData, "certificate"); err != nil {
-		return err
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
 	}
-	fmt.Printf("✓ Certificate file read successfully\n")
 
-	// Read private key file
-	keyData, err := readFile(cfg.PrivateKeyFile)
-	if err != nil {
-		return err
-	}
-	if err := validatePEMFormat(keyData, "private key"); err != nil {
-		return err
-	}
-	fmt.Printf("✓ Private key file read successfully\n")
-
-	// Read certificate chain file (optional)
-	var chainData []byte
-	if cfg.ChainFile != "" {
-		chainData, err = readFile(cfg.ChainFile)
-		if err != nil {
-			return err
-		}
-		if err := validatePEMFormat(chainData, "certificate chain"); err != nil {
-			return err
-		}
-		fmt.Printf("✓ Certificate chain file read successfully\n")
-	}
-
-	// Load AWS configuration
-	fmt.Printf("Initializing AWS client...\n")
-
-	var awsCfg aws.Config
-	if cfg.Profile != "" {
-		awsCfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithSharedConfigProfile(cfg.Profile),
-			config.WithRegion(cfg.Region),
-		)
-	} else {
-		awsCfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithRegion(cfg.Region),
-		)
+	// Flags without a subcommand are passed to the default command
+	name := defaultCommand
+	if !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+		usage()
+		os.Exit(exitUsage)
 	}
 
-	// Create ACM client
-	client := acm.NewFromConfig(awsCfg)
-
-	fmt.Printf("✓ AWS ACM client initialized (region: %s)\n", awsCfg.Region)
+	// Cancel in-flight AWS requests on Ctrl-C or SIGTERM. Once cancelled the
+	// default signal handling is restored, so a second Ctrl-C exits at once.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	// Prepare import input
-	input := &acm.ImportCertificateInput{
-		Certificate: certData,
-		PrivateKey:  keyData,
-	}
-
-	if chainData != nil {
-		input.CertificateChain = chainData
+	err := cmd.Run(ctx, args)
+	interrupted := ctx.Err() != nil
+	stop()
+	if err != nil {
+		// Errors can quote inputs, which may have been keys
+		err = certimport.RedactError(err, nil)
+		switch {
+		case jsonErrors:
+			printErrorJSON(describeError(err, interrupted))
+		case interrupted && errors.Is(err, context.Canceled):
+			log.Printf("Error: interrupted: %v", err)
+		case errors.Is(err, context.DeadlineExceeded):
+			log.Printf("Error: timed out (see -timeout): %v", errorMessage(err))
+		default:
+			log.Printf("Error: %v", errorHint(err))
+		}
+		os.Exit(exitCode(err, interrupted))
 	}
+}
 
-	// Add tags if provided
-	if len(cfg.Tags) > 0 {
-		var tags []types.Tag
-		for key, value := range cfg.Tags {
-			tags = append(tags, types.Tag{
-				Key:   aws.String(key),
-				Value: aws.String(value),
-			})
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
 		}
-		input.Tags = tags
-		fmt.Printf("✓ Tags prepared: %d tags\n", len(tags))
 	}
+	return nil
+}
 
-	// Import the certificate
-	fmt.Printf("Importing certificate to ACM...\n")
-
-	result, err := client.ImportCertificate(context.TODO(), input)
-	if err != nil {
-		return fmt.Errorf("failed to import certificate: %w", err)
+func usage() {
+	fmt.Fprintf(os.Stderr, "AWS Certificate Manager CLI\n\n")
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [OPTIONS]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", cmd.Name, cmd.Summary)
 	}
-
-	fmt.Printf("✅ Certificate imported successfully!\n")
-	fmt.Printf("Certificate ARN: %s\n", aws.ToString(result.CertificateArn))
-
-	return nil
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for command options.\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "When no command is given, options are passed to '%s'.\n", defaultCommand)
 }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"golang.org/x/term"
+)
+
+// loadMaterial reads the certificate, key and chain from the configured
+// inputs: a PKCS#12 bundle, a combined PEM bundle, a Kubernetes TLS secret
+// directory, the Windows certificate store, the macOS Keychain or separate
+// files.
+func loadMaterial(ctx context.Context, cfg CertImportConfig, awsCfg aws.Config, logger certimport.Logger) (*certimport.Material, error) {
+	if err := checkKeyFiles(cfg, logger); err != nil {
+		return nil, err
+	}
+
+	var material *certimport.Material
+	var err error
+	// NoPrompt is set when several certificates are read concurrently and
+	// prompts would interleave on the terminal
+	var passphrase certimport.PassphraseFunc
+	if !cfg.NoPrompt || cfg.KeyPassphrase != "" || cfg.KeyPassphraseFile != "" {
+		passphrase = keyPassphraseFunc(cfg.KeyPassphrase, cfg.KeyPassphraseFile)
+	}
+	switch {
+	case cfg.PKCS12File != "":
+		material, err = loadPKCS12(ctx, awsCfg, logger, input{Path: cfg.PKCS12File}, cfg.Passphrase, cfg.PassphraseFile)
+	case cfg.BundleFile != "":
+		material, err = loadBundle(ctx, awsCfg, logger, input{Path: cfg.BundleFile}, passphrase)
+	case cfg.SecretDir != "":
+		material, err = loadSecretDir(logger, cfg.SecretDir, passphrase)
+	case cfg.FromWindowsStore:
+		material, err = loadWindowsStore(logger, cfg.Thumbprint, cfg.StoreLocation)
+	case cfg.FromKeychain:
+		material, err = loadKeychain(logger, cfg.KeychainName)
+	default:
+		certFile := input{Path: cfg.CertFile, Env: cfg.CertEnv, Secret: cfg.CertSecret, Data: cfg.CertData}
+		keyFile := input{Path: cfg.PrivateKeyFile, Env: cfg.KeyEnv, Secret: cfg.KeySecret, Data: cfg.KeyData, KMSKeyID: cfg.KMSKeyID}
+		material, err = loadPEMFiles(ctx, awsCfg, logger, certFile, keyFile, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and parse certificate chain file (optional)
+	chain := input{Path: cfg.ChainFile, Env: cfg.ChainEnv, Secret: cfg.ChainSecret, Data: cfg.ChainData}
+	if chain.IsSet() {
+		chainPEM, err := chain.read(ctx, awsCfg)
+		if err != nil {
+			return nil, err
+		}
+		n, err := material.AddChain(chainPEM)
+		if err != nil {
+			return nil, err
+		}
+		logger.Printf("✓ Certificate chain file read successfully (%d certificates%s)\n", n, derNote(chainPEM))
+	}
+
+	return material, nil
+}
+
+func loadPEMFiles(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, certFile, keyFile input, passphrase certimport.PassphraseFunc) (*certimport.Material, error) {
+	certPEM, err := certFile.read(ctx, awsCfg)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := keyFile.read(ctx, awsCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The parsed key is all that is needed from here on
+	defer clear(keyPEM)
+
+	material, err := certimport.LoadPEM(certPEM, keyPEM, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("✓ Certificate file read successfully (subject: %s%s)\n", material.Leaf.Subject, derNote(certPEM))
+	if certimport.IsDER(keyPEM) {
+		logger.Printf("✓ Private key file read successfully (converted from DER)\n")
+	} else {
+		logger.Printf("✓ Private key file read successfully\n")
+	}
+	return material, nil
+}
+
+func loadBundle(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, file input, passphrase certimport.PassphraseFunc) (*certimport.Material, error) {
+	data, err := file.read(ctx, awsCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(data)
+
+	material, err := certimport.LoadBundle(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("✓ Bundle file read successfully (subject: %s, %d chain certificates)\n", material.Leaf.Subject, len(material.Chain))
+	return material, nil
+}
+
+// Files of a Kubernetes TLS secret, as written by cert-manager.
+const (
+	secretCertFile = "tls.crt"
+	secretKeyFile  = "tls.key"
+	secretCAFile   = "ca.crt"
+)
+
+// loadSecretDir reads a directory in the Kubernetes TLS secret layout:
+// tls.crt holds the leaf followed by the intermediates and ca.crt, when
+// present and not empty, the issuing CA. CA certificates already in tls.crt
+// are not added twice.
+func loadSecretDir(logger certimport.Logger, dir string, passphrase certimport.PassphraseFunc) (*certimport.Material, error) {
+	certPEM, err := readFile(filepath.Join(dir, secretCertFile))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := readFile(filepath.Join(dir, secretKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	defer clear(keyPEM)
+
+	material, err := certimport.LoadFullChain(certPEM, keyPEM, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	logger.Printf("✓ %s read successfully (subject: %s, %d chain certificates)\n", secretCertFile, material.Leaf.Subject, len(material.Chain))
+
+	caPEM, err := os.ReadFile(filepath.Join(dir, secretCAFile))
+	if errors.Is(err, os.ErrNotExist) || len(bytes.TrimSpace(caPEM)) == 0 {
+		return material, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filepath.Join(dir, secretCAFile), err)
+	}
+	cas, err := certimport.ParseCertificates(caPEM, secretCAFile)
+	if err != nil {
+		return nil, err
+	}
+	added := 0
+	for _, ca := range cas {
+		if !ca.Equal(material.Leaf) && !slices.ContainsFunc(material.Chain, ca.Equal) {
+			material.Chain = append(material.Chain, ca)
+			added++
+		}
+	}
+	logger.Printf("✓ %s read successfully (%d certificates added to the chain)\n", secretCAFile, added)
+	return material, nil
+}
+
+func loadPKCS12(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, file input, passphrase, passphraseFile string) (*certimport.Material, error) {
+	data, err := file.read(ctx, awsCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := readPassphrase(passphrase, passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := certimport.LoadPKCS12(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PKCS#12 file %s: %w", file, err)
+	}
+	logger.Printf("✓ PKCS#12 file read successfully (subject: %s, %d CA certificates)\n", material.Leaf.Subject, len(material.Chain))
+	return material, nil
+}
+
+// derNote returns a suffix for progress messages when data was DER encoded.
+func derNote(data []byte) string {
+	if certimport.IsDER(data) {
+		return ", converted from DER"
+	}
+	return ""
+}
+
+// readPassphrase returns the passphrase given directly or, if passphraseFile
+// is set, the first line of that file.
+func readPassphrase(passphrase, passphraseFile string) (string, error) {
+	if passphraseFile == "" {
+		return passphrase, nil
+	}
+	data, err := readFile(passphraseFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// keyPassphraseFunc returns the passphrase source for an encrypted private
+// key: the flag value, the passphrase file, or an interactive prompt when
+// stdin is a terminal.
+func keyPassphraseFunc(passphrase, passphraseFile string) certimport.PassphraseFunc {
+	return func() ([]byte, error) {
+		if passphrase != "" || passphraseFile != "" {
+			value, err := readPassphrase(passphrase, passphraseFile)
+			return []byte(value), err
+		}
+
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return nil, certimport.ErrEncryptedKey
+		}
+		fmt.Fprintf(os.Stderr, "Private key passphrase: ")
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return value, nil
+	}
+}
+
+func readFile(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type MigrateIAMConfig struct {
+	KeysFile         string
+	PathPrefix       string
+	Names            []string
+	RepointListeners bool
+	AllowExpired     bool
+	DryRun           bool
+	Tags             map[string]string
+	Output           string
+	Region           string
+	Profile          string
+	Timeout          time.Duration
+}
+
+// migratedCertificate is the outcome of migrating one IAM server
+// certificate.
+type migratedCertificate struct {
+	Name           string     `json:"name"`
+	IAMArn         string     `json:"iamArn"`
+	DomainName     string     `json:"domainName,omitempty"`
+	NotAfter       *time.Time `json:"notAfter,omitempty"`
+	Status         string     `json:"status"`
+	CertificateArn string     `json:"certificateArn,omitempty"`
+	Listeners      []string   `json:"listeners,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// Statuses of a migrated certificate.
+const (
+	migrated     = "migrated"
+	wouldMigrate = "would migrate"
+	skippedNoKey = "skipped"
+	migrateFail  = "failed"
+)
+
+// listenerUse is a listener serving a certificate, by default or for SNI.
+type listenerUse struct {
+	ListenerArn string
+	IsDefault   bool
+}
+
+func runMigrateIAM(ctx context.Context, args []string) error {
+	var cfg MigrateIAMConfig
+	var names, tagString string
+
+	fs := flag.NewFlagSet("migrate-iam", flag.ExitOnError)
+	fs.StringVar(&cfg.KeysFile, "keys", "", "YAML or JSON file mapping server certificate names to their private keys (paths or ssm://, s3:// or vault:// URIs) - REQUIRED")
+	fs.StringVar(&cfg.PathPrefix, "path-prefix", "/", "Only migrate server certificates under this IAM path, e.g. /cloudfront/")
+	fs.StringVar(&names, "names", "", "Only migrate these server certificates (comma-separated)")
+	fs.BoolVar(&cfg.RepointListeners, "repoint-listeners", false, "Switch the ALB/NLB listeners in -region that serve a migrated certificate to its ACM copy")
+	fs.BoolVar(&cfg.AllowExpired, "allow-expired", false, "Migrate expired server certificates too")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Show what would be imported and re-pointed without changing anything")
+	fs.StringVar(&tagString, "tags", "", "Tags for the imported certificates in format 'key1=value1,key2=value2'")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s migrate-iam -keys <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Import IAM server certificates, with their chains, into ACM in -region. IAM never returns\n")
+		fmt.Fprintf(os.Stderr, "private keys, so -keys maps each certificate name to its key; certificates without one are\n")
+		fmt.Fprintf(os.Stderr, "skipped. The IAM certificates are left in place; delete them once nothing uses them.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nKeys file:\n")
+		fmt.Fprintf(os.Stderr, "  www.example.com-2025-06-30: keys/www.key\n")
+		fmt.Fprintf(os.Stderr, "  api.example.com-2025-09-01: ssm:///tls/api/key\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s migrate-iam -keys keys.yaml -dry-run\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s migrate-iam -keys keys.yaml -region eu-west-1 -repoint-listeners\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	if cfg.KeysFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: -keys is required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text or json\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	cfg.Names = cliutil.SplitList(names)
+	cfg.Tags = parseTags(tagString)
+	if err := certimport.ValidateTags(cfg.Tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return migrateIAM(ctx, cfg)
+}
+
+func migrateIAM(ctx context.Context, cfg MigrateIAMConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	keys, err := loadKeyMapping(cfg.KeysFile)
+	if err != nil {
+		return err
+	}
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := preflight(ctx, awsCfg, progress); err != nil {
+		return err
+	}
+
+	client := iam.NewFromConfig(awsCfg)
+	certs := []migratedCertificate{}
+	paginator := iam.NewListServerCertificatesPaginator(client, &iam.ListServerCertificatesInput{
+		PathPrefix: aws.String(cfg.PathPrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list IAM server certificates: %w", err)
+		}
+		for _, meta := range page.ServerCertificateMetadataList {
+			name := aws.ToString(meta.ServerCertificateName)
+			if len(cfg.Names) > 0 && !slices.Contains(cfg.Names, name) {
+				continue
+			}
+			certs = append(certs, migratedCertificate{Name: name, IAMArn: aws.ToString(meta.Arn), NotAfter: meta.Expiration})
+		}
+	}
+	for _, name := range cfg.Names {
+		if !slices.ContainsFunc(certs, func(c migratedCertificate) bool { return c.Name == name }) {
+			return fmt.Errorf("IAM server certificate %s not found under path %s", name, cfg.PathPrefix)
+		}
+	}
+	progress.Printf("✓ Found %d IAM server certificates (%d with a key in %s)\n", len(certs), countWithKeys(certs, keys), cfg.KeysFile)
+
+	var uses map[string][]listenerUse
+	if cfg.RepointListeners {
+		if uses, err = listenerCertificates(ctx, awsCfg); err != nil {
+			return err
+		}
+	}
+
+	for i := range certs {
+		c := &certs[i]
+		ref, ok := keys[c.Name]
+		if !ok {
+			c.Status, c.Error = skippedNoKey, "no private key in "+cfg.KeysFile
+			continue
+		}
+		migrateServerCertificate(ctx, awsCfg, cfg, c, ref, uses[c.IAMArn])
+	}
+
+	if err := printMigrated(cfg.Output, certs); err != nil {
+		return err
+	}
+	return migrationSummary(certs)
+}
+
+// loadKeyMapping reads the -keys file. Relative paths are resolved against
+// the file's directory.
+func loadKeyMapping(path string) (map[string]string, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys map[string]string
+	if err := decodeStrict(path, data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse keys file %s: %w", path, err)
+	}
+	for name, ref := range keys {
+		keys[name] = resolvePath(filepath.Dir(path), ref)
+	}
+	return keys, nil
+}
+
+func countWithKeys(certs []migratedCertificate, keys map[string]string) int {
+	n := 0
+	for _, c := range certs {
+		if _, ok := keys[c.Name]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// migrateServerCertificate imports one server certificate and its key into
+// ACM like import would, then re-points the listeners in uses.
+func migrateServerCertificate(ctx context.Context, awsCfg aws.Config, cfg MigrateIAMConfig, c *migratedCertificate, keyRef string, uses []listenerUse) {
+	logger := prefixLogger{prefix: "[" + c.Name + "] ", progressLogger: progress}
+	fail := func(err error) {
+		c.Status, c.Error = migrateFail, errorHint(err).Error()
+		logger.Warnf("%v", c.Error)
+	}
+
+	out, err := iam.NewFromConfig(awsCfg).GetServerCertificate(ctx, &iam.GetServerCertificateInput{
+		ServerCertificateName: aws.String(c.Name),
+	})
+	if err != nil {
+		fail(fmt.Errorf("failed to read IAM server certificate %s: %w", c.Name, err))
+		return
+	}
+	keyPEM, err := input{Path: keyRef}.read(ctx, awsCfg)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer clear(keyPEM)
+
+	material, err := certimport.LoadPEM([]byte(aws.ToString(out.ServerCertificate.CertificateBody)), keyPEM, nil)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if chain := aws.ToString(out.ServerCertificate.CertificateChain); chain != "" {
+		if _, err := material.AddChain([]byte(chain)); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	importCfg := CertImportConfig{
+		Tags:         mergeTags(cfg.Tags, map[string]string{"MigratedFrom": c.IAMArn}),
+		AllowExpired: cfg.AllowExpired,
+		DryRun:       cfg.DryRun,
+		Output:       cfg.Output,
+		Timeout:      cfg.Timeout,
+	}
+	if len(uses) > 0 {
+		importCfg.IntendedUses = []string{"elb"}
+	}
+	result := importMaterial(ctx, awsCfg, logger, c.Name, importCfg, material)
+	c.DomainName = result.DomainName
+	if result.Error != "" || len(result.Regions) == 0 || result.Regions[0].Error != "" {
+		c.Status, c.Error = migrateFail, result.Error
+		if len(result.Regions) > 0 && result.Regions[0].Error != "" {
+			c.Error = result.Regions[0].Error
+		}
+		return
+	}
+	c.CertificateArn = result.Regions[0].CertificateArn
+
+	for _, use := range uses {
+		c.Listeners = append(c.Listeners, use.ListenerArn)
+	}
+	if cfg.DryRun {
+		c.Status = wouldMigrate
+		return
+	}
+	c.Status = migrated
+	if err := repointListeners(ctx, awsCfg, uses, c.IAMArn, c.CertificateArn); err != nil {
+		fail(fmt.Errorf("imported as %s, but %w", c.CertificateArn, err))
+	}
+}
+
+// listenerCertificates maps the certificate ARNs served by the ALB/NLB
+// listeners of the region to the listeners that serve them.
+func listenerCertificates(ctx context.Context, awsCfg aws.Config) (map[string][]listenerUse, error) {
+	client := elb.NewFromConfig(awsCfg)
+	uses := map[string][]listenerUse{}
+
+	balancers := elb.NewDescribeLoadBalancersPaginator(client, &elb.DescribeLoadBalancersInput{})
+	for balancers.HasMorePages() {
+		page, err := balancers.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list load balancers: %w", err)
+		}
+		for _, lb := range page.LoadBalancers {
+			listeners := elb.NewDescribeListenersPaginator(client, &elb.DescribeListenersInput{LoadBalancerArn: lb.LoadBalancerArn})
+			for listeners.HasMorePages() {
+				page, err := listeners.NextPage(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list listeners of %s: %w", aws.ToString(lb.LoadBalancerArn), err)
+				}
+				for _, l := range page.Listeners {
+					if len(l.Certificates) == 0 {
+						continue
+					}
+					input := &elb.DescribeListenerCertificatesInput{ListenerArn: l.ListenerArn}
+					for {
+						out, err := client.DescribeListenerCertificates(ctx, input)
+						if err != nil {
+							return nil, fmt.Errorf("failed to list certificates of listener %s: %w", aws.ToString(l.ListenerArn), err)
+						}
+						for _, c := range out.Certificates {
+							arn := aws.ToString(c.CertificateArn)
+							uses[arn] = append(uses[arn], listenerUse{ListenerArn: aws.ToString(l.ListenerArn), IsDefault: aws.ToBool(c.IsDefault)})
+						}
+						if out.NextMarker == nil {
+							break
+						}
+						input.Marker = out.NextMarker
+					}
+				}
+			}
+		}
+	}
+	return uses, nil
+}
+
+// repointListeners swaps oldArn for newArn on the listeners in uses.
+func repointListeners(ctx context.Context, awsCfg aws.Config, uses []listenerUse, oldArn, newArn string) error {
+	client := elb.NewFromConfig(awsCfg)
+	for _, use := range uses {
+		var err error
+		if use.IsDefault {
+			err = attachListener(ctx, awsCfg, use.ListenerArn, newArn, false)
+		} else {
+			err = replaceListenerSNI(ctx, client, use.ListenerArn, oldArn, newArn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printMigrated(format string, certs []migratedCertificate) error {
+	if format == "json" {
+		return printJSON(certs)
+	}
+	if progress.Quiet() {
+		for _, c := range certs {
+			if c.CertificateArn != "" {
+				fmt.Println(c.CertificateArn)
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tDOMAIN\tEXPIRES\tSTATUS\tLISTENERS\tACM ARN / ERROR\n")
+	for _, c := range certs {
+		expires := "-"
+		if c.NotAfter != nil {
+			expires = c.NotAfter.Format("2006-01-02")
+		}
+		detail := firstSet(c.Error, c.CertificateArn, "-")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", c.Name, c.DomainName, expires, c.Status, len(c.Listeners), detail)
+	}
+	return w.Flush()
+}
+
+// migrationSummary prints the counts of each outcome and returns an error if
+// any certificate failed to migrate.
+func migrationSummary(certs []migratedCertificate) error {
+	counts := map[string]int{}
+	listeners := 0
+	for _, c := range certs {
+		counts[c.Status]++
+		if c.Status == migrated {
+			listeners += len(c.Listeners)
+		}
+	}
+	progress.Printf("Summary: %d migrated, %d would migrate, %d skipped (no key), %d failed; %d listeners re-pointed\n",
+		counts[migrated], counts[wouldMigrate], counts[skippedNoKey], counts[migrateFail], listeners)
+
+	failed := counts[migrateFail]
+	if failed > 0 && failed == len(certs)-counts[skippedNoKey] {
+		return fmt.Errorf("all %d server certificates with keys failed to migrate", failed)
+	}
+	if failed > 0 {
+		return partialFailure(fmt.Errorf("%d of %d server certificates failed to migrate", failed, len(certs)))
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// Import event types sent by notifications.
+const (
+	eventImported   = "imported"
+	eventReimported = "reimported"
+	eventFailed     = "failed"
+)
+
+// EventBridge events have this source and a detail type per event type.
+const eventSource = "bldmgr.aws-certs"
+
+var eventDetailTypes = map[string]string{
+	eventImported:   "CertificateImported",
+	eventReimported: "CertificateReimported",
+	eventFailed:     "CertificateImportFailed",
+}
+
+// notifyTimeout bounds each notification, so a slow endpoint cannot hold up
+// an import that already succeeded.
+const notifyTimeout = 10 * time.Second
+
+// notifier sends an importEvent after each import to the configured
+// destinations. The zero value sends nothing.
+type notifier struct {
+	SNSTopic         string
+	Webhook          string
+	Slack            string
+	EventBus         string
+	Metrics          bool
+	MetricsNamespace string
+	AuditLog         string
+}
+
+// importEvent is the JSON document posted for an import.
+type importEvent struct {
+	Event       string         `json:"event"`
+	Time        time.Time      `json:"time"`
+	Name        string         `json:"name,omitempty"`
+	DomainName  string         `json:"domainName,omitempty"`
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	NotAfter    *time.Time     `json:"notAfter,omitempty"`
+	Regions     []regionResult `json:"regions,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	ErrorCode   string         `json:"errorCode,omitempty"`
+}
+
+// addNotifyFlags registers the notification flags.
+func addNotifyFlags(fs *flag.FlagSet, n *notifier) {
+	fs.StringVar(&n.SNSTopic, "notify-sns", "", "Publish a JSON event for every import, re-import or failure to this SNS topic ARN")
+	fs.StringVar(&n.Webhook, "notify-webhook", "", "POST a JSON event for every import, re-import or failure to this URL")
+	fs.StringVar(&n.EventBus, "notify-eventbridge", "", "Put a "+eventSource+" event for every import, re-import or failure on this EventBridge bus name or ARN (e.g. default)")
+	fs.StringVar(&n.Slack, "notify-slack", "", "Post a message for every import, re-import or failure to this Slack incoming webhook URL")
+	fs.BoolVar(&n.Metrics, "cloudwatch-metrics", false, "Publish ImportSuccess, ImportFailure and DaysToExpiry metrics to CloudWatch after each import")
+	fs.StringVar(&n.MetricsNamespace, "metrics-namespace", "AWSCerts", "CloudWatch namespace for -cloudwatch-metrics")
+	fs.StringVar(&n.AuditLog, "audit-log", "", "Append a JSON line recording each import and the AWS identity behind it to this file, or to 'logs:<group>' in CloudWatch Logs")
+}
+
+func (n notifier) validate() error {
+	if n.SNSTopic != "" {
+		if _, err := arnRegion(n.SNSTopic); err != nil || !strings.HasPrefix(n.SNSTopic, "arn:") {
+			return fmt.Errorf("invalid -notify-sns topic ARN %q", n.SNSTopic)
+		}
+	}
+	if n.Webhook != "" {
+		if u, err := url.Parse(n.Webhook); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid -notify-webhook URL %q", n.Webhook)
+		}
+	}
+	if strings.HasPrefix(n.EventBus, "arn:") {
+		if _, err := arnRegion(n.EventBus); err != nil {
+			return fmt.Errorf("invalid -notify-eventbridge bus ARN %q", n.EventBus)
+		}
+	}
+	if n.AuditLog == auditLogsPrefix {
+		return errors.New("-audit-log logs: needs a log group name")
+	}
+	if n.Slack != "" {
+		if u, err := url.Parse(n.Slack); err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("invalid -notify-slack URL, expected https://hooks.slack.com/services/...")
+		}
+	}
+	return nil
+}
+
+func (n notifier) enabled() bool {
+	return n.SNSTopic != "" || n.Webhook != "" || n.Slack != "" || n.EventBus != "" || n.Metrics || n.AuditLog != ""
+}
+
+// notifyImport sends the event for an import result. Dry runs are not
+// reported, and imports that found an identical certificate already in ACM
+// only update the metrics. Notification failures are only warnings, since
+// the import itself is done.
+func notifyImport(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, n notifier, r batchResult) {
+	if !n.enabled() || r.DryRun {
+		return
+	}
+	event := importEvent{
+		Event:       eventImported,
+		Time:        time.Now().UTC(),
+		Name:        r.Name,
+		DomainName:  r.DomainName,
+		Fingerprint: r.Fingerprint,
+		NotAfter:    r.NotAfter,
+		Regions:     r.Regions,
+		Error:       r.Error,
+		ErrorCode:   r.ErrorCode,
+	}
+	existing := len(r.Regions) > 0
+	for _, region := range r.Regions {
+		existing = existing && region.Existing
+		if region.Reimported {
+			event.Event = eventReimported
+		}
+	}
+	if r.failed() {
+		event.Event = eventFailed
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+	if n.Metrics {
+		if err := putMetrics(ctx, awsCfg, n.MetricsNamespace, event); err != nil {
+			logger.Warnf("Failed to publish metrics: %v", err)
+		}
+	}
+	if existing && !r.failed() {
+		return
+	}
+	if n.AuditLog != "" {
+		if err := writeAudit(ctx, awsCfg, n.AuditLog, event); err != nil {
+			logger.Warnf("Failed to write audit log: %v", err)
+		}
+	}
+	if err := n.send(ctx, awsCfg, event); err != nil {
+		logger.Warnf("Failed to send notification: %v", err)
+	}
+}
+
+// putMetrics publishes the outcome of an import: ImportSuccess and
+// ImportFailure without dimensions, so one alarm covers every certificate,
+// and DaysToExpiry per domain.
+func putMetrics(ctx context.Context, awsCfg aws.Config, namespace string, event importEvent) error {
+	success, failure := 1.0, 0.0
+	if event.Event == eventFailed {
+		success, failure = 0, 1
+	}
+	data := []cwtypes.MetricDatum{
+		{MetricName: aws.String("ImportSuccess"), Value: aws.Float64(success), Unit: cwtypes.StandardUnitCount},
+		{MetricName: aws.String("ImportFailure"), Value: aws.Float64(failure), Unit: cwtypes.StandardUnitCount},
+	}
+	if event.NotAfter != nil && event.DomainName != "" {
+		data = append(data, cwtypes.MetricDatum{
+			MetricName: aws.String("DaysToExpiry"),
+			Dimensions: []cwtypes.Dimension{{Name: aws.String("Domain"), Value: aws.String(event.DomainName)}},
+			Value:      aws.Float64(float64(daysUntil(*event.NotAfter))),
+			Unit:       cwtypes.StandardUnitNone,
+		})
+	}
+
+	_, err := cloudwatch.NewFromConfig(awsCfg).PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(namespace),
+		MetricData: data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put metrics in %s: %w", namespace, err)
+	}
+	progress.Debugf("Published metrics to %s in %s\n", namespace, awsCfg.Region)
+	return nil
+}
+
+// notifyFailure sends a failed event for an import that stopped before
+// anything was imported.
+func notifyFailure(ctx context.Context, awsCfg aws.Config, n notifier, err error) {
+	var result batchResult
+	result.setError(err)
+	notifyImport(ctx, awsCfg, progress, n, result)
+}
+
+// send delivers event to every destination, returning the errors of those
+// that failed.
+func (n notifier) send(ctx context.Context, awsCfg aws.Config, event importEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if n.SNSTopic != "" {
+		errs = append(errs, publishSNS(ctx, awsCfg, n.SNSTopic, event, body))
+	}
+	if n.Webhook != "" {
+		errs = append(errs, postJSON(ctx, n.Webhook, body))
+	}
+	if n.EventBus != "" {
+		errs = append(errs, putEvent(ctx, awsCfg, n.EventBus, event, body))
+	}
+	if n.Slack != "" {
+		message, err := json.Marshal(map[string]string{"text": slackText(event)})
+		if err != nil {
+			return err
+		}
+		errs = append(errs, postJSON(ctx, n.Slack, message))
+	}
+	return errors.Join(errs...)
+}
+
+// slackText formats event as a Slack message: the outcome and expiry on the
+// first line, then one line per region with its ARN or error.
+func slackText(event importEvent) string {
+	domain := event.DomainName
+	if domain == "" {
+		domain = event.Name
+	}
+	if domain == "" {
+		domain = "certificate"
+	}
+
+	var b strings.Builder
+	switch event.Event {
+	case eventFailed:
+		fmt.Fprintf(&b, ":x: Import of *%s* failed", domain)
+	case eventReimported:
+		fmt.Fprintf(&b, ":arrows_counterclockwise: Re-imported *%s*", domain)
+	default:
+		fmt.Fprintf(&b, ":white_check_mark: Imported *%s*", domain)
+	}
+	if event.NotAfter != nil {
+		fmt.Fprintf(&b, ", expires %s (%d days)", event.NotAfter.Format("2006-01-02"), daysUntil(*event.NotAfter))
+	}
+	if event.Error != "" {
+		fmt.Fprintf(&b, "\n%s", event.Error)
+	}
+	for _, r := range event.Regions {
+		if r.Error != "" {
+			fmt.Fprintf(&b, "\n• %s: %s", r.Region, r.Error)
+		} else {
+			fmt.Fprintf(&b, "\n• %s: `%s`", r.Region, r.CertificateArn)
+		}
+	}
+	return b.String()
+}
+
+// publishSNS publishes body to topicArn in the topic's region. The event type
+// is also set as a message attribute for subscription filter policies.
+func publishSNS(ctx context.Context, awsCfg aws.Config, topicArn string, event importEvent, body []byte) error {
+	region, err := arnRegion(topicArn)
+	if err != nil {
+		return err
+	}
+	awsCfg = awsCfg.Copy()
+	awsCfg.Region = region
+
+	subject := "aws-certs: " + event.Event
+	if event.DomainName != "" {
+		subject += " " + event.DomainName
+	}
+	// SNS limits subjects to 100 characters
+	if len(subject) > 100 {
+		subject = subject[:100]
+	}
+
+	_, err = sns.NewFromConfig(awsCfg).Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Subject:  aws.String(subject),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]snstypes.MessageAttributeValue{
+			"event": {DataType: aws.String("String"), StringValue: aws.String(event.Event)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topicArn, err)
+	}
+	progress.Debugf("Published %s event to %s\n", event.Event, topicArn)
+	return nil
+}
+
+// putEvent puts event on an EventBridge bus, in the bus's region when given
+// as an ARN and in the import region otherwise. The imported certificates
+// are the event's resources.
+func putEvent(ctx context.Context, awsCfg aws.Config, bus string, event importEvent, detail []byte) error {
+	if strings.HasPrefix(bus, "arn:") {
+		region, err := arnRegion(bus)
+		if err != nil {
+			return err
+		}
+		awsCfg = awsCfg.Copy()
+		awsCfg.Region = region
+	}
+
+	var resources []string
+	for _, r := range event.Regions {
+		if r.CertificateArn != "" {
+			resources = append(resources, r.CertificateArn)
+		}
+	}
+
+	out, err := eventbridge.NewFromConfig(awsCfg).PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []ebtypes.PutEventsRequestEntry{{
+			EventBusName: aws.String(bus),
+			Source:       aws.String(eventSource),
+			DetailType:   aws.String(eventDetailTypes[event.Event]),
+			Detail:       aws.String(string(detail)),
+			Resources:    resources,
+			Time:         aws.Time(event.Time),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put event on %s: %w", bus, err)
+	}
+	// PutEvents reports rejected entries in the response rather than as an error
+	if out.FailedEntryCount > 0 && len(out.Entries) > 0 {
+		return fmt.Errorf("event rejected by %s: %s", bus, aws.ToString(out.Entries[0].ErrorMessage))
+	}
+	progress.Debugf("Put %s event on %s\n", eventDetailTypes[event.Event], bus)
+	return nil
+}
+
+// postJSON posts body to endpoint and expects a 2xx response. Only the host
+// is logged, since webhook URLs often embed a secret token.
+func postJSON(ctx context.Context, endpoint string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "aws-certs/"+toolVersion())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The url.Error would repeat the full URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to post to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	progress.Debugf("Posted event to %s\n", req.URL.Host)
+	return nil
+}
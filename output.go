@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/internal/inventory"
+)
+
+// outputTemplate is the parsed -output-template, which replaces the JSON
+// output of every command.
+var outputTemplate *template.Template
+
+// templateFuncs are available to -output-template besides the built-in
+// functions.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+	"date": func(layout string, v interface{}) (string, error) {
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(layout), nil
+		case *time.Time:
+			if t == nil {
+				return "", nil
+			}
+			return t.Format(layout), nil
+		}
+		return "", fmt.Errorf("date: %T is not a time", v)
+	},
+}
+
+// parseOutputTemplate parses the value of -output-template.
+func parseOutputTemplate(text string) error {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+// applyOutputTemplate switches a command to -output json when
+// -output-template is given, since the template renders the JSON result.
+func applyOutputTemplate(fs *flag.FlagSet) error {
+	if outputTemplate == nil {
+		return nil
+	}
+	output := fs.Lookup("output")
+	if output == nil {
+		return fmt.Errorf("-output-template is not supported by %s, which has no -output", fs.Name())
+	}
+	if value := output.Value.String(); value != output.DefValue && value != "json" {
+		return errors.New("-output-template cannot be combined with -output " + value)
+	}
+	return output.Value.Set("json")
+}
+
+// printJSON writes v to stdout as indented JSON, or renders it with
+// -output-template.
+func printJSON(v interface{}) error {
+	if outputTemplate != nil {
+		return printTemplate(v)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON output: %w", err)
+	}
+	return nil
+}
+
+// printTemplate renders -output-template with v, once for each element
+// when v is a list, ending each rendering with a newline.
+func printTemplate(v interface{}) error {
+	items := []interface{}{v}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		items = make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+	}
+	for _, item := range items {
+		var buf bytes.Buffer
+		if err := outputTemplate.Execute(&buf, item); err != nil {
+			return fmt.Errorf("failed to render -output-template: %w", err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// daysUntil returns the number of whole days from now until t, negative once
+// t has passed.
+func daysUntil(t time.Time) int {
+	return inventory.DaysUntil(t, time.Now())
+}
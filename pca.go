@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	pcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// pcaIssueTimeout bounds the wait for ACM Private CA to issue a certificate,
+// which usually takes a few seconds.
+const pcaIssueTimeout = 5 * time.Minute
+
+type IssuePCAConfig struct {
+	Import       CertImportConfig
+	CAArn        string
+	Domains      []string
+	KeyType      string
+	ValidityDays int
+	TemplateArn  string
+	OutDir       string
+}
+
+func runIssuePCA(ctx context.Context, args []string) error {
+	var cfg IssuePCAConfig
+	var domains, regions, tagString, tagsFile, publishArn string
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("issue-pca", flag.ExitOnError)
+	fs.StringVar(&cfg.CAArn, "ca-arn", "", "ARN of the ACM Private CA to issue the certificate - REQUIRED")
+	fs.StringVar(&domains, "domains", "", "Names to include in the certificate, e.g. 'internal.example.com,api.internal.example.com' - REQUIRED")
+	fs.StringVar(&cfg.KeyType, "key-type", "ec256", "Certificate key type: "+keyTypes)
+	fs.IntVar(&cfg.ValidityDays, "validity-days", 365, "Validity of the certificate in days")
+	fs.StringVar(&cfg.TemplateArn, "template-arn", "", "ACM Private CA template to issue with (default: the CA's end-entity template)")
+	fs.StringVar(&cfg.OutDir, "out-dir", "", "Also write cert.pem, chain.pem, fullchain.pem and privkey.pem to this directory")
+	fs.StringVar(&cfg.Import.CertificateArn, "certificate-arn", "", "ARN of an existing imported certificate to replace in-place - OPTIONAL")
+	fs.StringVar(&regions, "regions", "", "Import into several regions concurrently, e.g. 'us-east-1,eu-west-1'")
+	fs.BoolVar(&cfg.Import.AllRegions, "all-regions", false, "Import into every region enabled for the account")
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.StringVar(&tagsFile, "tags-file", "", "JSON or YAML file with a map of tag keys to values")
+	fs.BoolVar(&cfg.Import.AutoTags, "auto-tags", false, "Add tags describing the certificate and the import (Domain, Issuer, NotAfter, ImportedBy, ...)")
+	fs.BoolVar(&cfg.Import.Upsert, "upsert", false, "Re-import over an existing imported certificate with the same domain and SANs")
+	fs.BoolVar(&cfg.Import.Wait, "wait", false, "After importing, wait until ACM reports the certificate as ISSUED and verify it matches")
+	fs.StringVar(&publishArn, "publish-arn", "", "After importing, write the ARN to 'ssm:/path/to/param' or 'secretsmanager:name' (comma-separated for several)")
+	fs.StringVar(&cfg.Import.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Import.Region, &cfg.Import.Profile, &cfg.Import.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s issue-pca -ca-arn <arn> -domains NAMES [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generate a key and CSR locally, have ACM Private CA issue the certificate and import it\n")
+		fmt.Fprintf(os.Stderr, "into ACM. The private key never leaves this machine except to be imported.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s issue-pca -ca-arn arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/abc -domains internal.example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s issue-pca -ca-arn arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/abc -domains api.internal -validity-days 90 -out-dir ./tls -upsert\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Domains = cliutil.SplitList(domains)
+	if cfg.CAArn == "" || len(cfg.Domains) == 0 {
+		fail(errors.New("-ca-arn and -domains are required"))
+	}
+	if _, err := arnRegion(cfg.CAArn); err != nil {
+		fail(fmt.Errorf("-ca-arn: %w", err))
+	}
+	if err := checkKeyType(cfg.KeyType); err != nil {
+		fail(err)
+	}
+	if cfg.ValidityDays <= 0 {
+		fail(errors.New("-validity-days must be positive"))
+	}
+
+	cfg.Import.Regions = cliutil.SplitList(regions)
+	var err error
+	cfg.Import.PublishArn, err = parsePublishTargets(publishArn)
+	if err != nil {
+		fail(err)
+	}
+
+	var fileTags, inlineTags map[string]string
+	if tagsFile != "" {
+		fileTags, err = loadTagsFile(tagsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Import.Tags = mergeTags(fileTags, inlineTags, tagList)
+
+	if err := cfg.Import.validateOptions(); err != nil {
+		fail(err)
+	}
+
+	return issuePCA(ctx, cfg)
+}
+
+func issuePCA(ctx context.Context, cfg IssuePCAConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Import.Timeout)
+	defer cancel()
+
+	awsCfg, regions, err := importTargets(ctx, &cfg.Import)
+	if err != nil {
+		return err
+	}
+
+	// The CA can live in a different region than the import target
+	caRegion, _ := arnRegion(cfg.CAArn)
+	pcaCfg := awsCfg.Copy()
+	pcaCfg.Region = caRegion
+	client := acmpca.NewFromConfig(pcaCfg)
+
+	ca, err := client.DescribeCertificateAuthority(ctx, &acmpca.DescribeCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(cfg.CAArn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe certificate authority: %w", err)
+	}
+	authority := ca.CertificateAuthority
+	if authority.Status != pcatypes.CertificateAuthorityStatusActive {
+		return fmt.Errorf("certificate authority %s is %s, not ACTIVE", cfg.CAArn, authority.Status)
+	}
+
+	certKey, err := generateKey(cfg.KeyType)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: strings.TrimPrefix(cfg.Domains[0], "*.")},
+		DNSNames: cfg.Domains,
+	}, certKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	input := &acmpca.IssueCertificateInput{
+		CertificateAuthorityArn: aws.String(cfg.CAArn),
+		Csr:                     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+		// The CA's signing algorithm matches its own key type
+		SigningAlgorithm: authority.CertificateAuthorityConfiguration.SigningAlgorithm,
+		Validity: &pcatypes.Validity{
+			Type:  pcatypes.ValidityPeriodTypeDays,
+			Value: aws.Int64(int64(cfg.ValidityDays)),
+		},
+	}
+	if cfg.TemplateArn != "" {
+		input.TemplateArn = aws.String(cfg.TemplateArn)
+	}
+	issued, err := client.IssueCertificate(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to issue certificate: %w", err)
+	}
+	progress.Debugf("Issued %s\n", aws.ToString(issued.CertificateArn))
+
+	get := &acmpca.GetCertificateInput{
+		CertificateAuthorityArn: aws.String(cfg.CAArn),
+		CertificateArn:          issued.CertificateArn,
+	}
+	out, err := acmpca.NewCertificateIssuedWaiter(client).WaitForOutput(ctx, get, pcaIssueTimeout)
+	if err != nil {
+		return fmt.Errorf("certificate %s was not issued: %w", aws.ToString(issued.CertificateArn), err)
+	}
+
+	material := &certimport.Material{Key: certKey}
+	material.Leaf, err = parseIssued(aws.ToString(out.Certificate))
+	if err != nil {
+		return err
+	}
+	if chain := aws.ToString(out.CertificateChain); chain != "" {
+		material.Chain, err = certimport.ParseCertificates([]byte(chain), "chain")
+		if err != nil {
+			return err
+		}
+	}
+	progress.Printf("✓ Certificate issued by %s (expires %s)\n", cfg.CAArn, material.Leaf.NotAfter.Format("2006-01-02"))
+
+	if cfg.OutDir != "" {
+		if err := writeMaterial(cfg.OutDir, material); err != nil {
+			return err
+		}
+		progress.Printf("✓ Certificate written to %s\n", cfg.OutDir)
+	}
+
+	return importLoaded(ctx, cfg.Import, awsCfg, regions, material)
+}
+
+// parseIssued parses the single certificate returned by a CA.
+func parseIssued(data string) (*x509.Certificate, error) {
+	certs, err := certimport.ParseCertificates([]byte(data), "issued certificate")
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) != 1 {
+		return nil, fmt.Errorf("expected one issued certificate, got %d", len(certs))
+	}
+	return certs[0], nil
+}
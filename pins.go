@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// pins is the SPKI pin file written after an import, for mobile apps and
+// other clients that pin the server's public key: the pin of the imported
+// certificate followed by those of the backup keys, so that the app can be
+// shipped with the next key before the certificate is rotated to it. The
+// zero value writes nothing.
+type pins struct {
+	File    string
+	Format  string
+	Backups []string
+	MaxAge  time.Duration
+}
+
+// Pin file formats.
+const (
+	pinsAndroid = "android"
+	pinsHPKP    = "hpkp"
+)
+
+// addPinFlags registers -pins-file, -pins-format, -backup-pin and
+// -pins-max-age.
+func addPinFlags(fs *flag.FlagSet, p *pins) {
+	fs.StringVar(&p.File, "pins-file", "", "After importing, write the SPKI pins (base64 SHA-256 of the public key) of the certificate and of the -backup-pin keys to this file")
+	fs.StringVar(&p.Format, "pins-format", pinsAndroid, "Format of -pins-file: android (network_security_config.xml) or hpkp (Public-Key-Pins header)")
+	fs.Func("backup-pin", "File with a backup key to pin next to the certificate's: a public key, CSR, certificate or unencrypted private key (may be repeated)", func(value string) error {
+		p.Backups = append(p.Backups, value)
+		return nil
+	})
+	fs.DurationVar(&p.MaxAge, "pins-max-age", 60*24*time.Hour, "max-age of the hpkp pins")
+}
+
+// validatePins checks the pin flags of an import.
+func (cfg *CertImportConfig) validatePins() error {
+	p := cfg.Pins
+	if p.File == "" {
+		if len(p.Backups) > 0 {
+			return errors.New("-backup-pin requires -pins-file")
+		}
+		return nil
+	}
+	if p.Format != pinsAndroid && p.Format != pinsHPKP {
+		return errors.New("-pins-format must be android or hpkp")
+	}
+	if cfg.Dir != "" || cfg.TargetsFile != "" || cfg.IAM {
+		return errors.New("-pins-file refers to a single ACM certificate and cannot be combined with -dir, -targets or -iam")
+	}
+	if p.MaxAge <= 0 {
+		return errors.New("-pins-max-age must be positive")
+	}
+	return nil
+}
+
+// write writes the pins of leaf and the backup keys to the pin file. Pins
+// without a backup pin lock clients out as soon as the key changes, so a
+// missing one is warned about.
+func (p pins) write(leaf *x509.Certificate) error {
+	if p.File == "" {
+		return nil
+	}
+	pin, err := certimport.SPKIPin(leaf.PublicKey)
+	if err != nil {
+		return err
+	}
+	all := []string{pin}
+	for _, path := range p.Backups {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read backup pin: %w", err)
+		}
+		pub, err := certimport.ParsePublicKey(data)
+		if err != nil {
+			return fmt.Errorf("backup pin %s: %w", path, err)
+		}
+		backup, err := certimport.SPKIPin(pub)
+		if err != nil {
+			return fmt.Errorf("backup pin %s: %w", path, err)
+		}
+		if slices.Contains(all, backup) {
+			progress.Warnf("Backup pin %s is the key of the certificate or of another backup pin, ignoring it", path)
+			continue
+		}
+		all = append(all, backup)
+	}
+	if len(all) == 1 {
+		progress.Warnf("No backup pin: clients pinning %s cannot move to a new key without an update, see -backup-pin", pin)
+	}
+
+	var content string
+	if p.Format == pinsHPKP {
+		content = hpkpPins(leaf, all, p.MaxAge)
+	} else {
+		content = androidPins(leaf, all)
+	}
+	if err := os.WriteFile(p.File, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write pins: %w", err)
+	}
+	progress.Printf("✓ SPKI pin (SHA-256): %s\n", pin)
+	progress.Printf("✓ %d pin(s) written to %s\n", len(all), p.File)
+	return nil
+}
+
+// pinDomain is a name pinned by the pin file.
+type pinDomain struct {
+	Name              string
+	IncludeSubdomains bool
+}
+
+// pinDomains returns the names the certificate is valid for. A wildcard
+// name pins the subdomains of its parent.
+func pinDomains(leaf *x509.Certificate) []pinDomain {
+	names := leaf.DNSNames
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = []string{leaf.Subject.CommonName}
+	}
+	var domains []pinDomain
+	for _, name := range names {
+		name = strings.ToLower(name)
+		parent, wildcard := strings.CutPrefix(name, "*.")
+		i := slices.IndexFunc(domains, func(d pinDomain) bool { return d.Name == parent })
+		switch {
+		case i < 0:
+			domains = append(domains, pinDomain{Name: parent, IncludeSubdomains: wildcard})
+		case wildcard:
+			domains[i].IncludeSubdomains = true
+		}
+	}
+	return domains
+}
+
+// androidPins returns an Android network security configuration pinning
+// the certificate's names to pins until the certificate expires, after
+// which Android stops enforcing them rather than locking clients out.
+func androidPins(leaf *x509.Certificate, pins []string) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n")
+	fmt.Fprintf(&b, "<!-- Generated by aws-certs for %s (SHA-256 %s), merge into res/xml/network_security_config.xml -->\n",
+		xmlEscape(leaf.Subject.CommonName), certimport.Fingerprint(leaf))
+	b.WriteString("<network-security-config>\n    <domain-config>\n")
+	for _, d := range pinDomains(leaf) {
+		fmt.Fprintf(&b, "        <domain includeSubdomains=\"%t\">%s</domain>\n", d.IncludeSubdomains, xmlEscape(d.Name))
+	}
+	fmt.Fprintf(&b, "        <pin-set expiration=\"%s\">\n", leaf.NotAfter.UTC().Format("2006-01-02"))
+	for _, pin := range pins {
+		fmt.Fprintf(&b, "            <pin digest=\"SHA-256\">%s</pin>\n", pin)
+	}
+	b.WriteString("        </pin-set>\n    </domain-config>\n</network-security-config>\n")
+	return b.String()
+}
+
+// hpkpPins returns the Public-Key-Pins header pinning pins for maxAge.
+func hpkpPins(leaf *x509.Certificate, pins []string, maxAge time.Duration) string {
+	directives := make([]string, 0, len(pins)+2)
+	for _, pin := range pins {
+		directives = append(directives, fmt.Sprintf("pin-sha256=%q", pin))
+	}
+	directives = append(directives, fmt.Sprintf("max-age=%d", int64(maxAge.Seconds())))
+	if slices.ContainsFunc(pinDomains(leaf), func(d pinDomain) bool { return d.IncludeSubdomains }) {
+		directives = append(directives, "includeSubDomains")
+	}
+	return "Public-Key-Pins: " + strings.Join(directives, "; ") + "\n"
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
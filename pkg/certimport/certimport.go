@@ -0,0 +1,578 @@
+// Package certimport reads, validates and imports TLS certificates into AWS
+// Certificate Manager. It is the library behind the aws-certs CLI and can be
+// embedded by other Go programs that need the same checks without shelling
+// out.
+//
+// A typical import loads the material, then hands it to an Importer:
+//
+//	material, err := certimport.LoadPEM(certPEM, keyPEM, nil)
+//	...
+//	importer := certimport.New(awsCfg)
+//	result, err := importer.Import(ctx, material, certimport.ImportOptions{})
+package certimport
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// Logger receives human-readable progress messages. Warnf messages carry no
+// trailing newline; Printf and Debugf messages do.
+type Logger interface {
+	Printf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// Errors for conditions a caller may want to override or explain, such as by
+// pointing at the option that allows them. They are wrapped with details, so
+// test for them with errors.Is.
+var (
+	// ErrExpired is returned for certificates that are expired or not yet
+	// valid, unless ImportOptions.AllowExpired is set.
+	ErrExpired = errors.New("certificate is not currently valid")
+	// ErrUnrelatedChain is returned when the chain holds certificates that
+	// did not issue the leaf, unless ImportOptions.FixChain is set.
+	ErrUnrelatedChain = errors.New("certificate chain contains certificates that are not part of the leaf's chain")
+	// ErrAmbiguousMatch is returned by an upsert when more than one imported
+	// certificate could be replaced.
+	ErrAmbiguousMatch = errors.New("several imported certificates match")
+	// ErrEncryptedKey is returned when a private key is encrypted and no
+	// passphrase is available.
+	ErrEncryptedKey = errors.New("private key is encrypted and no passphrase was given")
+	// ErrKeyMismatch is returned when the private key does not belong to
+	// the certificate.
+	ErrKeyMismatch = errors.New("private key does not match certificate")
+	// ErrMissingIssuer is returned when a chain was given but none of its
+	// certificates issued the leaf.
+	ErrMissingIssuer = errors.New("certificate chain does not contain the issuer")
+	// ErrInvalidChain is returned when the chain does not verify, other
+	// than for ending in an untrusted root.
+	ErrInvalidChain = errors.New("certificate chain verification failed")
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+
+// ImportOptions controls how a certificate is prepared and imported.
+type ImportOptions struct {
+	// CertificateArn re-imports over an existing certificate so that its
+	// associations with ELB, CloudFront and other services are kept. It can
+	// only be used with a single region.
+	CertificateArn string
+	// Tags are applied to the imported certificate. ACM does not accept tags
+	// when re-importing, so they are added with AddTagsToCertificate once a
+	// re-import succeeds.
+	Tags map[string]string
+	// FixChain strips the root CA and certificates unrelated to the leaf
+	// from the chain instead of failing or warning.
+	FixChain bool
+	// FetchChain downloads intermediates missing from the chain using the
+	// Authority Information Access extension.
+	FetchChain bool
+	// Regions to import into concurrently. When empty the region of the
+	// Importer's AWS config is used.
+	Regions []string
+	// AllowExpired imports certificates that are expired or not yet valid
+	// instead of refusing them.
+	AllowExpired bool
+	// Force imports the certificate even if an identical one already exists
+	// in ACM. Without it the existing certificate's ARN is returned instead.
+	Force bool
+	// Upsert re-imports over an existing imported certificate covering the
+	// same domain name and SANs instead of creating a duplicate. The match
+	// is made separately in each region.
+	Upsert bool
+	// UpsertTags makes Upsert replace the imported certificate carrying all
+	// of these tags, so that its identity survives changes to its names.
+	// Certificates are only matched by names when none carries the tags.
+	UpsertTags map[string]string
+	// Policy restricts the key algorithms, key sizes and signature
+	// algorithms accepted for the leaf and its chain.
+	Policy KeyPolicy
+	// MustCover lists hostnames the certificate has to be valid for; see
+	// CheckCoverage.
+	MustCover []string
+	// CheckRevocation asks the CA whether the certificate was revoked, via
+	// OCSP or its CRL, and refuses revoked certificates. A status that
+	// cannot be determined only warns.
+	CheckRevocation bool
+	// AllowRevoked imports revoked certificates with a warning instead of
+	// refusing them.
+	AllowRevoked bool
+	// CheckCT warns when the certificate neither embeds SCTs nor is found
+	// in Certificate Transparency logs.
+	CheckCT bool
+	// DebianBlocklist lists known Debian weak keys to refuse in addition to
+	// the keys CheckWeakKey always refuses.
+	DebianBlocklist DebianBlocklist
+}
+
+// Importer imports certificates into ACM using a base AWS configuration.
+type Importer struct {
+	config aws.Config
+	logger Logger
+}
+
+// Option configures an Importer.
+type Option func(*Importer)
+
+// WithLogger sets the logger that receives progress messages.
+func WithLogger(logger Logger) Option {
+	return func(i *Importer) {
+		i.logger = logger
+	}
+}
+
+// New returns an Importer that makes all AWS calls with cfg. The region of
+// cfg is overridden per client when importing into several regions.
+func New(cfg aws.Config, opts ...Option) *Importer {
+	i := &Importer{config: cfg, logger: nopLogger{}}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Prepared is a validated certificate ready to be sent to ACM.
+type Prepared struct {
+	Leaf *x509.Certificate
+	// Chain is the ordered chain that will be sent, without the leaf.
+	Chain []*x509.Certificate
+	Input *acm.ImportCertificateInput
+	// Tags are sent with new imports and added separately after a re-import.
+	Tags []types.Tag
+	// SkipExisting looks for an identical imported certificate in each
+	// region and returns its ARN instead of importing a duplicate.
+	SkipExisting bool
+	// Upsert re-imports over an imported certificate with the same names,
+	// or with UpsertTags, when one exists in the region.
+	Upsert     bool
+	UpsertTags map[string]string
+}
+
+// Clear zeroes the encoded private key once the certificate has been
+// imported everywhere it is going. The Prepared cannot be imported again.
+func (p *Prepared) Clear() {
+	if p != nil && p.Input != nil {
+		clear(p.Input.PrivateKey)
+	}
+}
+
+// Result is the outcome of an import.
+type Result struct {
+	DomainName  string
+	Fingerprint string
+	SANs        []string
+	NotAfter    time.Time
+	// Reimported is set when an existing certificate was replaced in at
+	// least one region.
+	Reimported bool
+	Regions    []RegionResult
+}
+
+// RegionResult is the outcome of importing into a single region.
+type RegionResult struct {
+	Region         string
+	CertificateArn string
+	// Reimported is set when an existing certificate was replaced, either
+	// by ARN or by an upsert match.
+	Reimported bool
+	// Existing is set when an identical certificate was already imported
+	// and CertificateArn refers to it.
+	Existing bool
+	// Err is the error returned by ACM, if the import failed
+	Err error
+}
+
+// Failed returns the number of regions the import failed in.
+func (r *Result) Failed() int {
+	failed := 0
+	for _, region := range r.Regions {
+		if region.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Import validates the material and imports it into every requested region.
+// An error is returned only when validation fails; per-region failures are
+// reported in the Result so that one failing region does not hide the
+// others.
+func (i *Importer) Import(ctx context.Context, m *Material, opts ImportOptions) (*Result, error) {
+	prepared, err := i.Prepare(ctx, m, opts)
+	if err != nil {
+		return nil, err
+	}
+	return i.ImportPrepared(ctx, prepared, opts.Regions), nil
+}
+
+// Prepare checks that the key matches the certificate, orders and verifies
+// the chain and builds the ImportCertificate request. Apart from optional
+// chain fetching, revocation and CT checks it makes no network calls.
+func (i *Importer) Prepare(ctx context.Context, m *Material, opts ImportOptions) (*Prepared, error) {
+	if len(opts.Regions) > 1 && opts.CertificateArn != "" {
+		return nil, fmt.Errorf("a certificate ARN cannot be used when importing into multiple regions")
+	}
+	if opts.Upsert && opts.CertificateArn != "" {
+		return nil, fmt.Errorf("upsert cannot be combined with a certificate ARN")
+	}
+	if err := ValidateTags(opts.Tags); err != nil {
+		return nil, err
+	}
+
+	// ACM accepts expired certificates, but nothing would trust them
+	if err := CheckValidity(m.Leaf, time.Now()); err != nil {
+		if !opts.AllowExpired {
+			return nil, err
+		}
+		i.logger.Warnf("Importing anyway: %v", err)
+	}
+
+	if err := CheckCoverage(m.Leaf, opts.MustCover); err != nil {
+		return nil, err
+	}
+	if len(opts.MustCover) > 0 {
+		i.logger.Printf("✓ Certificate covers %s\n", strings.Join(opts.MustCover, ", "))
+	}
+
+	// Make sure the key belongs to the certificate before calling ACM
+	if err := VerifyKeyMatch(m.Leaf, m.Key); err != nil {
+		return nil, err
+	}
+	i.logger.Printf("✓ Private key matches certificate\n")
+
+	// Compromised keys must never reach ACM, whatever the options
+	if err := CheckWeakKey(m.Leaf.PublicKey, opts.DebianBlocklist); err != nil {
+		return nil, err
+	}
+
+	chain := m.Chain
+
+	// Download any intermediates missing from the chain
+	if opts.FetchChain {
+		i.logger.Printf("Fetching certificate chain via Authority Information Access...\n")
+		fetched, err := FetchChain(ctx, m.Leaf, chain)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range fetched {
+			i.logger.Printf("✓ Fetched intermediate %q\n", cert.Subject.String())
+		}
+		chain = append(append([]*x509.Certificate(nil), chain...), fetched...)
+	}
+
+	if err := opts.Policy.Check(m.Leaf, chain); err != nil {
+		return nil, err
+	}
+
+	prepared := &Prepared{Leaf: m.Leaf}
+	if len(chain) > 0 {
+		var err error
+		prepared.Chain, err = i.prepareChain(m.Leaf, chain, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.CheckRevocation {
+		if err := i.checkRevocation(ctx, m.Leaf, prepared.Chain, opts.AllowRevoked); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.CheckCT {
+		i.checkCT(ctx, m.Leaf)
+	}
+
+	keyData, err := EncodePrivateKey(m.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare import input
+	input := &acm.ImportCertificateInput{
+		Certificate: EncodeCertificates([]*x509.Certificate{m.Leaf}),
+		PrivateKey:  keyData,
+	}
+
+	if len(prepared.Chain) > 0 {
+		input.CertificateChain = EncodeCertificates(prepared.Chain)
+	}
+
+	// Re-import over an existing certificate so its associations are kept
+	if opts.CertificateArn != "" {
+		input.CertificateArn = aws.String(opts.CertificateArn)
+		i.logger.Printf("✓ Re-importing over existing certificate %s\n", opts.CertificateArn)
+	}
+
+	// Add tags if provided. ACM does not accept tags when re-importing,
+	// so they are only sent with new certificates and added afterwards
+	// otherwise.
+	if len(opts.Tags) > 0 {
+		for key, value := range opts.Tags {
+			prepared.Tags = append(prepared.Tags, types.Tag{
+				Key:   aws.String(key),
+				Value: aws.String(value),
+			})
+		}
+		if opts.CertificateArn == "" {
+			input.Tags = prepared.Tags
+		}
+		i.logger.Printf("✓ Tags prepared: %d tags\n", len(prepared.Tags))
+	}
+
+	// Re-imports always go ahead; otherwise identical certificates are reused
+	prepared.SkipExisting = !opts.Force && opts.CertificateArn == ""
+	prepared.Upsert = opts.Upsert
+	prepared.UpsertTags = opts.UpsertTags
+
+	prepared.Input = input
+	return prepared, nil
+}
+
+// prepareChain orders the chain from the leaf's issuer upwards, verifies it
+// and returns the certificates that should be sent to ACM.
+func (i *Importer) prepareChain(leaf *x509.Certificate, chain []*x509.Certificate, opts ImportOptions) ([]*x509.Certificate, error) {
+	ordered := OrderChain(leaf, chain)
+
+	if ordered.LeafIncluded {
+		i.logger.Warnf("Chain file contains the leaf certificate; it has been removed from the chain")
+	}
+	if len(ordered.Unused) > 0 {
+		if !opts.FixChain {
+			return nil, fmt.Errorf("%w: %s", ErrUnrelatedChain, describeCerts(ordered.Unused))
+		}
+		i.logger.Printf("✓ Dropped unrelated chain certificates: %s\n", describeCerts(ordered.Unused))
+	}
+	if len(ordered.Certs) == 0 {
+		return nil, fmt.Errorf("%w of %q", ErrMissingIssuer, leaf.Subject.String())
+	}
+	if ordered.Reordered {
+		i.logger.Printf("✓ Chain certificates reordered from leaf to root\n")
+	}
+
+	if err := VerifyChain(leaf, ordered, opts.AllowExpired); err != nil {
+		if !IsUnknownAuthority(err) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidChain, err)
+		}
+		i.logger.Warnf("Chain does not end in a trusted root CA (expected for private CAs): %v", err)
+	} else {
+		i.logger.Printf("✓ Certificate chain verified\n")
+	}
+
+	certs := ordered.Certs
+	if ordered.Root != nil {
+		if opts.FixChain {
+			certs = ordered.Intermediates()
+			i.logger.Printf("✓ Root CA %q removed from chain\n", ordered.Root.Subject.String())
+		} else {
+			i.logger.Warnf("Chain includes root CA %q; ACM recommends excluding it", ordered.Root.Subject.String())
+		}
+	}
+	return certs, nil
+}
+
+// ImportPrepared imports a prepared certificate into every region
+// concurrently, or into the Importer's configured region when regions is
+// empty.
+func (i *Importer) ImportPrepared(ctx context.Context, p *Prepared, regions []string) *Result {
+	if len(regions) == 0 {
+		regions = []string{i.config.Region}
+	}
+	if len(regions) == 1 {
+		i.logger.Printf("Importing certificate to ACM (region: %s)...\n", regions[0])
+	} else {
+		i.logger.Printf("Importing certificate to ACM in %d regions...\n", len(regions))
+	}
+	return i.eachRegion(ctx, p, regions, i.importToRegion)
+}
+
+// Plan looks up what ImportPrepared would do in every region without
+// importing anything. A region's result is Existing when an identical
+// certificate would be reused, Reimported when the certificate named by
+// CertificateArn would be replaced, and has no ARN when a new certificate
+// would be imported.
+func (i *Importer) Plan(ctx context.Context, p *Prepared, regions []string) *Result {
+	if len(regions) == 0 {
+		regions = []string{i.config.Region}
+	}
+	return i.eachRegion(ctx, p, regions, func(ctx context.Context, client *acm.Client, region string, p *Prepared) RegionResult {
+		result := RegionResult{Region: region}
+		result.CertificateArn, result.Existing, result.Err = i.resolveTarget(ctx, client, region, p)
+		result.Reimported = result.CertificateArn != "" && !result.Existing
+		return result
+	})
+}
+
+type regionFunc func(ctx context.Context, client *acm.Client, region string, p *Prepared) RegionResult
+
+// eachRegion runs fn for every region concurrently with a client for that
+// region and collects the results.
+func (i *Importer) eachRegion(ctx context.Context, p *Prepared, regions []string, fn regionFunc) *Result {
+	result := &Result{
+		DomainName:  p.Leaf.Subject.CommonName,
+		Fingerprint: Fingerprint(p.Leaf),
+		SANs:        p.Leaf.DNSNames,
+		NotAfter:    p.Leaf.NotAfter,
+	}
+
+	result.Regions = make([]RegionResult, len(regions))
+	var wg sync.WaitGroup
+	for idx, region := range regions {
+		wg.Add(1)
+		go func(idx int, region string) {
+			defer wg.Done()
+			client := acm.NewFromConfig(i.config, func(o *acm.Options) {
+				if region != "" {
+					o.Region = region
+				}
+			})
+			result.Regions[idx] = fn(ctx, client, region, p)
+		}(idx, region)
+	}
+	wg.Wait()
+
+	for _, r := range result.Regions {
+		result.Reimported = result.Reimported || r.Reimported
+	}
+
+	return result
+}
+
+// resolveTarget decides what importing p into a region does. It returns the
+// ARN of an identical certificate to reuse, with existing set, the ARN of a
+// certificate to re-import over, or an empty ARN for a new certificate.
+func (i *Importer) resolveTarget(ctx context.Context, client *acm.Client, region string, p *Prepared) (arn string, existing bool, err error) {
+	if p.Input.CertificateArn != nil {
+		return aws.ToString(p.Input.CertificateArn), false, nil
+	}
+	if !p.SkipExisting && !p.Upsert {
+		return "", false, nil
+	}
+
+	summaries, err := importedCertificates(ctx, client)
+	if err != nil {
+		return "", false, err
+	}
+
+	if p.SkipExisting {
+		arn, err := findIdentical(ctx, client, summaries, p.Leaf)
+		if err != nil {
+			return "", false, err
+		}
+		if arn != "" {
+			i.logger.Debugf("Identical certificate already imported in %s: %s\n", region, arn)
+			return arn, true, nil
+		}
+	}
+
+	if !p.Upsert {
+		return "", false, nil
+	}
+
+	if len(p.UpsertTags) > 0 {
+		arns, err := findTagged(ctx, client, summaries, p.UpsertTags)
+		if err != nil {
+			return "", false, err
+		}
+		if len(arns) > 1 {
+			return "", false, fmt.Errorf("%w in %s by tags, cannot choose one to replace: %s", ErrAmbiguousMatch, region, strings.Join(arns, ", "))
+		}
+		if len(arns) == 1 {
+			return arns[0], false, nil
+		}
+	}
+
+	arns := findSameNames(summaries, p.Leaf)
+	if len(arns) > 1 {
+		return "", false, fmt.Errorf("%w in %s by names, cannot choose one to replace: %s", ErrAmbiguousMatch, region, strings.Join(arns, ", "))
+	}
+	if len(arns) == 1 {
+		return arns[0], false, nil
+	}
+	return "", false, nil
+}
+
+func (i *Importer) importToRegion(ctx context.Context, client *acm.Client, region string, p *Prepared) RegionResult {
+	result := RegionResult{Region: region}
+	i.logger.Debugf("Importing into %s\n", region)
+
+	arn, existing, err := i.resolveTarget(ctx, client, region, p)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if existing {
+		result.CertificateArn = arn
+		result.Existing = true
+		return result
+	}
+
+	// Each region gets its own copy of the request
+	input := *p.Input
+	if arn != "" && input.CertificateArn == nil {
+		i.logger.Printf("✓ Replacing existing certificate %s\n", arn)
+		input.CertificateArn = aws.String(arn)
+		input.Tags = nil
+	}
+
+	output, err := client.ImportCertificate(ctx, &input)
+	if err != nil {
+		result.Err = RedactError(err, input.PrivateKey)
+		return result
+	}
+	result.CertificateArn = aws.ToString(output.CertificateArn)
+	result.Reimported = input.CertificateArn != nil
+
+	// Re-imports keep the old tags, so update them to describe the new
+	// certificate
+	if result.Reimported && len(p.Tags) > 0 {
+		_, err := client.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+			CertificateArn: output.CertificateArn,
+			Tags:           p.Tags,
+		})
+		if err != nil {
+			result.Err = fmt.Errorf("certificate re-imported as %s but failed to update its tags: %w", result.CertificateArn, err)
+			return result
+		}
+		i.logger.Printf("✓ Tags updated on %s: %d tags\n", result.CertificateArn, len(p.Tags))
+	}
+	return result
+}
+
+// checkRevocation refuses a revoked leaf unless allowRevoked is set. The
+// issuer is taken from the prepared chain.
+func (i *Importer) checkRevocation(ctx context.Context, leaf *x509.Certificate, chain []*x509.Certificate, allowRevoked bool) error {
+	if len(chain) == 0 {
+		i.logger.Warnf("Cannot check revocation without the issuing certificate; add the chain or fetch it")
+		return nil
+	}
+	status, err := CheckRevocation(ctx, leaf, chain[0])
+	if err != nil {
+		i.logger.Warnf("Could not check revocation: %v", err)
+		return nil
+	}
+	if !status.Revoked {
+		i.logger.Printf("✓ Certificate is %s\n", status)
+		return nil
+	}
+	if !allowRevoked {
+		return fmt.Errorf("%w: %s", ErrRevoked, status)
+	}
+	i.logger.Warnf("Importing anyway: certificate is %s", status)
+	return nil
+}
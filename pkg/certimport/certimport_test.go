@@ -0,0 +1,182 @@
+package certimport
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// testCert is a certificate together with its private key.
+type testCert struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// issue creates an EC P-256 certificate for name valid between notBefore and
+// notAfter. It is self-signed when parent is nil.
+func issue(t *testing.T, name string, parent *testCert, isCA bool, notBefore, notAfter time.Time) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	} else {
+		template.DNSNames = []string{name}
+	}
+
+	issuer, signer := template, crypto.Signer(key)
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, key.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// testPKI is a root CA, an intermediate and a currently valid leaf.
+type testPKI struct {
+	root, intermediate, leaf *testCert
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	now := time.Now()
+	root := issue(t, "Test Root", nil, true, now.AddDate(-5, 0, 0), now.AddDate(10, 0, 0))
+	intermediate := issue(t, "Test Intermediate", root, true, now.AddDate(-2, 0, 0), now.AddDate(5, 0, 0))
+	leaf := issue(t, "www.example.com", intermediate, false, now.Add(-time.Hour), now.AddDate(0, 3, 0))
+	return testPKI{root: root, intermediate: intermediate, leaf: leaf}
+}
+
+func TestPrepareValidity(t *testing.T) {
+	pki := newTestPKI(t)
+	now := time.Now()
+	expired := issue(t, "old.example.com", pki.intermediate, false, now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1))
+	future := issue(t, "new.example.com", pki.intermediate, false, now.AddDate(0, 0, 1), now.AddDate(1, 0, 0))
+
+	tests := []struct {
+		name    string
+		leaf    *testCert
+		chain   []*x509.Certificate
+		opts    ImportOptions
+		wantErr error
+	}{
+		{name: "valid", leaf: pki.leaf, chain: []*x509.Certificate{pki.intermediate.cert}},
+		{name: "expired", leaf: expired, wantErr: ErrExpired},
+		{name: "not yet valid", leaf: future, wantErr: ErrExpired},
+		{name: "expired allowed", leaf: expired, opts: ImportOptions{AllowExpired: true}},
+		{name: "expired allowed with chain", leaf: expired, chain: []*x509.Certificate{pki.intermediate.cert, pki.root.cert}, opts: ImportOptions{AllowExpired: true}},
+		{name: "not yet valid allowed with chain", leaf: future, chain: []*x509.Certificate{pki.intermediate.cert, pki.root.cert}, opts: ImportOptions{AllowExpired: true}},
+	}
+
+	importer := New(aws.Config{Region: "us-east-1"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			material := &Material{Leaf: tt.leaf.cert, Key: tt.leaf.key, Chain: tt.chain}
+			prepared, err := importer.Prepare(context.Background(), material, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Prepare() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			if prepared.Input == nil || len(prepared.Input.Certificate) == 0 {
+				t.Fatal("Prepare() returned no ImportCertificate input")
+			}
+		})
+	}
+}
+
+func TestPrepareChain(t *testing.T) {
+	pki := newTestPKI(t)
+	other := issue(t, "Other CA", nil, true, time.Now().Add(-time.Hour), time.Now().AddDate(1, 0, 0))
+
+	tests := []struct {
+		name      string
+		chain     []*x509.Certificate
+		fixChain  bool
+		wantChain []*x509.Certificate
+		wantErr   error
+	}{
+		{name: "intermediate", chain: []*x509.Certificate{pki.intermediate.cert}, wantChain: []*x509.Certificate{pki.intermediate.cert}},
+		{name: "root kept", chain: []*x509.Certificate{pki.root.cert, pki.intermediate.cert}, wantChain: []*x509.Certificate{pki.intermediate.cert, pki.root.cert}},
+		{name: "root stripped", chain: []*x509.Certificate{pki.intermediate.cert, pki.root.cert}, fixChain: true, wantChain: []*x509.Certificate{pki.intermediate.cert}},
+		{name: "unrelated", chain: []*x509.Certificate{pki.intermediate.cert, other.cert}, wantErr: ErrUnrelatedChain},
+		{name: "unrelated dropped", chain: []*x509.Certificate{other.cert, pki.intermediate.cert}, fixChain: true, wantChain: []*x509.Certificate{pki.intermediate.cert}},
+		{name: "issuer missing", chain: []*x509.Certificate{other.cert}, fixChain: true, wantErr: ErrMissingIssuer},
+	}
+
+	importer := New(aws.Config{Region: "us-east-1"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			material := &Material{Leaf: pki.leaf.cert, Key: pki.leaf.key, Chain: tt.chain}
+			prepared, err := importer.Prepare(context.Background(), material, ImportOptions{FixChain: tt.fixChain})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Prepare() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			assertCerts(t, prepared.Chain, tt.wantChain)
+		})
+	}
+}
+
+func TestPrepareKeyMismatch(t *testing.T) {
+	pki := newTestPKI(t)
+	material := &Material{Leaf: pki.leaf.cert, Key: pki.intermediate.key}
+	if _, err := New(aws.Config{}).Prepare(context.Background(), material, ImportOptions{}); err == nil {
+		t.Fatal("Prepare() accepted a key that does not match the certificate")
+	}
+}
+
+func assertCerts(t *testing.T, got, want []*x509.Certificate) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d certificates (%s), want %d (%s)", len(got), describeCerts(got), len(want), describeCerts(want))
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Fatalf("certificate %d is %q, want %q", i, got[i].Subject, want[i].Subject)
+		}
+	}
+}
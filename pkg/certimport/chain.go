@@ -0,0 +1,158 @@
+package certimport
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OrderedChain is the result of arranging a certificate chain so that each
+// certificate is followed by its issuer, starting from the leaf's issuer.
+type OrderedChain struct {
+	// Certs holds the intermediates in issuing order, followed by the root
+	// if one was supplied.
+	Certs []*x509.Certificate
+	// Root is the self-signed root CA found in the chain, if any.
+	Root *x509.Certificate
+	// Reordered is set when the supplied order differed from issuing order.
+	Reordered bool
+	// Unused holds certificates that are not part of the leaf's chain.
+	Unused []*x509.Certificate
+	// LeafIncluded is set when the chain file also contained the leaf.
+	LeafIncluded bool
+}
+
+// Intermediates returns the ordered chain without the root CA.
+func (c OrderedChain) Intermediates() []*x509.Certificate {
+	if c.Root == nil {
+		return c.Certs
+	}
+	return c.Certs[:len(c.Certs)-1]
+}
+
+// OrderChain walks from leaf up through chain, picking at each step the
+// certificate that issued the previous one.
+func OrderChain(leaf *x509.Certificate, chain []*x509.Certificate) OrderedChain {
+	var result OrderedChain
+	var remaining []*x509.Certificate
+	for _, cert := range chain {
+		if cert.Equal(leaf) {
+			result.LeafIncluded = true
+			continue
+		}
+		remaining = append(remaining, cert)
+	}
+	supplied := append([]*x509.Certificate(nil), remaining...)
+
+	current := leaf
+	for !isSelfSigned(current) {
+		idx := -1
+		for i, cert := range remaining {
+			if issuedBy(current, cert) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			break
+		}
+
+		current = remaining[idx]
+		remaining = append(remaining[:idx], remaining[idx+1:]...)
+		result.Certs = append(result.Certs, current)
+		if isSelfSigned(current) {
+			result.Root = current
+		}
+	}
+	result.Unused = remaining
+
+	// Compare against the supplied order, ignoring certificates we dropped
+	j := 0
+	for _, cert := range supplied {
+		if containsCert(result.Unused, cert) {
+			continue
+		}
+		if j >= len(result.Certs) || !cert.Equal(result.Certs[j]) {
+			result.Reordered = true
+			break
+		}
+		j++
+	}
+
+	return result
+}
+
+// VerifyChain verifies leaf against the system roots plus any root CA
+// present in the chain, using the chain's intermediates. With allowExpired
+// an expired or not yet valid leaf is verified as of the edge of its own
+// validity period instead of now.
+func VerifyChain(leaf *x509.Certificate, chain OrderedChain, allowExpired bool) error {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if chain.Root != nil {
+		roots.AddCert(chain.Root)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain.Intermediates() {
+		intermediates.AddCert(cert)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if allowExpired {
+		now := time.Now()
+		switch {
+		case now.After(leaf.NotAfter):
+			opts.CurrentTime = leaf.NotAfter
+		case now.Before(leaf.NotBefore):
+			opts.CurrentTime = leaf.NotBefore
+		}
+	}
+
+	_, err = leaf.Verify(opts)
+	return err
+}
+
+// IsUnknownAuthority reports whether err only indicates that the chain does
+// not end in a trusted root, as is normal for private CAs.
+func IsUnknownAuthority(err error) bool {
+	var unknown x509.UnknownAuthorityError
+	return errors.As(err, &unknown)
+}
+
+func issuedBy(child, parent *x509.Certificate) bool {
+	return bytes.Equal(child.RawIssuer, parent.RawSubject) && child.CheckSignatureFrom(parent) == nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeCerts returns a short list of certificate subjects for messages.
+func describeCerts(certs []*x509.Certificate) string {
+	var buf bytes.Buffer
+	for i, cert := range certs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%q", cert.Subject.String())
+	}
+	return buf.String()
+}
@@ -0,0 +1,85 @@
+package certimport
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestOrderChain(t *testing.T) {
+	pki := newTestPKI(t)
+	other := issue(t, "Other CA", nil, true, time.Now().Add(-time.Hour), time.Now().AddDate(1, 0, 0)).cert
+	root, intermediate, leaf := pki.root.cert, pki.intermediate.cert, pki.leaf.cert
+
+	tests := []struct {
+		name             string
+		chain            []*x509.Certificate
+		wantCerts        []*x509.Certificate
+		wantRoot         *x509.Certificate
+		wantUnused       []*x509.Certificate
+		wantReordered    bool
+		wantLeafIncluded bool
+	}{
+		{
+			name:      "in order",
+			chain:     []*x509.Certificate{intermediate, root},
+			wantCerts: []*x509.Certificate{intermediate, root},
+			wantRoot:  root,
+		},
+		{
+			name:          "reversed",
+			chain:         []*x509.Certificate{root, intermediate},
+			wantCerts:     []*x509.Certificate{intermediate, root},
+			wantRoot:      root,
+			wantReordered: true,
+		},
+		{
+			name:      "intermediate only",
+			chain:     []*x509.Certificate{intermediate},
+			wantCerts: []*x509.Certificate{intermediate},
+		},
+		{
+			name:             "leaf included",
+			chain:            []*x509.Certificate{leaf, intermediate},
+			wantCerts:        []*x509.Certificate{intermediate},
+			wantLeafIncluded: true,
+		},
+		{
+			name:       "unrelated certificate",
+			chain:      []*x509.Certificate{intermediate, other},
+			wantCerts:  []*x509.Certificate{intermediate},
+			wantUnused: []*x509.Certificate{other},
+		},
+		{
+			name:       "missing issuer",
+			chain:      []*x509.Certificate{root},
+			wantUnused: []*x509.Certificate{root},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := OrderChain(leaf, tt.chain)
+			assertCerts(t, got.Certs, tt.wantCerts)
+			assertCerts(t, got.Unused, tt.wantUnused)
+			if (got.Root == nil) != (tt.wantRoot == nil) || (got.Root != nil && !got.Root.Equal(tt.wantRoot)) {
+				t.Errorf("Root = %v, want %v", got.Root, tt.wantRoot)
+			}
+			if got.Reordered != tt.wantReordered {
+				t.Errorf("Reordered = %t, want %t", got.Reordered, tt.wantReordered)
+			}
+			if got.LeafIncluded != tt.wantLeafIncluded {
+				t.Errorf("LeafIncluded = %t, want %t", got.LeafIncluded, tt.wantLeafIncluded)
+			}
+		})
+	}
+}
+
+func TestOrderedChainIntermediates(t *testing.T) {
+	pki := newTestPKI(t)
+	ordered := OrderChain(pki.leaf.cert, []*x509.Certificate{pki.root.cert, pki.intermediate.cert})
+	assertCerts(t, ordered.Intermediates(), []*x509.Certificate{pki.intermediate.cert})
+}
@@ -0,0 +1,137 @@
+package certimport
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// oidSCTList is the X.509 extension holding the Signed Certificate
+// Timestamps a CA embeds when it logs the precertificate (RFC 6962).
+var oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// minSCTs is the fewest embedded SCTs Chrome and Safari accept for any
+// certificate lifetime.
+const minSCTs = 2
+
+// ctSearchURL looks a certificate up by its SHA-256 fingerprint in crt.sh.
+var ctSearchURL = "https://crt.sh/?output=json&sha256="
+
+var ctClient = &http.Client{Timeout: 30 * time.Second}
+
+// SCT is a Signed Certificate Timestamp: a log's promise to include the
+// certificate.
+type SCT struct {
+	// LogID identifies the log, base64 encoded as in the public log lists
+	LogID     string
+	Timestamp time.Time
+}
+
+// EmbeddedSCTs returns the SCTs embedded in cert. Their signatures are not
+// verified.
+func EmbeddedSCTs(cert *x509.Certificate) ([]SCT, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSCTList) {
+			continue
+		}
+		var list []byte
+		if _, err := asn1.Unmarshal(ext.Value, &list); err != nil {
+			return nil, fmt.Errorf("invalid SCT list: %w", err)
+		}
+		return parseSCTList(list)
+	}
+	return nil, nil
+}
+
+// parseSCTList parses a TLS encoded SignedCertificateTimestampList.
+func parseSCTList(data []byte) ([]SCT, error) {
+	var scts []SCT
+	input := cryptobyte.String(data)
+	var list cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&list) || !input.Empty() {
+		return nil, errors.New("invalid SCT list")
+	}
+	for !list.Empty() {
+		var sct cryptobyte.String
+		var version uint8
+		var logID []byte
+		var timestamp uint64
+		if !list.ReadUint16LengthPrefixed(&sct) ||
+			!sct.ReadUint8(&version) ||
+			!sct.ReadBytes(&logID, 32) ||
+			!sct.ReadUint64(&timestamp) {
+			return nil, errors.New("invalid SCT in SCT list")
+		}
+		if version != 0 {
+			continue
+		}
+		scts = append(scts, SCT{
+			LogID:     base64.StdEncoding.EncodeToString(logID),
+			Timestamp: time.UnixMilli(int64(timestamp)).UTC(),
+		})
+	}
+	return scts, nil
+}
+
+// LookupCT reports whether cert is found in Certificate Transparency logs,
+// as indexed by crt.sh.
+func LookupCT(ctx context.Context, cert *x509.Certificate) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ctSearchURL+url.QueryEscape(Fingerprint(cert)), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := ctClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to search CT logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to search CT logs: %s", resp.Status)
+	}
+	var entries []json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&entries); err != nil {
+		return false, fmt.Errorf("invalid CT search response: %w", err)
+	}
+	return len(entries) > 0, nil
+}
+
+// checkCT warns when the leaf is not logged in CT, since browsers that
+// enforce CT reject publicly trusted certificates that are not.
+func (i *Importer) checkCT(ctx context.Context, leaf *x509.Certificate) {
+	scts, err := EmbeddedSCTs(leaf)
+	if err != nil {
+		i.logger.Warnf("Could not read the certificate's SCTs: %v", err)
+	}
+	switch {
+	case len(scts) >= minSCTs:
+		i.logger.Printf("✓ Certificate carries %d SCTs from CT logs\n", len(scts))
+		return
+	case len(scts) > 0:
+		i.logger.Warnf("Certificate carries only %d SCT; Chrome and Safari require at least %d", len(scts), minSCTs)
+		return
+	}
+
+	logged, err := LookupCT(ctx, leaf)
+	switch {
+	case err != nil:
+		i.logger.Warnf("Certificate carries no SCTs and could not be looked up in CT logs: %v", err)
+	case logged:
+		i.logger.Printf("✓ Certificate is logged in Certificate Transparency (crt.sh)\n")
+	default:
+		i.logger.Warnf("Certificate carries no SCTs and is not in Certificate Transparency logs; browsers that enforce CT will reject it if it is publicly trusted")
+	}
+}
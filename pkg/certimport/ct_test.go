@@ -0,0 +1,117 @@
+package certimport
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// leafWithSCTs issues a leaf embedding an SCT list with one SCT per
+// timestamp.
+func leafWithSCTs(t *testing.T, issuer *testCert, timestamps ...time.Time) *x509.Certificate {
+	t.Helper()
+
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(list *cryptobyte.Builder) {
+		for i, ts := range timestamps {
+			list.AddUint16LengthPrefixed(func(sct *cryptobyte.Builder) {
+				sct.AddUint8(0)
+				sct.AddBytes(bytes.Repeat([]byte{byte(i + 1)}, 32))
+				sct.AddUint64(uint64(ts.UnixMilli()))
+				sct.AddUint16(0)
+				sct.AddBytes([]byte{4, 3, 0, 0})
+			})
+		}
+	})
+	value, err := asn1.Marshal(b.BytesOrPanic())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "www.example.com"},
+		DNSNames:        []string{"www.example.com"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().AddDate(0, 3, 0),
+		ExtraExtensions: []pkix.Extension{{Id: oidSCTList, Value: value}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer.cert, key.Public(), issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestEmbeddedSCTs(t *testing.T) {
+	pki := newTestPKI(t)
+	ts := time.Now().Add(-time.Hour).Truncate(time.Millisecond).UTC()
+
+	scts, err := EmbeddedSCTs(leafWithSCTs(t, pki.intermediate, ts, ts.Add(time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scts) != 2 {
+		t.Fatalf("got %d SCTs, want 2", len(scts))
+	}
+	if !scts[0].Timestamp.Equal(ts) || scts[0].LogID == scts[1].LogID {
+		t.Errorf("SCTs = %+v", scts)
+	}
+
+	scts, err = EmbeddedSCTs(pki.leaf.cert)
+	if err != nil || len(scts) != 0 {
+		t.Errorf("EmbeddedSCTs() without the extension = %v, %v", scts, err)
+	}
+}
+
+func TestLookupCT(t *testing.T) {
+	pki := newTestPKI(t)
+	logged := Fingerprint(pki.leaf.cert)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sha256") == logged {
+			w.Write([]byte(`[{"id": 1}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	defer func(url string) { ctSearchURL = url }(ctSearchURL)
+	ctSearchURL = server.URL + "/?output=json&sha256="
+
+	for _, tt := range []struct {
+		cert *x509.Certificate
+		want bool
+	}{
+		{pki.leaf.cert, true},
+		{pki.intermediate.cert, false},
+	} {
+		got, err := LookupCT(context.Background(), tt.cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("LookupCT(%s) = %t, want %t", tt.cert.Subject, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package certimport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// Fingerprint returns the hex encoded SHA-256 digest of the certificate's DER
+// encoding.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// importedCertificates returns the summaries of every imported certificate
+// in the client's region.
+func importedCertificates(ctx context.Context, client *acm.Client) ([]types.CertificateSummary, error) {
+	input := &acm.ListCertificatesInput{
+		Includes: &types.Filters{KeyTypes: types.KeyAlgorithm("").Values()},
+	}
+
+	var imported []types.CertificateSummary
+	paginator := acm.NewListCertificatesPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for existing certificates: %w", err)
+		}
+		for _, summary := range page.CertificateSummaryList {
+			if summary.Type == types.CertificateTypeImported {
+				imported = append(imported, summary)
+			}
+		}
+	}
+	return imported, nil
+}
+
+// findIdentical returns the ARN of the certificate among summaries that is
+// identical to leaf, or an empty string if there is none. Candidates are
+// narrowed by expiry date before their bodies are fetched and compared.
+func findIdentical(ctx context.Context, client *acm.Client, summaries []types.CertificateSummary, leaf *x509.Certificate) (string, error) {
+	for _, summary := range summaries {
+		if summary.NotAfter != nil && !summary.NotAfter.Equal(leaf.NotAfter) {
+			continue
+		}
+
+		arn := aws.ToString(summary.CertificateArn)
+		output, err := client.GetCertificate(ctx, &acm.GetCertificateInput{
+			CertificateArn: aws.String(arn),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to get certificate %s: %w", arn, err)
+		}
+
+		block, _ := pem.Decode([]byte(aws.ToString(output.Certificate)))
+		if block != nil && bytes.Equal(block.Bytes, leaf.Raw) {
+			return arn, nil
+		}
+	}
+	return "", nil
+}
+
+// findSameNames returns the ARNs of the certificates among summaries that
+// cover exactly the same names as leaf.
+func findSameNames(summaries []types.CertificateSummary, leaf *x509.Certificate) []string {
+	want := certificateNames(leaf.Subject.CommonName, leaf.DNSNames)
+
+	var arns []string
+	for _, summary := range summaries {
+		names := certificateNames(aws.ToString(summary.DomainName), summary.SubjectAlternativeNameSummaries)
+		if sameNames(names, want) {
+			arns = append(arns, aws.ToString(summary.CertificateArn))
+		}
+	}
+	return arns
+}
+
+// findTagged returns the ARNs of the certificates among summaries that carry
+// every tag in want.
+func findTagged(ctx context.Context, client *acm.Client, summaries []types.CertificateSummary, want map[string]string) ([]string, error) {
+	var arns []string
+	for _, summary := range summaries {
+		arn := aws.ToString(summary.CertificateArn)
+		output, err := client.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{
+			CertificateArn: aws.String(arn),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for %s: %w", arn, err)
+		}
+
+		matched := 0
+		for _, tag := range output.Tags {
+			if value, ok := want[aws.ToString(tag.Key)]; ok && value == aws.ToString(tag.Value) {
+				matched++
+			}
+		}
+		if matched == len(want) {
+			arns = append(arns, arn)
+		}
+	}
+	return arns, nil
+}
+
+// certificateNames returns the lower-cased set of a certificate's domain name
+// and SANs.
+func certificateNames(domain string, sans []string) map[string]bool {
+	names := make(map[string]bool, len(sans)+1)
+	if domain != "" {
+		names[strings.ToLower(domain)] = true
+	}
+	for _, san := range sans {
+		names[strings.ToLower(san)] = true
+	}
+	return names
+}
+
+func sameNames(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if !b[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package certimport
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxChainDepth bounds how many issuers are followed when fetching a chain.
+const maxChainDepth = 10
+
+var aiaClient = &http.Client{Timeout: 15 * time.Second}
+
+// FetchChain completes the chain for leaf by following the Authority
+// Information Access "CA Issuers" URLs of every certificate whose issuer is
+// not already in known. It returns only the downloaded intermediates; root
+// CAs are not returned since ACM does not need them.
+func FetchChain(ctx context.Context, leaf *x509.Certificate, known []*x509.Certificate) ([]*x509.Certificate, error) {
+	chain := append([]*x509.Certificate(nil), known...)
+	var fetched []*x509.Certificate
+
+	current := leaf
+	for depth := 0; depth < maxChainDepth && !isSelfSigned(current); depth++ {
+		if issuer := findIssuer(current, chain); issuer != nil {
+			current = issuer
+			continue
+		}
+
+		// Intermediates issued directly by a root often carry no AIA URL
+		if len(current.IssuingCertificateURL) == 0 {
+			if current == leaf {
+				return nil, fmt.Errorf("cannot fetch issuer of %q: certificate has no CA Issuers URL", leaf.Subject.String())
+			}
+			break
+		}
+
+		var issuer *x509.Certificate
+		var lastErr error
+		for _, url := range current.IssuingCertificateURL {
+			issuer, lastErr = downloadCertificate(ctx, url)
+			if lastErr == nil && issuedBy(current, issuer) {
+				break
+			}
+			if lastErr == nil {
+				lastErr = fmt.Errorf("certificate at %s did not issue %q", url, current.Subject.String())
+			}
+			issuer = nil
+		}
+		if issuer == nil {
+			return nil, fmt.Errorf("failed to fetch issuer of %q: %w", current.Subject.String(), lastErr)
+		}
+
+		if isSelfSigned(issuer) {
+			break
+		}
+		chain = append(chain, issuer)
+		fetched = append(fetched, issuer)
+		current = issuer
+	}
+
+	return fetched, nil
+}
+
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if issuedBy(cert, candidate) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// downloadCertificate fetches a single certificate, accepting both DER (the
+// usual AIA encoding) and PEM responses.
+func downloadCertificate(ctx context.Context, url string) (*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA Issuers URL %s: %w", url, err)
+	}
+
+	resp, err := aiaClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%s returned unexpected PEM block %q", url, block.Type)
+		}
+		data = block.Bytes
+	}
+
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s did not return a valid certificate: %w", url, err)
+	}
+	return cert, nil
+}
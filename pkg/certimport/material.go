@@ -0,0 +1,129 @@
+package certimport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// MaxMaterialSize is the size above which an input is clearly not
+// certificate material and is not read.
+const MaxMaterialSize = 1 << 20
+
+// Material is the parsed certificate, private key and chain to import.
+type Material struct {
+	Leaf  *x509.Certificate
+	Key   crypto.Signer
+	Chain []*x509.Certificate
+}
+
+// LoadPEM parses a single leaf certificate and its private key. Both may be
+// PEM or DER encoded; passphrase is only called for encrypted keys.
+func LoadPEM(certData, keyData []byte, passphrase PassphraseFunc) (*Material, error) {
+	certs, err := ParseCertificates(certData, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) > 1 {
+		return nil, fmt.Errorf("certificate data contains %d certificates; pass only the leaf certificate and add intermediates to the chain", len(certs))
+	}
+
+	key, err := ParsePrivateKey(keyData, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Material{Leaf: certs[0], Key: key}, nil
+}
+
+// LoadBundle splits a single PEM file holding the leaf certificate,
+// intermediates and private key. The leaf is the certificate matching the
+// key; every other certificate becomes part of the chain.
+func LoadBundle(data []byte, passphrase PassphraseFunc) (*Material, error) {
+	certs, err := bundleCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("bundle file: %w", err)
+	}
+
+	material := splitLeaf(certs, key)
+	if material.Leaf == nil {
+		return nil, errors.New("bundle file does not contain a certificate matching its private key")
+	}
+	return material, nil
+}
+
+// LoadFullChain parses a certificate file holding the leaf followed by its
+// intermediates, such as cert-manager's tls.crt or certbot's fullchain.pem,
+// and its private key. The leaf is the certificate matching the key; the
+// others become the chain.
+func LoadFullChain(certData, keyData []byte, passphrase PassphraseFunc) (*Material, error) {
+	certs, err := ParseCertificates(certData, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(keyData, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	material := splitLeaf(certs, key)
+	if material.Leaf == nil {
+		return nil, errors.New("certificate file does not contain a certificate matching the private key")
+	}
+	return material, nil
+}
+
+// splitLeaf returns material with the first certificate matching key as the
+// leaf and the other certificates as the chain. Leaf is nil if none match.
+func splitLeaf(certs []*x509.Certificate, key crypto.Signer) *Material {
+	material := &Material{Key: key}
+	for _, cert := range certs {
+		if material.Leaf == nil && VerifyKeyMatch(cert, key) == nil {
+			material.Leaf = cert
+			continue
+		}
+		material.Chain = append(material.Chain, cert)
+	}
+	return material
+}
+
+// LoadPKCS12 extracts the leaf certificate, private key and any CA
+// certificates from a .pfx/.p12 bundle.
+func LoadPKCS12(data []byte, password string) (*Material, error) {
+	privateKey, leaf, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PKCS#12 data: %w", err)
+	}
+
+	var key crypto.Signer
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key = k
+	case *ecdsa.PrivateKey:
+		key = k
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T in PKCS#12 file (ACM accepts RSA and EC keys)", privateKey)
+	}
+
+	return &Material{Leaf: leaf, Key: key, Chain: caCerts}, nil
+}
+
+// AddChain parses PEM or DER chain certificates and appends them to the
+// material's chain, returning how many were added.
+func (m *Material) AddChain(data []byte) (int, error) {
+	chain, err := ParseCertificates(data, "certificate chain")
+	if err != nil {
+		return 0, err
+	}
+	m.Chain = append(m.Chain, chain...)
+	return len(chain), nil
+}
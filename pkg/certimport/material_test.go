@@ -0,0 +1,157 @@
+package certimport
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestLoadBundle(t *testing.T) {
+	pki := newTestPKI(t)
+
+	certPEM := func(certs ...*testCert) []byte {
+		var out []byte
+		for _, c := range certs {
+			out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})...)
+		}
+		return out
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(pki.leaf.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	encryptedDER, err := pkcs8.MarshalPrivateKey(pki.leaf.key, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedPEM := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedDER})
+	passphrase := func() ([]byte, error) { return []byte("secret"), nil }
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase PassphraseFunc
+		wantChain  []*x509.Certificate
+		wantErr    error
+		anyErr     bool
+	}{
+		{
+			name:      "leaf first",
+			data:      concat(certPEM(pki.leaf, pki.intermediate), keyPEM),
+			wantChain: []*x509.Certificate{pki.intermediate.cert},
+		},
+		{
+			name:      "key first, leaf last",
+			data:      concat(keyPEM, certPEM(pki.intermediate, pki.root, pki.leaf)),
+			wantChain: []*x509.Certificate{pki.intermediate.cert, pki.root.cert},
+		},
+		{
+			name:       "encrypted key",
+			data:       concat(certPEM(pki.leaf), encryptedPEM),
+			passphrase: passphrase,
+		},
+		{
+			name:    "encrypted key without passphrase",
+			data:    concat(certPEM(pki.leaf), encryptedPEM),
+			wantErr: ErrEncryptedKey,
+		},
+		{
+			name:   "no matching certificate",
+			data:   concat(certPEM(pki.intermediate), keyPEM),
+			anyErr: true,
+		},
+		{
+			name:   "no certificates",
+			data:   keyPEM,
+			anyErr: true,
+		},
+		{
+			name:   "no key",
+			data:   certPEM(pki.leaf),
+			anyErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			material, err := LoadBundle(tt.data, tt.passphrase)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("LoadBundle() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.anyErr:
+				if err == nil {
+					t.Fatal("LoadBundle() succeeded, want an error")
+				}
+				return
+			case err != nil:
+				t.Fatalf("LoadBundle() error = %v", err)
+			}
+			if !material.Leaf.Equal(pki.leaf.cert) {
+				t.Errorf("Leaf = %q, want %q", material.Leaf.Subject, pki.leaf.cert.Subject)
+			}
+			assertCerts(t, material.Chain, tt.wantChain)
+		})
+	}
+}
+
+func TestLoadPEM(t *testing.T) {
+	pki := newTestPKI(t)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(pki.leaf.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// DER input is accepted for both the certificate and the key
+	material, err := LoadPEM(pki.leaf.cert.Raw, keyDER, nil)
+	if err != nil {
+		t.Fatalf("LoadPEM() error = %v", err)
+	}
+	if !material.Leaf.Equal(pki.leaf.cert) {
+		t.Errorf("Leaf = %q, want %q", material.Leaf.Subject, pki.leaf.cert.Subject)
+	}
+
+	// A certificate file holding the chain as well is refused
+	bundle := EncodeCertificates([]*x509.Certificate{pki.leaf.cert, pki.intermediate.cert})
+	if _, err := LoadPEM(bundle, keyDER, nil); err == nil {
+		t.Error("LoadPEM() accepted a certificate file with several certificates")
+	}
+}
+
+func TestLoadFullChain(t *testing.T) {
+	pki := newTestPKI(t)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(pki.leaf.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fullChain := EncodeCertificates([]*x509.Certificate{pki.leaf.cert, pki.intermediate.cert})
+	material, err := LoadFullChain(fullChain, keyDER, nil)
+	if err != nil {
+		t.Fatalf("LoadFullChain() error = %v", err)
+	}
+	if !material.Leaf.Equal(pki.leaf.cert) {
+		t.Errorf("Leaf = %q, want %q", material.Leaf.Subject, pki.leaf.cert.Subject)
+	}
+	assertCerts(t, material.Chain, []*x509.Certificate{pki.intermediate.cert})
+
+	// The key must belong to one of the certificates
+	intermediateOnly := EncodeCertificates([]*x509.Certificate{pki.intermediate.cert})
+	if _, err := LoadFullChain(intermediateOnly, keyDER, nil); err == nil {
+		t.Error("LoadFullChain() accepted a certificate file without the leaf")
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
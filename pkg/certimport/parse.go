@@ -0,0 +1,276 @@
+package certimport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/youmark/pkcs8"
+)
+
+// ParseCertificates decodes every CERTIFICATE block in data and parses it as
+// an X.509 certificate. Non-certificate blocks are rejected so that a key or
+// CSR passed in the wrong flag is caught early. Data without any PEM blocks
+// is parsed as one or more concatenated DER certificates.
+func ParseCertificates(data []byte, fileType string) ([]*x509.Certificate, error) {
+	if IsDER(data) {
+		certs, err := x509.ParseCertificates(data)
+		if err != nil || len(certs) == 0 {
+			return nil, fmt.Errorf("%s file is neither PEM nor a DER encoded X.509 certificate", fileType)
+		}
+		return certs, nil
+	}
+
+	var certs []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%s file contains unexpected PEM block %q", fileType, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s file contains an invalid X.509 certificate: %w", fileType, err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s file does not contain any PEM encoded certificates", fileType)
+	}
+	return certs, nil
+}
+
+// bundleCertificates parses the CERTIFICATE blocks in a combined bundle,
+// ignoring private key and other blocks.
+func bundleCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("bundle file contains an invalid X.509 certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("bundle file does not contain any PEM encoded certificates")
+	}
+	return certs, nil
+}
+
+// IsDER reports whether data has no PEM blocks at all, in which case it is
+// treated as DER.
+func IsDER(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block == nil
+}
+
+// PassphraseFunc returns the passphrase for an encrypted private key. It is
+// only called when the key turns out to be encrypted, and the returned
+// slice is zeroed once the key is decrypted.
+type PassphraseFunc func() ([]byte, error)
+
+// ParsePrivateKey decodes the first private key block in data. PKCS#1 RSA,
+// SEC 1 EC and PKCS#8 keys are supported, including password protected
+// traditional PEM and encrypted PKCS#8 keys; other blocks such as
+// "EC PARAMETERS" are skipped. Data without any PEM blocks is parsed as DER.
+func ParsePrivateKey(data []byte, passphrase PassphraseFunc) (crypto.Signer, error) {
+	if IsDER(data) {
+		return parseDERPrivateKey(data, passphrase)
+	}
+
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("private key file does not contain a PEM encoded private key")
+		}
+
+		der := block.Bytes
+		// Legacy "Proc-Type: 4,ENCRYPTED" PEM is deprecated but still what
+		// openssl -traditional and many older tools emit
+		if x509.IsEncryptedPEMBlock(block) {
+			password, err := keyPassphrase(passphrase)
+			if err != nil {
+				return nil, err
+			}
+			der, err = x509.DecryptPEMBlock(block, password)
+			clear(password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s: %w", block.Type, err)
+			}
+		}
+
+		key, ok, err := parseKeyBlock(block.Type, der, passphrase)
+		if !ok {
+			continue
+		}
+		return key, err
+	}
+}
+
+// parseDERPrivateKey tries each supported private key encoding in turn,
+// falling back to encrypted PKCS#8 which cannot be recognised up front.
+func parseDERPrivateKey(der []byte, passphrase PassphraseFunc) (crypto.Signer, error) {
+	for _, blockType := range []string{"PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY"} {
+		if key, _, err := parseKeyBlock(blockType, der, nil); err == nil {
+			return key, nil
+		}
+	}
+
+	key, _, err := parseKeyBlock("ENCRYPTED PRIVATE KEY", der, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("private key file is neither PEM nor a supported DER private key: %w", err)
+	}
+	return key, nil
+}
+
+// parseKeyBlock parses der according to the PEM block type. ok is false for
+// block types that do not hold a private key.
+func parseKeyBlock(blockType string, der []byte, passphrase PassphraseFunc) (signer crypto.Signer, ok bool, err error) {
+	var key interface{}
+	switch blockType {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(der)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	case "ENCRYPTED PRIVATE KEY":
+		password, perr := keyPassphrase(passphrase)
+		if perr != nil {
+			return nil, true, perr
+		}
+		key, err = pkcs8.ParsePKCS8PrivateKey(der, password)
+		clear(password)
+	default:
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to parse %s: %w", blockType, err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, true, nil
+	case *ecdsa.PrivateKey:
+		return k, true, nil
+	default:
+		return nil, true, fmt.Errorf("unsupported private key type %T (ACM accepts RSA and EC keys)", key)
+	}
+}
+
+func keyPassphrase(passphrase PassphraseFunc) ([]byte, error) {
+	if passphrase == nil {
+		return nil, ErrEncryptedKey
+	}
+	password, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if len(password) == 0 {
+		return nil, ErrEncryptedKey
+	}
+	return password, nil
+}
+
+// VerifyKeyMatch checks that key is the private half of the certificate's
+// public key, comparing the RSA modulus and exponent or the EC curve point.
+func VerifyKeyMatch(cert *x509.Certificate, key crypto.Signer) error {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return fmt.Errorf("%w: private key type %T does not match RSA certificate", ErrKeyMismatch, key)
+		}
+		if !pub.Equal(&priv.PublicKey) {
+			return fmt.Errorf("%w: RSA modulus differs", ErrKeyMismatch)
+		}
+	case *ecdsa.PublicKey:
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return fmt.Errorf("%w: private key type %T does not match EC certificate", ErrKeyMismatch, key)
+		}
+		if pub.Curve != priv.Curve {
+			return fmt.Errorf("%w: curve %s differs from %s", ErrKeyMismatch,
+				priv.Curve.Params().Name, pub.Curve.Params().Name)
+		}
+		if !pub.Equal(&priv.PublicKey) {
+			return fmt.Errorf("%w: EC public point differs", ErrKeyMismatch)
+		}
+	default:
+		return fmt.Errorf("unsupported certificate public key type %T", cert.PublicKey)
+	}
+	return nil
+}
+
+// CheckValidity returns an error wrapping ErrExpired if cert is expired or
+// not yet valid at now.
+func CheckValidity(cert *x509.Certificate, now time.Time) error {
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("%w: expired on %s", ErrExpired, cert.NotAfter.Format(time.RFC3339))
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("%w: not valid until %s", ErrExpired, cert.NotBefore.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// PublicKeyDescription returns a short human-readable key type such as
+// "RSA 2048" or "EC P-256".
+func PublicKeyDescription(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "EC " + k.Curve.Params().Name
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+// EncodeCertificates returns certs as concatenated PEM CERTIFICATE blocks.
+func EncodeCertificates(certs []*x509.Certificate) []byte {
+	var out []byte
+	for _, cert := range certs {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return out
+}
+
+// EncodePrivateKey returns key as an unencrypted PEM block in the
+// traditional PKCS#1 (RSA) or SEC 1 (EC) form, which ACM always accepts.
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode private key: %w", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
@@ -0,0 +1,170 @@
+package certimport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs1 := x509.MarshalPKCS1PrivateKey(rsaKey)
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedPKCS8, err := pkcs8.MarshalPrivateKey(ecKey, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//lint:ignore SA1019 legacy encrypted PEM is still supported on input
+	legacyBlock, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", pkcs1, []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecParams := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}})
+
+	encode := func(blockType string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	}
+	passphrase := func(value string) PassphraseFunc {
+		return func() ([]byte, error) { return []byte(value), nil }
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase PassphraseFunc
+		want       crypto.Signer
+		wantErr    error
+		anyErr     bool
+	}{
+		{name: "PKCS#1 PEM", data: encode("RSA PRIVATE KEY", pkcs1), want: rsaKey},
+		{name: "SEC 1 PEM", data: encode("EC PRIVATE KEY", sec1), want: ecKey},
+		{name: "SEC 1 PEM after EC PARAMETERS", data: append(ecParams, encode("EC PRIVATE KEY", sec1)...), want: ecKey},
+		{name: "PKCS#8 RSA PEM", data: encode("PRIVATE KEY", rsaPKCS8), want: rsaKey},
+		{name: "PKCS#8 EC PEM", data: encode("PRIVATE KEY", ecPKCS8), want: ecKey},
+		{name: "PKCS#1 DER", data: pkcs1, want: rsaKey},
+		{name: "SEC 1 DER", data: sec1, want: ecKey},
+		{name: "PKCS#8 DER", data: rsaPKCS8, want: rsaKey},
+		{name: "encrypted PKCS#8 PEM", data: encode("ENCRYPTED PRIVATE KEY", encryptedPKCS8), passphrase: passphrase("secret"), want: ecKey},
+		{name: "encrypted PKCS#8 DER", data: encryptedPKCS8, passphrase: passphrase("secret"), want: ecKey},
+		{name: "legacy encrypted PEM", data: pem.EncodeToMemory(legacyBlock), passphrase: passphrase("secret"), want: rsaKey},
+		{name: "encrypted PKCS#8 without passphrase", data: encode("ENCRYPTED PRIVATE KEY", encryptedPKCS8), wantErr: ErrEncryptedKey},
+		{name: "legacy encrypted PEM without passphrase", data: pem.EncodeToMemory(legacyBlock), wantErr: ErrEncryptedKey},
+		{name: "empty passphrase", data: encode("ENCRYPTED PRIVATE KEY", encryptedPKCS8), passphrase: passphrase(""), wantErr: ErrEncryptedKey},
+		{name: "wrong passphrase", data: encode("ENCRYPTED PRIVATE KEY", encryptedPKCS8), passphrase: passphrase("wrong"), anyErr: true},
+		{name: "certificate only", data: encode("CERTIFICATE", []byte{0x30, 0x00}), anyErr: true},
+		{name: "garbage DER", data: []byte("not a key"), anyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePrivateKey(tt.data, tt.passphrase)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParsePrivateKey() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil {
+					t.Fatal("ParsePrivateKey() succeeded, want an error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("ParsePrivateKey() error = %v", err)
+				}
+				if !got.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(tt.want.Public()) {
+					t.Fatalf("ParsePrivateKey() returned a different key")
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyKeyMatch(t *testing.T) {
+	pki := newTestPKI(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		wantErr bool
+	}{
+		{name: "matching EC key", key: pki.leaf.key},
+		{name: "different EC key", key: pki.intermediate.key, wantErr: true},
+		{name: "different curve", key: p384Key, wantErr: true},
+		{name: "RSA key for EC certificate", key: rsaKey, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyKeyMatch(pki.leaf.cert, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyKeyMatch() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrKeyMismatch) {
+				t.Fatalf("VerifyKeyMatch() error = %v, want %v", err, ErrKeyMismatch)
+			}
+		})
+	}
+}
+
+func TestCheckValidity(t *testing.T) {
+	pki := newTestPKI(t)
+	leaf := pki.leaf.cert
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "valid", now: time.Now()},
+		{name: "expired", now: leaf.NotAfter.Add(time.Second), wantErr: true},
+		{name: "not yet valid", now: leaf.NotBefore.Add(-time.Second), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckValidity(leaf, tt.now)
+			if tt.wantErr && !errors.Is(err, ErrExpired) {
+				t.Fatalf("CheckValidity() error = %v, want %v", err, ErrExpired)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("CheckValidity() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package certimport
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// SPKIPin returns the base64 encoded SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of pub: the pin-sha256 value of HPKP (RFC 7469) and
+// Android's network security configuration.
+func SPKIPin(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// ParsePublicKey returns the public key of the first public key,
+// certificate, certificate request or unencrypted private key in data, so
+// that backup pins can be computed from whatever the key's owner has at
+// hand. Data without any PEM blocks is parsed as DER.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	if IsDER(data) {
+		for _, blockType := range []string{"PUBLIC KEY", "CERTIFICATE", "CERTIFICATE REQUEST", "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY"} {
+			if pub, ok, err := parsePublicKeyBlock(blockType, data); ok && err == nil {
+				return pub, nil
+			}
+		}
+		return nil, errors.New("no public key, certificate, certificate request or private key found")
+	}
+
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("no PEM encoded public key, certificate, certificate request or private key found")
+		}
+		if pub, ok, err := parsePublicKeyBlock(block.Type, block.Bytes); ok {
+			return pub, err
+		}
+	}
+}
+
+// parsePublicKeyBlock returns the public key in a PEM block of blockType.
+// ok is false for blocks of other types.
+func parsePublicKeyBlock(blockType string, der []byte) (pub crypto.PublicKey, ok bool, err error) {
+	switch blockType {
+	case "PUBLIC KEY":
+		pub, err = x509.ParsePKIXPublicKey(der)
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(der)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(der); err == nil {
+			pub = cert.PublicKey
+		}
+	case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+		var csr *x509.CertificateRequest
+		if csr, err = x509.ParseCertificateRequest(der); err == nil {
+			pub = csr.PublicKey
+		}
+	default:
+		// Encrypted private keys fail with ErrEncryptedKey
+		key, ok, err := parseKeyBlock(blockType, der, nil)
+		if !ok || err != nil {
+			return nil, ok, err
+		}
+		return key.Public(), true, nil
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to parse %s: %w", blockType, err)
+	}
+	return pub, true, nil
+}
@@ -0,0 +1,89 @@
+package certimport
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestSPKIPin(t *testing.T) {
+	pki := newTestPKI(t)
+
+	sum := sha256.Sum256(pki.leaf.cert.RawSubjectPublicKeyInfo)
+	want := base64.StdEncoding.EncodeToString(sum[:])
+	got, err := SPKIPin(pki.leaf.cert.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("SPKIPin() = %q, want %q", got, want)
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pki := newTestPKI(t)
+	want, err := SPKIPin(pki.leaf.key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(pki.leaf.key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(pki.leaf.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, pki.leaf.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedDER, err := pkcs8.MarshalPrivateKey(pki.leaf.key, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(blockType string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+		anyErr  bool
+	}{
+		{name: "public key", data: encode("PUBLIC KEY", publicDER)},
+		{name: "certificate", data: encode("CERTIFICATE", pki.leaf.cert.Raw)},
+		{name: "certificate request", data: encode("CERTIFICATE REQUEST", csrDER)},
+		{name: "private key", data: encode("PRIVATE KEY", privateDER)},
+		{name: "after other blocks", data: concat(encode("EC PARAMETERS", []byte{0x06, 0x00}), encode("PUBLIC KEY", publicDER))},
+		{name: "DER public key", data: publicDER},
+		{name: "DER certificate", data: pki.leaf.cert.Raw},
+		{name: "encrypted private key", data: encode("ENCRYPTED PRIVATE KEY", encryptedDER), wantErr: ErrEncryptedKey},
+		{name: "no key", data: []byte("not a key"), anyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, err := ParsePublicKey(tt.data)
+			if tt.wantErr != nil || tt.anyErr {
+				if err == nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParsePublicKey() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := SPKIPin(pub); got != want {
+				t.Errorf("SPKIPin(ParsePublicKey()) = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package certimport
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrKeyPolicy is returned when a certificate's key or signature is rejected
+// by the KeyPolicy of an import.
+var ErrKeyPolicy = errors.New("certificate does not meet the key policy")
+
+// ErrNotCovered is returned when a certificate does not cover a hostname
+// listed in ImportOptions.MustCover.
+var ErrNotCovered = errors.New("certificate does not cover the required hostnames")
+
+// KeyAlgorithms are the key algorithm names used by KeyPolicy. "RSA" and
+// "ECDSA" match any size or curve.
+const KeyAlgorithms = "RSA, RSA_1024, RSA_2048, RSA_3072, RSA_4096, ECDSA, ECDSA_P256, ECDSA_P384 or ECDSA_P521"
+
+// useAlgorithms lists the key algorithms each service accepts in an ACM
+// certificate. ACM itself imports all of them.
+var useAlgorithms = map[string][]string{
+	"acm":        {"RSA_1024", "RSA_2048", "RSA_3072", "RSA_4096", "ECDSA_P256", "ECDSA_P384", "ECDSA_P521"},
+	"elb":        {"RSA_1024", "RSA_2048", "RSA_3072", "RSA_4096", "ECDSA_P256", "ECDSA_P384", "ECDSA_P521"},
+	"cloudfront": {"RSA_1024", "RSA_2048", "ECDSA_P256"},
+}
+
+// weakSignatures are signature algorithms that are no longer considered
+// secure. Certificates signed with them are rejected by browsers.
+var weakSignatures = []x509.SignatureAlgorithm{
+	x509.MD2WithRSA,
+	x509.MD5WithRSA,
+	x509.SHA1WithRSA,
+	x509.DSAWithSHA1,
+	x509.ECDSAWithSHA1,
+}
+
+// DefaultMinRSABits is the shortest RSA key accepted unless configured
+// otherwise, as in NIST SP 800-131A.
+const DefaultMinRSABits = 2048
+
+// KeyPolicy restricts the keys and signatures accepted for import. The zero
+// value only rejects weak signature algorithms and keys ACM cannot import.
+type KeyPolicy struct {
+	// MinRSABits rejects RSA keys shorter than this many bits.
+	MinRSABits int
+	// Algorithms, when not empty, lists the accepted key algorithms; see
+	// KeyAlgorithms for the names.
+	Algorithms []string
+	// Uses lists the services the certificate is meant for ("elb",
+	// "cloudfront"). Keys those services cannot use are rejected.
+	Uses []string
+}
+
+// CheckUses reports an error for an unknown use.
+func CheckUses(uses []string) error {
+	for _, use := range uses {
+		if _, ok := useAlgorithms[use]; !ok {
+			return fmt.Errorf("unknown intended use %q, expected elb or cloudfront", use)
+		}
+	}
+	return nil
+}
+
+// CheckAlgorithms reports an error for an unknown algorithm name.
+func CheckAlgorithms(algorithms []string) error {
+	for _, algo := range algorithms {
+		if !slices.Contains(useAlgorithms["acm"], algo) && algo != "RSA" && algo != "ECDSA" {
+			return fmt.Errorf("unknown key algorithm %q, expected %s", algo, KeyAlgorithms)
+		}
+	}
+	return nil
+}
+
+// KeyAlgorithm returns the KeyPolicy name of a public key, such as RSA_2048
+// or ECDSA_P256.
+func KeyAlgorithm(cert *x509.Certificate) string {
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA_%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA_" + strings.ReplaceAll(k.Curve.Params().Name, "-", "")
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// Check reports the first way leaf and the chain certificates that issued it
+// violate the policy. Self-signed roots are not checked for their signature,
+// which clients do not verify.
+func (p KeyPolicy) Check(leaf *x509.Certificate, chain []*x509.Certificate) error {
+	for _, cert := range append([]*x509.Certificate{leaf}, chain...) {
+		if isSelfSigned(cert) && cert != leaf {
+			continue
+		}
+		if slices.Contains(weakSignatures, cert.SignatureAlgorithm) {
+			return fmt.Errorf("%w: %q is signed with %s, which clients reject as insecure; have it reissued with SHA-256 or better",
+				ErrKeyPolicy, cert.Subject.String(), cert.SignatureAlgorithm)
+		}
+	}
+
+	algo := KeyAlgorithm(leaf)
+	if !slices.Contains(useAlgorithms["acm"], algo) {
+		return fmt.Errorf("%w: ACM cannot import %s keys", ErrKeyPolicy, algo)
+	}
+	if rsaKey, ok := leaf.PublicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < p.MinRSABits {
+		return fmt.Errorf("%w: the RSA key has %d bits, at least %d are required", ErrKeyPolicy, rsaKey.N.BitLen(), p.MinRSABits)
+	}
+	if len(p.Algorithms) > 0 && !slices.ContainsFunc(p.Algorithms, func(allowed string) bool {
+		return allowed == algo || strings.HasPrefix(algo, allowed+"_")
+	}) {
+		return fmt.Errorf("%w: %s keys are not allowed, expected %s", ErrKeyPolicy, algo, strings.Join(p.Algorithms, ", "))
+	}
+	for _, use := range p.Uses {
+		if !slices.Contains(useAlgorithms[use], algo) {
+			return fmt.Errorf("%w: %s cannot use %s keys from ACM, only %s", ErrKeyPolicy, use, algo, strings.Join(useAlgorithms[use], ", "))
+		}
+	}
+	return nil
+}
+
+// CheckCoverage reports the hostnames that leaf is not valid for, using the
+// same wildcard rules as TLS clients: "*.example.com" covers
+// "www.example.com" but neither "example.com" nor "a.b.example.com".
+// Certificates without SANs are matched against their common name.
+func CheckCoverage(leaf *x509.Certificate, hosts []string) error {
+	cert := leaf
+	if len(leaf.DNSNames) == 0 && len(leaf.IPAddresses) == 0 && leaf.Subject.CommonName != "" {
+		withCN := *leaf
+		withCN.DNSNames = []string{leaf.Subject.CommonName}
+		cert = &withCN
+	}
+
+	var missing []string
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			missing = append(missing, host)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s not covered by %s", ErrNotCovered, strings.Join(missing, ", "), strings.Join(cert.DNSNames, ", "))
+	}
+	return nil
+}
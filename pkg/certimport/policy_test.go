@@ -0,0 +1,130 @@
+package certimport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// leafWithKey creates a self-signed leaf certificate for key, valid for
+// names or www.example.com.
+func leafWithKey(t *testing.T, key crypto.Signer, names ...string) *x509.Certificate {
+	t.Helper()
+	if len(names) == 0 {
+		names = []string{"www.example.com"}
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(0, 3, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestKeyPolicyCheck(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pki := newTestPKI(t)
+	ecLeaf := pki.leaf.cert
+	p384Leaf := leafWithKey(t, p384)
+	rsaLeaf := leafWithKey(t, rsa1024)
+	sha1Intermediate := *pki.intermediate.cert
+	sha1Intermediate.SignatureAlgorithm = x509.ECDSAWithSHA1
+
+	tests := []struct {
+		name    string
+		policy  KeyPolicy
+		leaf    *x509.Certificate
+		chain   []*x509.Certificate
+		wantErr bool
+	}{
+		{name: "zero policy", leaf: ecLeaf, chain: []*x509.Certificate{pki.intermediate.cert, pki.root.cert}},
+		{name: "short RSA key", policy: KeyPolicy{MinRSABits: 2048}, leaf: rsaLeaf, wantErr: true},
+		{name: "RSA key long enough", policy: KeyPolicy{MinRSABits: 1024}, leaf: rsaLeaf},
+		{name: "algorithm family allowed", policy: KeyPolicy{Algorithms: []string{"RSA", "ECDSA"}}, leaf: p384Leaf},
+		{name: "algorithm not allowed", policy: KeyPolicy{Algorithms: []string{"RSA", "ECDSA_P256"}}, leaf: p384Leaf, wantErr: true},
+		{name: "usable with CloudFront", policy: KeyPolicy{Uses: []string{"cloudfront"}}, leaf: ecLeaf},
+		{name: "not usable with CloudFront", policy: KeyPolicy{Uses: []string{"cloudfront"}}, leaf: p384Leaf, wantErr: true},
+		{name: "usable with ELB", policy: KeyPolicy{Uses: []string{"elb"}}, leaf: p384Leaf},
+		{name: "SHA-1 intermediate", leaf: ecLeaf, chain: []*x509.Certificate{&sha1Intermediate}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.leaf, tt.chain)
+			if tt.wantErr && !errors.Is(err, ErrKeyPolicy) {
+				t.Fatalf("Check() error = %v, want %v", err, ErrKeyPolicy)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestKeyAlgorithm(t *testing.T) {
+	pki := newTestPKI(t)
+	if got := KeyAlgorithm(pki.leaf.cert); got != "ECDSA_P256" {
+		t.Fatalf("KeyAlgorithm() = %q, want ECDSA_P256", got)
+	}
+}
+
+func TestCheckCoverage(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wildcard := leafWithKey(t, key, "*.example.com", "example.com")
+	cnOnly := leafWithKey(t, key, "cn.example.com")
+	cnOnly.DNSNames = nil
+
+	tests := []struct {
+		name    string
+		leaf    *x509.Certificate
+		hosts   []string
+		wantErr bool
+	}{
+		{name: "nothing required", leaf: wildcard},
+		{name: "wildcard and apex", leaf: wildcard, hosts: []string{"www.example.com", "example.com"}},
+		{name: "wildcard is one label deep", leaf: wildcard, hosts: []string{"a.b.example.com"}, wantErr: true},
+		{name: "other domain", leaf: wildcard, hosts: []string{"www.example.org"}, wantErr: true},
+		{name: "common name only", leaf: cnOnly, hosts: []string{"cn.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCoverage(tt.leaf, tt.hosts)
+			if tt.wantErr && !errors.Is(err, ErrNotCovered) {
+				t.Fatalf("CheckCoverage() error = %v, want %v", err, ErrNotCovered)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("CheckCoverage() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package certimport
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces private key material in messages.
+const redacted = "[REDACTED PRIVATE KEY]"
+
+// privateKeyPEM matches PEM private key blocks of any type, including one
+// cut off before its END line.
+var privateKeyPEM = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z0-9 ]*PRIVATE KEY-----|$)`)
+
+// RedactPrivateKeys replaces every PEM private key block in s.
+func RedactPrivateKeys(s string) string {
+	return privateKeyPEM.ReplaceAllString(s, redacted)
+}
+
+// redactedError is an error whose message had key material removed. The
+// original error is still available to errors.Is and errors.As, so that
+// AWS error codes can be inspected.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// RedactError hides private key material in the message of err: PEM private
+// key blocks and key, raw or base64 encoded as in AWS request bodies. It
+// returns err itself when there is nothing to hide.
+func RedactError(err error, key []byte) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	clean := RedactPrivateKeys(msg)
+	if len(key) > 0 {
+		clean = strings.ReplaceAll(clean, string(key), redacted)
+		clean = strings.ReplaceAll(clean, base64.StdEncoding.EncodeToString(key), redacted)
+	}
+	if clean == msg {
+		return err
+	}
+	return &redactedError{msg: clean, err: err}
+}
@@ -0,0 +1,164 @@
+package certimport
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ErrRevoked is returned for certificates their CA has revoked, unless
+// ImportOptions.AllowRevoked is set.
+var ErrRevoked = errors.New("certificate has been revoked")
+
+// RevocationStatus is the revocation status of a certificate as reported by
+// its CA.
+type RevocationStatus struct {
+	Revoked   bool
+	RevokedAt time.Time
+	// Reason is the RFC 5280 reason code, such as 1 for key compromise
+	Reason int
+	// Source is the OCSP responder or CRL that answered
+	Source string
+}
+
+var revocationClient = &http.Client{Timeout: 15 * time.Second}
+
+// CheckRevocation asks the OCSP responders of cert for its status, falling
+// back to its CRL distribution points when it has no responder or none
+// answers. issuer is the certificate that issued cert. An error means the
+// status could not be determined.
+func CheckRevocation(ctx context.Context, cert, issuer *x509.Certificate) (RevocationStatus, error) {
+	if len(cert.OCSPServer) == 0 && len(cert.CRLDistributionPoints) == 0 {
+		return RevocationStatus{}, fmt.Errorf("certificate %q names no OCSP responder or CRL", cert.Subject.String())
+	}
+
+	var errs []error
+	for _, url := range cert.OCSPServer {
+		status, err := checkOCSP(ctx, url, cert, issuer)
+		if err == nil {
+			return status, nil
+		}
+		errs = append(errs, err)
+	}
+	for _, url := range cert.CRLDistributionPoints {
+		status, err := checkCRL(ctx, url, cert, issuer)
+		if err == nil {
+			return status, nil
+		}
+		errs = append(errs, err)
+	}
+	return RevocationStatus{}, errors.Join(errs...)
+}
+
+func checkOCSP(ctx context.Context, url string, cert, issuer *x509.Certificate) (RevocationStatus, error) {
+	status := RevocationStatus{Source: url}
+
+	request, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return status, fmt.Errorf("failed to create OCSP request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(request))
+	if err != nil {
+		return status, fmt.Errorf("invalid OCSP responder %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	data, err := download(req)
+	if err != nil {
+		return status, err
+	}
+
+	resp, err := ocsp.ParseResponseForCert(data, cert, issuer)
+	if err != nil {
+		return status, fmt.Errorf("invalid OCSP response from %s: %w", url, err)
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return status, nil
+	case ocsp.Revoked:
+		status.Revoked, status.RevokedAt, status.Reason = true, resp.RevokedAt, resp.RevocationReason
+		return status, nil
+	}
+	return status, fmt.Errorf("OCSP responder %s does not know the certificate", url)
+}
+
+func checkCRL(ctx context.Context, url string, cert, issuer *x509.Certificate) (RevocationStatus, error) {
+	status := RevocationStatus{Source: url}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return status, fmt.Errorf("invalid CRL distribution point %s: %w", url, err)
+	}
+	data, err := download(req)
+	if err != nil {
+		return status, err
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return status, fmt.Errorf("invalid CRL at %s: %w", url, err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return status, fmt.Errorf("CRL at %s was not signed by %q: %w", url, issuer.Subject.String(), err)
+	}
+	if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+		return status, fmt.Errorf("CRL at %s is out of date since %s", url, crl.NextUpdate.Format(time.RFC3339))
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			status.Revoked, status.RevokedAt, status.Reason = true, entry.RevocationTime, entry.ReasonCode
+			break
+		}
+	}
+	return status, nil
+}
+
+// download returns the body of a successful response to req.
+func download(req *http.Request) ([]byte, error) {
+	resp, err := revocationClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query %s: %s", req.URL, resp.Status)
+	}
+	// CRLs of large CAs run to tens of megabytes
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", req.URL, err)
+	}
+	return data, nil
+}
+
+// revocationReasons names the RFC 5280 reason codes.
+var revocationReasons = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "key compromise",
+	ocsp.CACompromise:         "CA compromise",
+	ocsp.AffiliationChanged:   "affiliation changed",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessation of operation",
+	ocsp.CertificateHold:      "certificate hold",
+	ocsp.RemoveFromCRL:        "remove from CRL",
+	ocsp.PrivilegeWithdrawn:   "privilege withdrawn",
+	ocsp.AACompromise:         "AA compromise",
+}
+
+func (s RevocationStatus) String() string {
+	if !s.Revoked {
+		return "not revoked (" + s.Source + ")"
+	}
+	reason, ok := revocationReasons[s.Reason]
+	if !ok {
+		reason = fmt.Sprintf("reason %d", s.Reason)
+	}
+	return fmt.Sprintf("revoked on %s, %s (%s)", s.RevokedAt.UTC().Format(time.RFC3339), reason, s.Source)
+}
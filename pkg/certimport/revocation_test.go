@@ -0,0 +1,149 @@
+package certimport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// revocableLeaf issues a leaf naming the given OCSP responder and CRL.
+func revocableLeaf(t *testing.T, issuer *testCert, ocspURL, crlURL string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(4242),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(0, 3, 0),
+	}
+	if ocspURL != "" {
+		template.OCSPServer = []string{ocspURL}
+	}
+	if crlURL != "" {
+		template.CRLDistributionPoints = []string{crlURL}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer.cert, key.Public(), issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCheckRevocationOCSP(t *testing.T) {
+	pki := newTestPKI(t)
+	revokedAt := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+	for _, tt := range []struct {
+		name        string
+		status      int
+		wantRevoked bool
+		wantErr     bool
+	}{
+		{name: "good", status: ocsp.Good},
+		{name: "revoked", status: ocsp.Revoked, wantRevoked: true},
+		{name: "unknown", status: ocsp.Unknown, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				req, err := ocsp.ParseRequest(body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				resp, err := ocsp.CreateResponse(pki.intermediate.cert, pki.intermediate.cert, ocsp.Response{
+					Status:           tt.status,
+					SerialNumber:     req.SerialNumber,
+					ThisUpdate:       time.Now().Add(-time.Hour),
+					NextUpdate:       time.Now().Add(time.Hour),
+					RevokedAt:        revokedAt,
+					RevocationReason: ocsp.KeyCompromise,
+				}, pki.intermediate.key)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.Write(resp)
+			}))
+			defer server.Close()
+			leaf := revocableLeaf(t, pki.intermediate, server.URL, "")
+
+			status, err := CheckRevocation(context.Background(), leaf, pki.intermediate.cert)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckRevocation() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if status.Revoked != tt.wantRevoked {
+				t.Errorf("Revoked = %t, want %t", status.Revoked, tt.wantRevoked)
+			}
+			if tt.wantRevoked && (status.Reason != ocsp.KeyCompromise || !status.RevokedAt.Equal(revokedAt)) {
+				t.Errorf("status = %+v, want key compromise at %s", status, revokedAt)
+			}
+		})
+	}
+}
+
+func TestCheckRevocationCRLFallback(t *testing.T) {
+	pki := newTestPKI(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ocsp", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/crl", func(w http.ResponseWriter, r *http.Request) {
+		crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: time.Now().Add(-time.Hour),
+			NextUpdate: time.Now().Add(time.Hour),
+			RevokedCertificateEntries: []x509.RevocationListEntry{
+				{SerialNumber: big.NewInt(4242), RevocationTime: time.Now().Add(-time.Hour), ReasonCode: ocsp.Superseded},
+			},
+		}, pki.intermediate.cert, pki.intermediate.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(crl)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	leaf := revocableLeaf(t, pki.intermediate, server.URL+"/ocsp", server.URL+"/crl")
+	status, err := CheckRevocation(context.Background(), leaf, pki.intermediate.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Revoked || status.Reason != ocsp.Superseded || status.Source != server.URL+"/crl" {
+		t.Errorf("status = %+v, want revoked as superseded by the CRL", status)
+	}
+
+	// A CRL signed by another CA is not trusted
+	if _, err := CheckRevocation(context.Background(), leaf, pki.root.cert); err == nil {
+		t.Error("CheckRevocation() with the wrong issuer succeeded")
+	}
+}
+
+func TestCheckRevocationNoSources(t *testing.T) {
+	pki := newTestPKI(t)
+	if _, err := CheckRevocation(context.Background(), pki.leaf.cert, pki.intermediate.cert); err == nil {
+		t.Error("CheckRevocation() without OCSP or CRL succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+package certimport
+
+import (
+	"crypto/x509"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ACM tag limits, see the ACM Tag API reference.
+const (
+	MaxTags           = 50
+	MaxTagKeyLength   = 128
+	MaxTagValueLength = 256
+)
+
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// ValidateTags checks tags against ACM's limits on count, length, allowed
+// characters and the reserved "aws:" prefix, so that a bad tag is reported
+// before any API call is made.
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("too many tags: %d (ACM allows at most %d)", len(tags), MaxTags)
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := tags[key]
+		switch {
+		case key == "":
+			return fmt.Errorf("tag key must not be empty")
+		case utf8.RuneCountInString(key) > MaxTagKeyLength:
+			return fmt.Errorf("tag key %q is longer than %d characters", key, MaxTagKeyLength)
+		case utf8.RuneCountInString(value) > MaxTagValueLength:
+			return fmt.Errorf("value of tag %q is longer than %d characters", key, MaxTagValueLength)
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			return fmt.Errorf("tag key %q uses the reserved aws: prefix", key)
+		case !tagPattern.MatchString(key):
+			return fmt.Errorf("tag key %q contains characters ACM does not allow", key)
+		case !tagPattern.MatchString(value):
+			return fmt.Errorf("value of tag %q contains characters ACM does not allow", key)
+		}
+	}
+	return nil
+}
+
+// CertificateTags returns tags describing cert itself, so that imported
+// certificates can be identified in the console without downloading them.
+func CertificateTags(cert *x509.Certificate) map[string]string {
+	domain := cert.Subject.CommonName
+	if domain == "" && len(cert.DNSNames) > 0 {
+		domain = cert.DNSNames[0]
+	}
+	issuer := cert.Issuer.CommonName
+	if issuer == "" {
+		issuer = cert.Issuer.String()
+	}
+
+	return map[string]string{
+		"Domain":       SanitizeTagValue(domain),
+		"Issuer":       SanitizeTagValue(issuer),
+		"NotAfter":     cert.NotAfter.UTC().Format(time.RFC3339),
+		"SerialNumber": cert.SerialNumber.Text(16),
+		"KeyAlgorithm": PublicKeyDescription(cert.PublicKey),
+	}
+}
+
+// SanitizeTagValue replaces characters ACM does not allow in tag values with
+// underscores and truncates the value to the maximum length.
+func SanitizeTagValue(value string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range value {
+		if n == MaxTagValueLength {
+			break
+		}
+		if tagPattern.MatchString(string(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+		n++
+	}
+	return b.String()
+}
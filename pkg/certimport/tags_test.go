@@ -0,0 +1,63 @@
+package certimport
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateTags(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxTags; i++ {
+		tooMany[fmt.Sprintf("Key%d", i)] = "value"
+	}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "valid", tags: map[string]string{"Environment": "prod", "Owner": "team-a@example.com", "Path": "/a/b:c"}},
+		{name: "empty value", tags: map[string]string{"Environment": ""}},
+		{name: "unicode", tags: map[string]string{"Équipe": "données"}},
+		{name: "empty key", tags: map[string]string{"": "value"}, wantErr: true},
+		{name: "key too long", tags: map[string]string{strings.Repeat("k", MaxTagKeyLength+1): "value"}, wantErr: true},
+		{name: "value too long", tags: map[string]string{"Key": strings.Repeat("v", MaxTagValueLength+1)}, wantErr: true},
+		{name: "longest key and value", tags: map[string]string{strings.Repeat("k", MaxTagKeyLength): strings.Repeat("v", MaxTagValueLength)}},
+		{name: "reserved prefix", tags: map[string]string{"aws:cloudformation:stack-name": "web"}, wantErr: true},
+		{name: "reserved prefix upper case", tags: map[string]string{"AWS:Name": "web"}, wantErr: true},
+		{name: "invalid key character", tags: map[string]string{"Key*": "value"}, wantErr: true},
+		{name: "invalid value character", tags: map[string]string{"Key": "a,b"}, wantErr: true},
+		{name: "too many", tags: tooMany, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTags() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSanitizeTagValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "Let's Encrypt", want: "Let_s Encrypt"},
+		{value: "*.example.com", want: "_.example.com"},
+		{value: strings.Repeat("a", MaxTagValueLength+10), want: strings.Repeat("a", MaxTagValueLength)},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeTagValue(tt.value); got != tt.want {
+			t.Errorf("SanitizeTagValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+		if err := ValidateTags(map[string]string{"Key": SanitizeTagValue(tt.value)}); err != nil {
+			t.Errorf("sanitized value of %q is not a valid tag: %v", tt.value, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package certimport
+
+import (
+	"crypto/x509"
+	_ "embed"
+	"errors"
+	"fmt"
+)
+
+// amazonRootsPEM holds the Amazon Trust Services roots: Amazon Root CA 1 to
+// 4 and the Starfield Services root that cross-signs them.
+//
+//go:embed amazon_roots.pem
+var amazonRootsPEM []byte
+
+// TrustStore is a named set of trusted root CAs.
+type TrustStore struct {
+	Name  string
+	Roots *x509.CertPool
+}
+
+// TrustStores returns the local system root store and the Amazon Trust
+// Services roots.
+func TrustStores() []TrustStore {
+	system, err := x509.SystemCertPool()
+	if err != nil {
+		system = x509.NewCertPool()
+	}
+	amazon := x509.NewCertPool()
+	amazon.AppendCertsFromPEM(amazonRootsPEM)
+
+	return []TrustStore{
+		{Name: "system", Roots: system},
+		{Name: "Amazon Trust Services", Roots: amazon},
+	}
+}
+
+// TrustResult is the outcome of verifying a chain against one trust store.
+type TrustResult struct {
+	Store string
+	// Path runs from the leaf to a root of the store when verification
+	// succeeded.
+	Path []*x509.Certificate
+	Err  error
+	// Missing is set when the chain stops short of the store's roots: it is
+	// the certificate whose issuer is neither in the chain nor a root of the
+	// store.
+	Missing *x509.Certificate
+}
+
+// VerifyTrust verifies leaf and the intermediates of chain against the roots
+// of store only. Unlike VerifyChain, a root CA supplied in the chain is not
+// trusted.
+func VerifyTrust(leaf *x509.Certificate, chain OrderedChain, store TrustStore) TrustResult {
+	result := TrustResult{Store: store.Name}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain.Intermediates() {
+		intermediates.AddCert(cert)
+	}
+	paths, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         store.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err == nil {
+		result.Path = paths[0]
+		return result
+	}
+	result.Err = err
+
+	if !IsUnknownAuthority(err) {
+		return result
+	}
+	top := leaf
+	if certs := chain.Intermediates(); len(certs) > 0 {
+		top = certs[len(certs)-1]
+	}
+	if chain.Root != nil {
+		result.Err = fmt.Errorf("root CA %q is not trusted by the %s store", chain.Root.Subject.String(), store.Name)
+		return result
+	}
+	result.Missing = top
+	msg := fmt.Sprintf("the issuer of %q, %q, is neither in the chain nor a root of the %s store", top.Subject.String(), top.Issuer.String(), store.Name)
+	if len(top.IssuingCertificateURL) > 0 {
+		msg += "; it can be downloaded from " + top.IssuingCertificateURL[0]
+	}
+	result.Err = errors.New(msg)
+	return result
+}
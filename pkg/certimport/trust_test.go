@@ -0,0 +1,82 @@
+package certimport
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestAmazonRoots(t *testing.T) {
+	roots, err := ParseCertificates(amazonRootsPEM, "Amazon roots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Amazon Root CA 1 to 4 and Starfield Services Root G2
+	if len(roots) != 5 {
+		t.Fatalf("got %d Amazon Trust Services roots, want 5", len(roots))
+	}
+	for _, root := range roots {
+		if !root.IsCA || !isSelfSigned(root) {
+			t.Errorf("%s is not a self-signed CA", root.Subject)
+		}
+	}
+}
+
+func TestVerifyTrust(t *testing.T) {
+	pki := newTestPKI(t)
+	root, intermediate, leaf := pki.root.cert, pki.intermediate.cert, pki.leaf.cert
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(root)
+	store := TrustStore{Name: "test", Roots: trusted}
+	empty := TrustStore{Name: "empty", Roots: x509.NewCertPool()}
+
+	tests := []struct {
+		name        string
+		chain       []*x509.Certificate
+		store       TrustStore
+		wantPathLen int
+		wantMissing *x509.Certificate
+		wantErr     bool
+	}{
+		{
+			name:        "complete",
+			chain:       []*x509.Certificate{intermediate},
+			store:       store,
+			wantPathLen: 3,
+		},
+		{
+			name:        "missing intermediate",
+			store:       store,
+			wantMissing: leaf,
+			wantErr:     true,
+		},
+		{
+			name:        "untrusted root",
+			chain:       []*x509.Certificate{intermediate},
+			store:       empty,
+			wantMissing: intermediate,
+			wantErr:     true,
+		},
+		{
+			name:    "root supplied but untrusted",
+			chain:   []*x509.Certificate{intermediate, root},
+			store:   empty,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := VerifyTrust(leaf, OrderChain(leaf, tt.chain), tt.store)
+			if (got.Err != nil) != tt.wantErr {
+				t.Fatalf("Err = %v, wantErr %t", got.Err, tt.wantErr)
+			}
+			if len(got.Path) != tt.wantPathLen {
+				t.Errorf("len(Path) = %d, want %d", len(got.Path), tt.wantPathLen)
+			}
+			if got.Missing != tt.wantMissing {
+				t.Errorf("Missing = %v, want %v", got.Missing, tt.wantMissing)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package certimport
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// ErrWeakKey is returned for keys known to be compromised or trivially
+// factorable. There is no option to import them.
+var ErrWeakKey = errors.New("private key is known to be weak")
+
+// rocaPrimes are the small primes whose residues identify RSA moduli
+// generated by the Infineon library vulnerable to ROCA (CVE-2017-15361).
+var rocaPrimes = []int64{3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41, 43, 47, 53, 59, 61, 67, 71, 73, 79, 83, 89, 97, 101, 103, 107, 109, 113, 127, 131, 137, 139, 149, 151, 157, 163, 167}
+
+// rocaResidues holds, for each of rocaPrimes, the residues in the subgroup
+// generated by 65537. Vulnerable moduli have all their residues in them.
+var rocaResidues = func() [][]bool {
+	residues := make([][]bool, len(rocaPrimes))
+	for i, p := range rocaPrimes {
+		residues[i] = make([]bool, p)
+		for r := int64(1); !residues[i][r]; r = r * 65537 % p {
+			residues[i][r] = true
+		}
+	}
+	return residues
+}()
+
+// fermatRounds bounds the search for factors close to the square root of a
+// modulus (CVE-2022-26320). Keys from sound generators are never found.
+const fermatRounds = 100
+
+// DebianBlocklist holds the fingerprints of RSA keys generated by Debian's
+// predictable OpenSSL (CVE-2008-0166), as listed by the openssl-blacklist
+// package: the last 20 hex digits of the SHA-1 of "Modulus=<HEX>\n".
+type DebianBlocklist map[string]bool
+
+// LoadDebianBlocklist reads a blocklist in the format of the files in
+// /usr/share/openssl-blacklist, adding to list. Lines starting with # are
+// comments.
+func LoadDebianBlocklist(list DebianBlocklist, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(line) != 20 {
+			return fmt.Errorf("invalid blocklist entry %q, expected 20 hex digits", line)
+		}
+		list[strings.ToLower(line)] = true
+	}
+	return scanner.Err()
+}
+
+// debianFingerprint returns the blocklist fingerprint of an RSA modulus.
+func debianFingerprint(n *big.Int) string {
+	sum := sha1.Sum([]byte("Modulus=" + strings.ToUpper(n.Text(16)) + "\n"))
+	return hex.EncodeToString(sum[:])[20:]
+}
+
+// CheckWeakKey rejects RSA keys that are vulnerable to ROCA, whose factors
+// are close enough to be found with Fermat's method, or that are listed in
+// blocklist. Other keys pass.
+func CheckWeakKey(pub crypto.PublicKey, blocklist DebianBlocklist) error {
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil
+	}
+	switch {
+	case isROCA(key.N):
+		return fmt.Errorf("%w: the RSA key was generated by an Infineon chip vulnerable to ROCA (CVE-2017-15361)", ErrWeakKey)
+	case fermatFactorable(key.N):
+		return fmt.Errorf("%w: the RSA key's primes are too close together and it can be factored (CVE-2022-26320)", ErrWeakKey)
+	case blocklist[debianFingerprint(key.N)]:
+		return fmt.Errorf("%w: the RSA key was generated by Debian's predictable OpenSSL (CVE-2008-0166)", ErrWeakKey)
+	}
+	return nil
+}
+
+func isROCA(n *big.Int) bool {
+	var r big.Int
+	for i, p := range rocaPrimes {
+		if !rocaResidues[i][r.Mod(n, big.NewInt(p)).Int64()] {
+			return false
+		}
+	}
+	return true
+}
+
+// fermatFactorable looks for a and b with n = a² - b², starting at the
+// square root of n.
+func fermatFactorable(n *big.Int) bool {
+	a := new(big.Int).Sqrt(n)
+	var b2, b big.Int
+	if b2.Mul(a, a).Cmp(n) == 0 {
+		return true
+	}
+	one := big.NewInt(1)
+	for range fermatRounds {
+		a.Add(a, one)
+		b2.Mul(a, a)
+		b2.Sub(&b2, n)
+		if b.Sqrt(&b2); b.Mul(&b, &b).Cmp(&b2) == 0 {
+			return true
+		}
+	}
+	return false
+}
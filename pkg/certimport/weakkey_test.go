@@ -0,0 +1,75 @@
+package certimport
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestCheckWeakKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Moduli of the vulnerable library are powers of 65537 modulo the
+	// product of the ROCA primes
+	m := big.NewInt(1)
+	for _, p := range rocaPrimes {
+		m.Mul(m, big.NewInt(p))
+	}
+	roca := new(big.Int).Exp(big.NewInt(65537), big.NewInt(123457), m)
+	roca.Add(roca, new(big.Int).Mul(m, big.NewInt(1e9)))
+
+	// Primes next to each other fall to Fermat's method at once
+	p, err := rand.Prime(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := new(big.Int).Add(p, big.NewInt(2))
+	for !q.ProbablyPrime(20) {
+		q.Add(q, big.NewInt(2))
+	}
+	fermat := new(big.Int).Mul(p, q)
+
+	blocked, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocklist := DebianBlocklist{}
+	entries := "# comment\n" + debianFingerprint(blocked.N) + "\n"
+	if err := LoadDebianBlocklist(blocklist, strings.NewReader(entries)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		n       *big.Int
+		wantErr bool
+	}{
+		{name: "sound key", n: key.N},
+		{name: "ROCA", n: roca, wantErr: true},
+		{name: "close primes", n: fermat, wantErr: true},
+		{name: "Debian weak key", n: blocked.N, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckWeakKey(&rsa.PublicKey{N: tt.n, E: 65537}, blocklist)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckWeakKey() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrWeakKey) {
+				t.Errorf("CheckWeakKey() error = %v, want ErrWeakKey", err)
+			}
+		})
+	}
+}
+
+func TestLoadDebianBlocklistInvalid(t *testing.T) {
+	if err := LoadDebianBlocklist(DebianBlocklist{}, strings.NewReader("not-a-fingerprint\n")); err == nil {
+		t.Error("LoadDebianBlocklist() accepted an invalid entry")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// expectAccount is the -expect-account flag of the commands that import.
+// Like the -role-arn flags it applies to every configuration loaded, so it
+// is kept globally.
+var expectAccount string
+
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// addPreflightFlags registers -expect-account.
+func addPreflightFlags(fs *flag.FlagSet) {
+	fs.Func("expect-account", "Abort before importing unless the AWS credentials belong to this account ID", func(value string) error {
+		if !accountIDPattern.MatchString(value) {
+			return fmt.Errorf("invalid account ID %q, expected 12 digits", value)
+		}
+		expectAccount = value
+		return nil
+	})
+}
+
+// preflight checks the AWS credentials before anything is imported and
+// prints the account and identity they belong to, so that importing into
+// the wrong environment is noticed, or refused with -expect-account. It
+// returns the account ID.
+func preflight(ctx context.Context, awsCfg aws.Config, logger certimport.Logger) (string, error) {
+	identity, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to verify AWS credentials: %w", err)
+	}
+	account := aws.ToString(identity.Account)
+	logger.Printf("✓ AWS credentials valid (account: %s, identity: %s)\n", account, aws.ToString(identity.Arn))
+
+	if expectAccount != "" && account != expectAccount {
+		return account, fmt.Errorf("the AWS credentials belong to account %s, not %s as given with -expect-account", account, expectAccount)
+	}
+	return account, nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+)
+
+type PruneConfig struct {
+	ExpiredDays    int
+	SupersededDays int
+	MinAgeDays     int
+	DomainGlob     string
+	DryRun         bool
+	Yes            bool
+	Regions        []string
+	AllRegions     bool
+	Output         string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+// prunedCertificate is a certificate selected by prune.
+type prunedCertificate struct {
+	Region string `json:"region"`
+	listedCertificate
+	Reason       string `json:"reason"`
+	SupersededBy string `json:"supersededBy,omitempty"`
+	Deleted      bool   `json:"deleted"`
+	Error        string `json:"error,omitempty"`
+}
+
+func runPrune(ctx context.Context, args []string) error {
+	var cfg PruneConfig
+	var regions string
+
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	fs.IntVar(&cfg.ExpiredDays, "expired-days", 0, "Delete expired certificates once they have been expired for this many days")
+	fs.IntVar(&cfg.SupersededDays, "superseded-days", 7, "Delete superseded certificates once their replacement has been imported for this many days")
+	fs.IntVar(&cfg.MinAgeDays, "min-age-days", 1, "Never delete certificates imported less than this many days ago")
+	fs.StringVar(&cfg.DomainGlob, "domain", "", "Only prune certificates whose domain or SANs match this glob, e.g. '*.example.com'")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "List the certificates that would be deleted without deleting them")
+	addYesFlag(fs, &cfg.Yes)
+	fs.StringVar(&regions, "regions", "", "Prune several regions, e.g. 'us-east-1,eu-west-1'")
+	fs.BoolVar(&cfg.AllRegions, "all-regions", false, "Prune every region enabled for the account")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s prune [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Delete imported certificates that are expired, or superseded by a later import for the\n")
+		fmt.Fprintf(os.Stderr, "same names with a later expiry. Certificates that are in use are never deleted.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s prune -dry-run\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s prune -domain '*.example.com' -expired-days 30 -superseded-days 14 -all-regions\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s prune -yes -quiet   # from cron\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	if cfg.ExpiredDays < 0 || cfg.SupersededDays < 0 || cfg.MinAgeDays < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -expired-days, -superseded-days and -min-age-days must not be negative\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fmt.Fprintf(os.Stderr, "Error: -output must be text or json\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Regions = cliutil.SplitList(regions)
+	if len(cfg.Regions) > 0 && cfg.AllRegions {
+		fmt.Fprintf(os.Stderr, "Error: -regions and -all-regions are mutually exclusive\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return pruneCertificates(ctx, cfg)
+}
+
+func pruneCertificates(ctx context.Context, cfg PruneConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	regions := cfg.Regions
+	if cfg.AllRegions {
+		var err error
+		regions, err = enabledRegions(ctx, cfg.Region, cfg.Profile)
+		if err != nil {
+			return err
+		}
+	}
+	if len(regions) == 0 {
+		regions = []string{cfg.Region}
+	}
+
+	pruned := []prunedCertificate{}
+	clients := map[string]*acm.Client{}
+
+	for _, region := range regions {
+		awsCfg, err := loadAWSConfig(ctx, region, cfg.Profile)
+		if err != nil {
+			return err
+		}
+		client := acm.NewFromConfig(awsCfg)
+		clients[awsCfg.Region] = client
+		progress.Printf("Checking certificates in %s...\n", awsCfg.Region)
+
+		certs, err := findCertificates(ctx, client, ListConfig{Type: "IMPORTED", DomainGlob: cfg.DomainGlob})
+		if err != nil {
+			return err
+		}
+		if err := completeSANs(ctx, client, certs); err != nil {
+			return err
+		}
+		for _, c := range selectPrunable(certs, cfg, time.Now()) {
+			c.Region = awsCfg.Region
+			pruned = append(pruned, c)
+		}
+	}
+
+	// Everything selected is confirmed at once, before anything is deleted
+	failed := 0
+	if !cfg.DryRun && len(pruned) > 0 {
+		items := make([]string, len(pruned))
+		for i, c := range pruned {
+			items[i] = deletionItem(c.CertificateArn, c.DomainName, c.NotAfter, nil) + ": " + c.Reason
+		}
+		if err := confirmDeletion(cfg.Yes, fmt.Sprintf("%d certificate(s)", len(pruned)), items); err != nil {
+			return err
+		}
+
+		for i := range pruned {
+			c := &pruned[i]
+			if err := deleteIfUnused(ctx, clients[c.Region], c.CertificateArn); err != nil {
+				progress.Warnf("%v", err)
+				c.Error = err.Error()
+				failed++
+			} else {
+				c.Deleted = true
+			}
+		}
+	}
+
+	if err := printPruned(cfg, pruned); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d certificates could not be deleted", failed, len(pruned))
+	}
+	return nil
+}
+
+// selectPrunable returns the certificates that are not in use, were imported
+// at least -min-age-days ago, and are either expired for -expired-days or
+// superseded by a certificate for the same names with a later expiry that
+// was imported at least -superseded-days ago.
+func selectPrunable(certs []listedCertificate, cfg PruneConfig, now time.Time) []prunedCertificate {
+	// The newest certificate for each set of names supersedes the others
+	newest := map[string]listedCertificate{}
+	for _, c := range certs {
+		if c.NotAfter == nil || c.TruncatedSANs {
+			continue
+		}
+		key := certificateNames(c)
+		if n, ok := newest[key]; !ok || c.NotAfter.After(*n.NotAfter) {
+			newest[key] = c
+		}
+	}
+
+	minImported := now.AddDate(0, 0, -cfg.MinAgeDays)
+	var selected []prunedCertificate
+	for _, c := range certs {
+		if c.InUse || c.NotAfter == nil {
+			continue
+		}
+		if c.ImportedAt != nil && c.ImportedAt.After(minImported) {
+			continue
+		}
+
+		if c.NotAfter.Before(now.AddDate(0, 0, -cfg.ExpiredDays)) {
+			selected = append(selected, prunedCertificate{
+				listedCertificate: c,
+				Reason:            fmt.Sprintf("expired %s", c.NotAfter.Format("2006-01-02")),
+			})
+			continue
+		}
+
+		// Without all of its names, nothing is known to replace it
+		if c.TruncatedSANs {
+			continue
+		}
+		n := newest[certificateNames(c)]
+		if n.CertificateArn == c.CertificateArn || !n.NotAfter.After(*c.NotAfter) {
+			continue
+		}
+		if n.ImportedAt != nil && n.ImportedAt.After(now.AddDate(0, 0, -cfg.SupersededDays)) {
+			continue
+		}
+		selected = append(selected, prunedCertificate{
+			listedCertificate: c,
+			Reason:            fmt.Sprintf("superseded (expires %s)", c.NotAfter.Format("2006-01-02")),
+			SupersededBy:      n.CertificateArn,
+		})
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].NotAfter.Before(*selected[j].NotAfter)
+	})
+	return selected
+}
+
+// completeSANs describes the certificates whose SANs ListCertificates
+// truncated, so that certificates are only grouped by all of their names.
+func completeSANs(ctx context.Context, client *acm.Client, certs []listedCertificate) error {
+	for i := range certs {
+		if !certs[i].TruncatedSANs {
+			continue
+		}
+		detail, err := describeCertificate(ctx, client, certs[i].CertificateArn)
+		if err != nil {
+			return err
+		}
+		certs[i].SANs = detail.SubjectAlternativeNames
+		certs[i].TruncatedSANs = false
+	}
+	return nil
+}
+
+// certificateNames identifies the names a certificate covers, independent
+// of the order ACM lists its SANs in.
+func certificateNames(c listedCertificate) string {
+	names := slices.Clone(c.SANs)
+	for i := range names {
+		names[i] = strings.ToLower(names[i])
+	}
+	sort.Strings(names)
+	return strings.ToLower(c.DomainName) + "|" + strings.Join(names, ",")
+}
+
+// deleteIfUnused deletes a certificate after checking that nothing started
+// using it since it was listed.
+func deleteIfUnused(ctx context.Context, client *acm.Client, certificateArn string) error {
+	detail, err := describeCertificate(ctx, client, certificateArn)
+	if err != nil {
+		return err
+	}
+	if len(detail.InUseBy) > 0 {
+		return fmt.Errorf("not deleting %s, it is in use by %s", certificateArn, strings.Join(detail.InUseBy, ", "))
+	}
+
+	_, err = client.DeleteCertificate(ctx, &acm.DeleteCertificateInput{CertificateArn: aws.String(certificateArn)})
+	if err != nil {
+		return fmt.Errorf("failed to delete certificate %s: %w", certificateArn, err)
+	}
+	progress.Printf("✓ Deleted %s\n", certificateArn)
+	return nil
+}
+
+func printPruned(cfg PruneConfig, pruned []prunedCertificate) error {
+	if cfg.Output == "json" {
+		return printJSON(pruned)
+	}
+
+	// Quiet mode prints one ARN per line for use in shell pipelines
+	if progress.Quiet() {
+		for _, c := range pruned {
+			if c.Deleted || cfg.DryRun {
+				fmt.Println(c.CertificateArn)
+			}
+		}
+		return nil
+	}
+
+	if len(pruned) == 0 {
+		stdout.Printf("✅ Nothing to prune\n")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "REGION\tDOMAIN\tREASON\tDELETED\tARN\n")
+	for _, c := range pruned {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", c.Region, c.DomainName, c.Reason, c.Deleted, c.CertificateArn)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if cfg.DryRun {
+		progress.Printf("✅ Dry run complete, no changes made\n")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelectPrunable(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time {
+		t := now.AddDate(0, 0, days)
+		return &t
+	}
+	cert := func(arn string, notAfter int, sans ...string) listedCertificate {
+		return listedCertificate{CertificateArn: arn, DomainName: "example.com", SANs: sans, NotAfter: at(notAfter), ImportedAt: at(-60)}
+	}
+	truncated := func(c listedCertificate) listedCertificate {
+		c.TruncatedSANs = true
+		return c
+	}
+	cfg := PruneConfig{SupersededDays: 7, MinAgeDays: 1}
+
+	tests := []struct {
+		name  string
+		certs []listedCertificate
+		want  map[string]string
+	}{
+		{
+			name: "superseded by the same names",
+			certs: []listedCertificate{
+				cert("old", 10, "example.com", "www.example.com"),
+				cert("new", 300, "www.example.com", "example.com"),
+			},
+			want: map[string]string{"old": "new"},
+		},
+		{
+			name: "other names",
+			certs: []listedCertificate{
+				cert("old", 10, "example.com", "www.example.com"),
+				cert("new", 300, "example.com", "api.example.com"),
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "truncated SANs of the older certificate",
+			certs: []listedCertificate{
+				truncated(cert("old", 10, "example.com", "www.example.com")),
+				cert("new", 300, "example.com", "www.example.com"),
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "truncated SANs of the newer certificate",
+			certs: []listedCertificate{
+				cert("old", 10, "example.com", "www.example.com"),
+				truncated(cert("new", 300, "example.com", "www.example.com")),
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "expired with truncated SANs",
+			certs: []listedCertificate{
+				truncated(cert("expired", -5, "example.com", "www.example.com")),
+			},
+			want: map[string]string{"expired": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectPrunable(tt.certs, cfg, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("selectPrunable() selected %d certificates, want %d", len(got), len(tt.want))
+			}
+			for _, c := range got {
+				supersededBy, ok := tt.want[c.CertificateArn]
+				if !ok {
+					t.Errorf("selectPrunable() selected %s", c.CertificateArn)
+				} else if c.SupersededBy != supersededBy {
+					t.Errorf("%s: SupersededBy = %q, want %q", c.CertificateArn, c.SupersededBy, supersededBy)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// publishTarget is where -publish-arn writes the certificate ARN: an SSM
+// parameter (ssm:/path/to/param) or a Secrets Manager secret
+// (secretsmanager:name).
+type publishTarget struct {
+	Parameter string
+	Secret    string
+}
+
+func (t publishTarget) String() string {
+	if t.Secret != "" {
+		return "secretsmanager:" + t.Secret
+	}
+	return "ssm:" + t.Parameter
+}
+
+func parsePublishTargets(value string) ([]publishTarget, error) {
+	var targets []publishTarget
+	for _, item := range cliutil.SplitList(value) {
+		switch {
+		case strings.HasPrefix(item, "ssm:"):
+			name := strings.TrimPrefix(strings.TrimPrefix(item, "ssm:"), "//")
+			if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") {
+				name = "/" + name
+			}
+			if name == "" || name == "/" {
+				return nil, fmt.Errorf("invalid -publish-arn target %q: missing parameter name", item)
+			}
+			targets = append(targets, publishTarget{Parameter: name})
+		case strings.HasPrefix(item, "secretsmanager:"):
+			name := strings.TrimPrefix(strings.TrimPrefix(item, "secretsmanager:"), "//")
+			if name == "" {
+				return nil, fmt.Errorf("invalid -publish-arn target %q: missing secret name", item)
+			}
+			targets = append(targets, publishTarget{Secret: name})
+		default:
+			return nil, fmt.Errorf("invalid -publish-arn target %q, expected ssm:/path or secretsmanager:name", item)
+		}
+	}
+	return targets, nil
+}
+
+// publishArns writes the ARN imported into each region to the targets in
+// that region, so that consumers can look up the local certificate.
+func publishArns(ctx context.Context, awsCfg aws.Config, logger certimport.Logger, targets []publishTarget, result *certimport.Result) error {
+	for _, r := range result.Regions {
+		if r.Err != nil || r.CertificateArn == "" {
+			continue
+		}
+		cfg := awsCfg.Copy()
+		cfg.Region = r.Region
+
+		for _, target := range targets {
+			if err := publishArn(ctx, cfg, target, r.CertificateArn); err != nil {
+				return fmt.Errorf("certificate imported as %s but failed to publish the ARN to %s: %w", r.CertificateArn, target, err)
+			}
+			logger.Printf("✓ ARN published to %s (region: %s)\n", target, r.Region)
+		}
+	}
+	return nil
+}
+
+func publishArn(ctx context.Context, awsCfg aws.Config, target publishTarget, certificateArn string) error {
+	if target.Parameter != "" {
+		_, err := ssm.NewFromConfig(awsCfg).PutParameter(ctx, &ssm.PutParameterInput{
+			Name:        aws.String(target.Parameter),
+			Value:       aws.String(certificateArn),
+			Type:        ssmtypes.ParameterTypeString,
+			Overwrite:   aws.Bool(true),
+			Description: aws.String("ACM certificate ARN published by aws-certs"),
+		})
+		return err
+	}
+
+	// Update the secret if it exists, otherwise create it
+	client := secretsmanager.NewFromConfig(awsCfg)
+	_, err := client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(target.Secret),
+		SecretString: aws.String(certificateArn),
+	})
+	var notFound *smtypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		_, err = client.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+			Name:         aws.String(target.Secret),
+			SecretString: aws.String(certificateArn),
+			Description:  aws.String("ACM certificate ARN published by aws-certs"),
+		})
+	}
+	return err
+}
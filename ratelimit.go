@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// defaultImportRPS is ACM's default quota for ImportCertificate requests per
+// account and region.
+const defaultImportRPS = 1
+
+// rateOptions paces ImportCertificate requests. Like retryOptions it applies
+// to every client.
+var rateOptions = struct {
+	importRPS float64
+}{importRPS: defaultImportRPS}
+
+// addRateFlags registers -rps for the commands that import many
+// certificates.
+func addRateFlags(fs *flag.FlagSet) {
+	fs.Func("rps", fmt.Sprintf("ImportCertificate requests per second in each region, shared by all workers (default %d, ACM's quota; 0 disables)", defaultImportRPS), func(value string) error {
+		rps, err := strconv.ParseFloat(value, 64)
+		if err != nil || rps < 0 || math.IsInf(rps, 0) {
+			return fmt.Errorf("invalid rate %q, expected a number of requests per second", value)
+		}
+		rateOptions.importRPS = rps
+		return nil
+	})
+}
+
+// tokenBucket allows rate events per second on average, and bursts of up to
+// burst events.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := math.Max(1, math.Floor(rate))
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait takes a token, sleeping until it is due. Waiters reserve tokens in
+// the order they arrive, so the bucket can go negative.
+func (b *tokenBucket) wait(ctx context.Context) (time.Duration, error) {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+// importLimiter paces ImportCertificate requests with one token bucket per
+// region, since ACM's quotas are per account and region. It is added to the
+// APIOptions of an AWS config, which clients for other regions copy, so every
+// import made with the config shares the buckets.
+type importLimiter struct {
+	rps     float64
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+const importLimiterID = "ImportRateLimit"
+
+func newImportLimiter(rps float64) *importLimiter {
+	return &importLimiter{rps: rps, buckets: map[string]*tokenBucket{}}
+}
+
+func (l *importLimiter) bucket(region string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[region]
+	if !ok {
+		b = newTokenBucket(l.rps)
+		l.buckets[region] = b
+	}
+	return b
+}
+
+// limitImports gives awsCfg a limiter of its own. A limiter added later
+// replaces the earlier one, so a copy of a config for another account stops
+// sharing the original's buckets.
+func limitImports(awsCfg *aws.Config) {
+	if rateOptions.importRPS > 0 {
+		awsCfg.APIOptions = append(awsCfg.APIOptions, newImportLimiter(rateOptions.importRPS).addTo)
+	}
+}
+
+// addTo inserts the limiter after the retryer, so that retried attempts
+// are paced too.
+func (l *importLimiter) addTo(stack *middleware.Stack) error {
+	stack.Finalize.Remove(importLimiterID)
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc(importLimiterID, func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		if awsmiddleware.GetServiceID(ctx) == "ACM" && awsmiddleware.GetOperationName(ctx) == "ImportCertificate" {
+			region := awsmiddleware.GetRegion(ctx)
+			delay, err := l.bucket(region).wait(ctx)
+			if err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+			if delay > 0 {
+				progress.Debugf("Waited %s for the ImportCertificate rate limit in %s\n", delay.Round(time.Millisecond), region)
+			}
+		}
+		return next.HandleFinalize(ctx, in)
+	}), "Retry", middleware.After)
+}
+
+// rateNote describes the import rate limit for progress messages.
+func rateNote() string {
+	if rateOptions.importRPS == 0 {
+		return "no rate limit"
+	}
+	return fmt.Sprintf("%g imports/s per region", rateOptions.importRPS)
+}
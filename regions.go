@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// enabledRegions returns the regions enabled for the account, as reported by
+// EC2 DescribeRegions using the given base region and profile.
+func enabledRegions(ctx context.Context, region, profile string) ([]string, error) {
+	awsCfg, err := loadAWSConfig(ctx, region, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ec2.NewFromConfig(awsCfg).DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list enabled regions: %w", err)
+	}
+
+	var regions []string
+	for _, r := range result.Regions {
+		regions = append(regions, aws.ToString(r.RegionName))
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
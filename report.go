@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/inventory"
+)
+
+type ReportConfig struct {
+	Regions     []string
+	AllRegions  bool
+	TargetsFile string
+	Format      string
+	OutFile     string
+	Region      string
+	Profile     string
+	Timeout     time.Duration
+}
+
+// reportRow is one certificate in the inventory report.
+type reportRow struct {
+	Account         string            `json:"account"`
+	Target          string            `json:"target,omitempty"`
+	Region          string            `json:"region"`
+	CertificateArn  string            `json:"certificateArn"`
+	DomainName      string            `json:"domainName"`
+	SANs            []string          `json:"subjectAlternativeNames,omitempty"`
+	Type            string            `json:"type"`
+	Status          string            `json:"status"`
+	KeyAlgorithm    string            `json:"keyAlgorithm"`
+	NotAfter        *time.Time        `json:"notAfter,omitempty"`
+	DaysUntilExpiry *int              `json:"daysUntilExpiry,omitempty"`
+	InUseBy         []string          `json:"inUseBy"`
+	Tags            map[string]string `json:"tags"`
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":  func(items []string) string { return strings.Join(items, ", ") },
+	"tags":  formatTags,
+	"deref": func(n *int) int { return *n },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ACM certificate report</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.expiring { background: #fff3cd; }
+.expired { background: #f8d7da; }
+</style>
+</head>
+<body>
+<h1>ACM certificate report</h1>
+<p>Generated {{.Generated}}: {{len .Rows}} certificates.</p>
+<table>
+<tr><th>Account</th><th>Region</th><th>Domain</th><th>SANs</th><th>Type</th><th>Status</th><th>Key</th><th>Expires</th><th>Days</th><th>In use by</th><th>Tags</th><th>ARN</th></tr>
+{{range .Rows}}<tr{{if .DaysUntilExpiry}}{{if lt (deref .DaysUntilExpiry) 0}} class="expired"{{else if lt (deref .DaysUntilExpiry) 30}} class="expiring"{{end}}{{end}}>
+<td>{{.Account}}</td><td>{{.Region}}</td><td>{{.DomainName}}</td><td>{{join .SANs}}</td><td>{{.Type}}</td><td>{{.Status}}</td><td>{{.KeyAlgorithm}}</td>
+<td>{{if .NotAfter}}{{.NotAfter.Format "2006-01-02"}}{{end}}</td><td>{{if .DaysUntilExpiry}}{{deref .DaysUntilExpiry}}{{end}}</td>
+<td>{{join .InUseBy}}</td><td>{{tags .Tags}}</td><td>{{.CertificateArn}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func runReport(ctx context.Context, args []string) error {
+	var cfg ReportConfig
+	var regions string
+
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	fs.StringVar(&regions, "regions", "", "Report on several regions, e.g. 'us-east-1,eu-west-1'")
+	fs.BoolVar(&cfg.AllRegions, "all-regions", false, "Report on every region enabled for the account")
+	fs.StringVar(&cfg.TargetsFile, "targets", "", "YAML or JSON file listing accounts (profile and/or roleArn) and regions to report on")
+	fs.StringVar(&cfg.Format, "format", "csv", "Report format: csv, json or html")
+	fs.StringVar(&cfg.OutFile, "out", "", "Write the report to this file instead of stdout")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s report [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Inventory every certificate (imported and ACM-issued) across regions and accounts, with\n")
+		fmt.Fprintf(os.Stderr, "expiry, usage, key algorithm and tags, for security and compliance reviews.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s report -all-regions -out certificates.csv\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s report -targets accounts.yaml -format html -out report.html\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Regions = cliutil.SplitList(regions)
+	if len(cfg.Regions) > 0 && cfg.AllRegions {
+		fail(errors.New("-regions and -all-regions are mutually exclusive"))
+	}
+	if cfg.Format != "csv" && cfg.Format != "json" && cfg.Format != "html" {
+		fail(errors.New("-format must be csv, json or html"))
+	}
+
+	return reportCertificates(ctx, cfg)
+}
+
+func reportCertificates(ctx context.Context, cfg ReportConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	targets := []importTarget{{}}
+	if cfg.TargetsFile != "" {
+		var err error
+		if targets, err = loadTargets(cfg.TargetsFile); err != nil {
+			return err
+		}
+		for _, target := range targets {
+			if target.Profile != "" && assumeRole.roleArn != "" {
+				return fmt.Errorf("-role-arn cannot be used with targets that name a profile (target %s)", target.Name)
+			}
+		}
+	}
+
+	base, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+
+	rows := []reportRow{}
+	for _, target := range targets {
+		targetRows, err := reportTarget(ctx, base, cfg, target)
+		if err != nil {
+			if target.Name == "" {
+				return err
+			}
+			return fmt.Errorf("target %s: %w", target.Name, err)
+		}
+		rows = append(rows, targetRows...)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].NotAfter == nil || rows[j].NotAfter == nil {
+			return rows[j].NotAfter == nil && rows[i].NotAfter != nil
+		}
+		return rows[i].NotAfter.Before(*rows[j].NotAfter)
+	})
+
+	w := io.Writer(os.Stdout)
+	if cfg.OutFile != "" {
+		f, err := os.Create(cfg.OutFile)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeReport(w, cfg.Format, rows); err != nil {
+		return err
+	}
+	if cfg.OutFile != "" {
+		progress.Printf("✓ Report with %d certificates written to %s\n", len(rows), cfg.OutFile)
+	}
+	return nil
+}
+
+// reportTarget lists the certificates of one account in its regions.
+func reportTarget(ctx context.Context, base aws.Config, cfg ReportConfig, target importTarget) ([]reportRow, error) {
+	awsCfg, err := targetConfig(ctx, base, cfg.Region, target)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify AWS credentials: %w", err)
+	}
+	account := aws.ToString(identity.Account)
+
+	regions := target.Regions
+	if len(regions) == 0 {
+		regions = cfg.Regions
+	}
+	if len(regions) == 0 && cfg.AllRegions {
+		if regions, err = enabledRegions(ctx, awsCfg.Region, target.Profile); err != nil {
+			return nil, err
+		}
+	}
+	if len(regions) == 0 {
+		regions = []string{awsCfg.Region}
+	}
+
+	var rows []reportRow
+	for _, region := range regions {
+		regionCfg := awsCfg.Copy()
+		regionCfg.Region = region
+		client := acm.NewFromConfig(regionCfg)
+		progress.Printf("Listing certificates in %s (%s)...\n", account, region)
+
+		certs, err := findCertificates(ctx, client, ListConfig{})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range certs {
+			row := reportRow{
+				Account:        account,
+				Target:         target.Name,
+				Region:         region,
+				CertificateArn: c.CertificateArn,
+				DomainName:     c.DomainName,
+				SANs:           c.SANs,
+				Type:           c.Type,
+				Status:         c.Status,
+				KeyAlgorithm:   c.KeyAlgorithm,
+				NotAfter:       c.NotAfter,
+				InUseBy:        []string{},
+			}
+			if c.NotAfter != nil {
+				days := daysUntil(*c.NotAfter)
+				row.DaysUntilExpiry = &days
+			}
+			if c.InUse {
+				detail, err := describeCertificate(ctx, client, c.CertificateArn)
+				if err != nil {
+					return nil, err
+				}
+				row.InUseBy = detail.InUseBy
+			}
+			if row.Tags, err = inventory.Tags(ctx, client, c.CertificateArn); err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func writeReport(w io.Writer, format string, rows []reportRow) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "html":
+		return reportHTML.Execute(w, struct {
+			Generated string
+			Rows      []reportRow
+		}{time.Now().UTC().Format(time.RFC3339), rows})
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"account", "region", "certificate_arn", "domain_name", "sans", "type", "status", "key_algorithm", "not_after", "days_until_expiry", "in_use_by", "tags"})
+	for _, r := range rows {
+		var notAfter, days string
+		if r.NotAfter != nil {
+			notAfter = r.NotAfter.UTC().Format(time.RFC3339)
+			days = strconv.Itoa(*r.DaysUntilExpiry)
+		}
+		cw.Write([]string{r.Account, r.Region, r.CertificateArn, r.DomainName, strings.Join(r.SANs, " "), r.Type, r.Status,
+			r.KeyAlgorithm, notAfter, days, strings.Join(r.InUseBy, " "), formatTags(r.Tags)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatTags formats tags as sorted 'key=value' pairs.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
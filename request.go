@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// validationRecordTimeout bounds the wait for ACM to publish the DNS
+// validation records of a new certificate, which takes a few seconds.
+const validationRecordTimeout = 2 * time.Minute
+
+type RequestConfig struct {
+	Domains      []string
+	KeyAlgorithm string
+	HostedZoneID string
+	NoWait       bool
+	Tags         map[string]string
+	PublishArn   []publishTarget
+	Output       string
+	Region       string
+	Profile      string
+	Timeout      time.Duration
+}
+
+// requestResult is the JSON representation of a requested certificate.
+type requestResult struct {
+	CertificateArn string   `json:"certificateArn"`
+	DomainName     string   `json:"domainName"`
+	SANs           []string `json:"subjectAlternativeNames,omitempty"`
+	Status         string   `json:"status"`
+	Region         string   `json:"region"`
+}
+
+func runRequest(ctx context.Context, args []string) error {
+	var cfg RequestConfig
+	var domains, tagString, tagsFile, publishArn string
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("request", flag.ExitOnError)
+	fs.StringVar(&domains, "domains", "", "Names to include in the certificate, the first being the domain name, e.g. 'example.com,*.example.com' - REQUIRED")
+	fs.StringVar(&cfg.KeyAlgorithm, "key-algorithm", string(types.KeyAlgorithmRsa2048), "Key algorithm: RSA_2048, EC_prime256v1 or EC_secp384r1")
+	fs.StringVar(&cfg.HostedZoneID, "hosted-zone-id", "", "Route 53 hosted zone for the validation records (default: the closest public zone of each name)")
+	fs.BoolVar(&cfg.NoWait, "no-wait", false, "Print the ARN once the validation records exist instead of waiting for ISSUED")
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.StringVar(&tagsFile, "tags-file", "", "JSON or YAML file with a map of tag keys to values")
+	fs.StringVar(&publishArn, "publish-arn", "", "Write the ARN to 'ssm:/path/to/param' or 'secretsmanager:name' (comma-separated for several)")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s request -domains NAMES [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Request an ACM-issued certificate validated by DNS. The CNAME validation records are\n")
+		fmt.Fprintf(os.Stderr, "created in Route 53 and kept, so ACM can renew the certificate automatically.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s request -domains example.com,www.example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s request -domains '*.example.com' -region us-east-1 -tag Environment=prod -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Domains = cliutil.SplitList(domains)
+	if len(cfg.Domains) == 0 {
+		fail(errors.New("-domains is required"))
+	}
+	switch types.KeyAlgorithm(cfg.KeyAlgorithm) {
+	case types.KeyAlgorithmRsa2048, types.KeyAlgorithmEcPrime256v1, types.KeyAlgorithmEcSecp384r1:
+	default:
+		fail(fmt.Errorf("-key-algorithm must be RSA_2048, EC_prime256v1 or EC_secp384r1"))
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fail(errors.New("-output must be text or json"))
+	}
+
+	var err error
+	cfg.PublishArn, err = parsePublishTargets(publishArn)
+	if err != nil {
+		fail(err)
+	}
+
+	var fileTags, inlineTags map[string]string
+	if tagsFile != "" {
+		fileTags, err = loadTagsFile(tagsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Tags = mergeTags(fileTags, inlineTags, tagList)
+	if err := certimport.ValidateTags(cfg.Tags); err != nil {
+		fail(err)
+	}
+
+	return requestCertificate(ctx, cfg)
+}
+
+func requestCertificate(ctx context.Context, cfg RequestConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	client := acm.NewFromConfig(awsCfg)
+
+	input := &acm.RequestCertificateInput{
+		DomainName:       aws.String(cfg.Domains[0]),
+		ValidationMethod: types.ValidationMethodDns,
+		KeyAlgorithm:     types.KeyAlgorithm(cfg.KeyAlgorithm),
+	}
+	if len(cfg.Domains) > 1 {
+		input.SubjectAlternativeNames = cfg.Domains
+	}
+	for _, key := range sortedKeys(cfg.Tags) {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(cfg.Tags[key])})
+	}
+
+	out, err := client.RequestCertificate(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to request certificate: %w", err)
+	}
+	arn := aws.ToString(out.CertificateArn)
+	progress.Printf("✓ Certificate requested: %s\n", arn)
+
+	records, err := validationRecords(ctx, client, arn)
+	if err != nil {
+		return err
+	}
+
+	r53 := route53.NewFromConfig(awsCfg)
+	progress.Printf("Creating %d validation records in Route 53...\n", len(records))
+	if err := changeRecords(ctx, r53, newZoneFinder(r53, cfg.HostedZoneID), r53types.ChangeActionUpsert, records); err != nil {
+		return fmt.Errorf("certificate %s requested but validation records could not be created: %w", arn, err)
+	}
+	progress.Printf("✓ Validation records in sync\n")
+
+	if !cfg.NoWait {
+		progress.Printf("Waiting for ACM to issue the certificate...\n")
+		waiter := acm.NewCertificateValidatedWaiter(client)
+		// The waiter needs a limit of its own; the command timeout still applies
+		maxWait := 24 * time.Hour
+		if deadline, ok := ctx.Deadline(); ok {
+			maxWait = time.Until(deadline)
+		}
+		if err := waiter.Wait(ctx, &acm.DescribeCertificateInput{CertificateArn: aws.String(arn)}, maxWait); err != nil {
+			return fmt.Errorf("certificate %s was not issued: %w", arn, err)
+		}
+		progress.Printf("✓ Certificate issued\n")
+	}
+
+	for _, target := range cfg.PublishArn {
+		if err := publishArn(ctx, awsCfg, target, arn); err != nil {
+			return fmt.Errorf("certificate requested as %s but failed to publish the ARN to %s: %w", arn, target, err)
+		}
+		progress.Printf("✓ ARN published to %s (region: %s)\n", target, awsCfg.Region)
+	}
+
+	detail, err := describeCertificate(ctx, client, arn)
+	if err != nil {
+		return err
+	}
+	result := requestResult{
+		CertificateArn: arn,
+		DomainName:     aws.ToString(detail.DomainName),
+		SANs:           detail.SubjectAlternativeNames,
+		Status:         string(detail.Status),
+		Region:         awsCfg.Region,
+	}
+
+	if cfg.Output == "json" {
+		return printJSON(result)
+	}
+	if progress.Quiet() {
+		fmt.Println(arn)
+		return nil
+	}
+	if cfg.NoWait {
+		stdout.Printf("✅ Certificate requested, validation pending\n")
+	} else {
+		stdout.Printf("✅ Certificate issued successfully!\n")
+	}
+	fmt.Printf("Certificate ARN: %s\n", arn)
+	fmt.Printf("Status: %s\n", result.Status)
+	return nil
+}
+
+// validationRecords polls the new certificate until ACM has published the
+// CNAME record for each name. Names sharing a record (e.g. a wildcard and its
+// base name) yield a single record.
+func validationRecords(ctx context.Context, client *acm.Client, arn string) ([]dnsRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, validationRecordTimeout)
+	defer cancel()
+
+	for {
+		detail, err := describeCertificate(ctx, client, arn)
+		if err != nil {
+			return nil, err
+		}
+
+		var records []dnsRecord
+		seen := make(map[string]bool)
+		complete := len(detail.DomainValidationOptions) > 0
+		for _, option := range detail.DomainValidationOptions {
+			rr := option.ResourceRecord
+			if rr == nil {
+				complete = false
+				break
+			}
+			if name := aws.ToString(rr.Name); !seen[name] {
+				seen[name] = true
+				records = append(records, dnsRecord{Name: name, Type: r53types.RRType(rr.Type), Values: []string{aws.ToString(rr.Value)}})
+			}
+		}
+		if complete {
+			return records, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("ACM did not publish the validation records for %s: %w", arn, ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
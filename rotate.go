@@ -0,0 +1,520 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// unreferencedTimeout bounds the wait for ACM to drop the resources that
+// stopped using the old certificate from its InUseBy list.
+const unreferencedTimeout = 5 * time.Minute
+
+type RotateConfig struct {
+	Import        CertImportConfig
+	OldArn        string
+	DetectRegions bool
+	DeleteOld     bool
+	Yes           bool
+}
+
+// rotateResult is the JSON representation of a rotation.
+type rotateResult struct {
+	Region            string   `json:"region"`
+	OldCertificateArn string   `json:"oldCertificateArn"`
+	NewCertificateArn string   `json:"newCertificateArn,omitempty"`
+	Updated           []string `json:"updated"`
+	Skipped           []string `json:"skipped"`
+	Deleted           bool     `json:"deleted"`
+	DryRun            bool     `json:"dryRun,omitempty"`
+	Error             string   `json:"error,omitempty"`
+	Warnings          []string `json:"warnings"`
+}
+
+func runRotate(ctx context.Context, args []string) error {
+	var cfg RotateConfig
+	var tagString, tagsFile string
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	addInputFlags(fs, &cfg.Import)
+	fs.StringVar(&cfg.OldArn, "old-arn", "", "ARN of the certificate being replaced - REQUIRED")
+	fs.BoolVar(&cfg.DetectRegions, "detect-regions", false, "Also rotate the certificates for the same names that resources use in the other enabled regions, found by scanning each of them")
+	fs.BoolVar(&cfg.DeleteOld, "delete-old", false, "Delete the old certificate once nothing uses it any more")
+	addYesFlag(fs, &cfg.Yes)
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.StringVar(&tagsFile, "tags-file", "", "JSON or YAML file with a map of tag keys to values")
+	fs.BoolVar(&cfg.Import.AutoTags, "auto-tags", false, "Add tags describing the certificate and the import (Domain, Issuer, NotAfter, ImportedBy, ...)")
+	fs.BoolVar(&cfg.Import.AllowExpired, "allow-expired", false, "Import certificates that are expired or not yet valid")
+	fs.BoolVar(&cfg.Import.DryRun, "dry-run", false, "Show the resources that would be updated without changing anything")
+	fs.StringVar(&cfg.Import.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Import.Region, &cfg.Import.Profile, &cfg.Import.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s rotate -old-arn <arn> -cert <file> -key <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Import a new certificate next to an old one, move every load balancer, CloudFront\n")
+		fmt.Fprintf(os.Stderr, "distribution and API Gateway custom domain using the old certificate over to it, and\n")
+		fmt.Fprintf(os.Stderr, "optionally delete the old certificate. The region is taken from -old-arn.\n\n")
+		fmt.Fprintf(os.Stderr, "With -detect-regions, the other enabled regions are scanned for certificates for the\n")
+		fmt.Fprintf(os.Stderr, "same names in use by those resources, e.g. a copy in us-east-1 for CloudFront, and\n")
+		fmt.Fprintf(os.Stderr, "the new certificate is imported into each of their regions and replaces them too.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s rotate -old-arn arn:aws:acm:us-east-1:123456789012:certificate/abc -cert cert.pem -key key.pem -chain chain.pem -dry-run\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s rotate -old-arn arn:aws:acm:us-east-1:123456789012:certificate/abc -bundle new.pem -delete-old -yes\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s rotate -old-arn arn:aws:acm:eu-west-1:123456789012:certificate/abc -bundle new.pem -detect-regions -dry-run\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if cfg.OldArn == "" {
+		fail(errors.New("-old-arn is required"))
+	}
+	region, err := arnRegion(cfg.OldArn)
+	if err != nil {
+		fail(fmt.Errorf("-old-arn: %w", err))
+	}
+	if cfg.Import.Region != "" && cfg.Import.Region != region {
+		fail(fmt.Errorf("-region %s does not match the region of -old-arn (%s)", cfg.Import.Region, region))
+	}
+	cfg.Import.Region = region
+
+	var fileTags, inlineTags map[string]string
+	if tagsFile != "" {
+		fileTags, err = loadTagsFile(tagsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Import.Tags = mergeTags(fileTags, inlineTags, tagList)
+
+	if err := cfg.Import.validate(); err != nil {
+		fail(err)
+	}
+
+	return rotateCertificate(ctx, cfg)
+}
+
+// rotationTarget is a certificate to replace with the new one.
+type rotationTarget struct {
+	Region string
+	OldArn string
+	Detail *types.CertificateDetail
+}
+
+func rotateCertificate(ctx context.Context, cfg RotateConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Import.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Import.Region, cfg.Import.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := preflight(ctx, awsCfg, progress); err != nil {
+		return err
+	}
+
+	old, err := describeCertificate(ctx, acm.NewFromConfig(awsCfg), cfg.OldArn)
+	if err != nil {
+		return err
+	}
+	progress.Printf("✓ %s is used by %d resource(s)\n", cfg.OldArn, len(old.InUseBy))
+
+	targets := []rotationTarget{{Region: awsCfg.Region, OldArn: cfg.OldArn, Detail: old}}
+	if cfg.DetectRegions {
+		others, err := detectRotationTargets(ctx, cfg, old)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, others...)
+	}
+
+	// Confirmed before importing, so that declining changes nothing
+	if cfg.DeleteOld && !cfg.Import.DryRun {
+		items := make([]string, len(targets))
+		for i, t := range targets {
+			items[i] = deletionItem(t.OldArn, aws.ToString(t.Detail.DomainName), t.Detail.NotAfter, t.Detail.InUseBy)
+		}
+		what := "the old certificate once its resources use the new one"
+		if len(targets) > 1 {
+			what = fmt.Sprintf("%d old certificates once their resources use the new one", len(targets))
+		}
+		if err := confirmDeletion(cfg.Yes, what, items); err != nil {
+			return err
+		}
+	}
+
+	progress.Printf("Reading certificate files...\n")
+	material, err := loadMaterial(ctx, cfg.Import, awsCfg, progress)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) == 1 {
+		result, err := rotateTarget(ctx, awsCfg, cfg, targets[0], material)
+		if err != nil {
+			return err
+		}
+		return printRotateResult(cfg.Import.Output, result)
+	}
+
+	// With several regions, a failed region does not stop the others
+	results := make([]rotateResult, len(targets))
+	failed := 0
+	for i, t := range targets {
+		regionCfg := awsCfg.Copy()
+		regionCfg.Region = t.Region
+		warned := len(progress.Warnings())
+		result, err := rotateTarget(ctx, regionCfg, cfg, t, material)
+		result.Warnings = progress.Warnings()[warned:]
+		if err != nil {
+			progress.Warnf("%s: %v", t.Region, err)
+			result.Error = errorHint(err).Error()
+			failed++
+		}
+		results[i] = result
+	}
+	if err := printRotateResults(cfg.Import.Output, results); err != nil {
+		return err
+	}
+	switch {
+	case failed == len(results):
+		return errors.New("rotation failed for every certificate")
+	case failed > 0:
+		return partialFailure(fmt.Errorf("rotation failed for %d of %d certificates", failed, len(results)))
+	}
+	return nil
+}
+
+// rotateTarget imports the new certificate into the region of t, moves the
+// resources using the old certificate over and deletes it with -delete-old.
+func rotateTarget(ctx context.Context, awsCfg aws.Config, cfg RotateConfig, t rotationTarget, material *certimport.Material) (rotateResult, error) {
+	client := acm.NewFromConfig(awsCfg)
+	result := rotateResult{Region: t.Region, OldCertificateArn: t.OldArn, Updated: []string{}, Skipped: []string{}, DryRun: cfg.Import.DryRun}
+
+	imported := importMaterial(ctx, awsCfg, progress, "rotate", cfg.Import, material)
+	// The errors themselves are returned, so that they keep their exit and
+	// error codes
+	if imported.failed() {
+		if len(imported.Regions) > 0 && imported.Regions[0].err != nil {
+			return result, imported.Regions[0].err
+		}
+		return result, imported.err
+	}
+	if !cfg.Import.DryRun {
+		result.NewCertificateArn = imported.Regions[0].CertificateArn
+		if result.NewCertificateArn == t.OldArn {
+			return result, fmt.Errorf("the new certificate is identical to %s, nothing to rotate", t.OldArn)
+		}
+		progress.Printf("✓ New certificate imported: %s\n", result.NewCertificateArn)
+	}
+
+	for _, resource := range t.Detail.InUseBy {
+		if cfg.Import.DryRun {
+			if describeResource(resource) == "" {
+				result.Skipped = append(result.Skipped, resource)
+			} else {
+				result.Updated = append(result.Updated, resource)
+			}
+			continue
+		}
+
+		updated, err := repointResource(ctx, awsCfg, resource, t.OldArn, result.NewCertificateArn)
+		if err != nil {
+			return result, fmt.Errorf("new certificate %s imported but %w", result.NewCertificateArn, err)
+		}
+		if updated {
+			result.Updated = append(result.Updated, resource)
+		} else {
+			progress.Warnf("%s cannot be updated automatically, point it at %s yourself", resource, result.NewCertificateArn)
+			result.Skipped = append(result.Skipped, resource)
+		}
+	}
+
+	if !cfg.Import.DryRun {
+		if err := verifyRotated(ctx, client, result.NewCertificateArn, material.Leaf); err != nil {
+			return result, err
+		}
+	}
+
+	if cfg.DeleteOld && !cfg.Import.DryRun {
+		if len(result.Skipped) > 0 {
+			progress.Warnf("Keeping %s because %d resource(s) still use it", t.OldArn, len(result.Skipped))
+		} else if err := deleteUnreferenced(ctx, client, t.OldArn); err != nil {
+			return result, err
+		} else {
+			result.Deleted = true
+		}
+	}
+	return result, nil
+}
+
+// detectRotationTargets scans the other enabled regions for certificates
+// of the same type and for the same names as old that load balancers,
+// CloudFront distributions or API Gateway domains use. CloudFront only uses
+// certificates in us-east-1, so a distribution makes that region a target.
+func detectRotationTargets(ctx context.Context, cfg RotateConfig, old *types.CertificateDetail) ([]rotationTarget, error) {
+	regions, err := enabledRegions(ctx, cfg.Import.Region, cfg.Import.Profile)
+	if err != nil {
+		return nil, err
+	}
+	names := detailNames(old)
+
+	var targets []rotationTarget
+	for _, region := range regions {
+		// Only -old-arn is rotated in its own region
+		if region == cfg.Import.Region {
+			continue
+		}
+		awsCfg, err := loadAWSConfig(ctx, region, cfg.Import.Profile)
+		if err != nil {
+			return nil, err
+		}
+		client := acm.NewFromConfig(awsCfg)
+		progress.Printf("Looking for certificates for %s in use in %s...\n", aws.ToString(old.DomainName), region)
+
+		certs, err := findCertificates(ctx, client, ListConfig{Type: string(old.Type)})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", region, err)
+		}
+		for _, c := range certs {
+			// ListCertificates truncates the SANs of certificates with many,
+			// so the summary only rules certificates out; the names are
+			// compared in full once described
+			if !c.InUse || c.CertificateArn == cfg.OldArn || !c.TruncatedSANs && certificateNames(c) != names {
+				continue
+			}
+			detail, err := describeCertificate(ctx, client, c.CertificateArn)
+			if err != nil {
+				return nil, err
+			}
+			if detailNames(detail) != names {
+				continue
+			}
+			if !slices.ContainsFunc(detail.InUseBy, func(resource string) bool { return describeResource(resource) != "" }) {
+				progress.Debugf("Skipping %s, none of the resources using it can be updated\n", c.CertificateArn)
+				continue
+			}
+			progress.Printf("✓ Found %s, used by %d resource(s)\n", c.CertificateArn, len(detail.InUseBy))
+			targets = append(targets, rotationTarget{Region: region, OldArn: c.CertificateArn, Detail: detail})
+		}
+	}
+	progress.Printf("✓ Rotating %d certificate(s) in total\n", len(targets)+1)
+	return targets, nil
+}
+
+// detailNames identifies the names of a described certificate, as
+// certificateNames does for a listed one.
+func detailNames(detail *types.CertificateDetail) string {
+	return certificateNames(listedCertificate{DomainName: aws.ToString(detail.DomainName), SANs: detail.SubjectAlternativeNames})
+}
+
+// verifyRotated checks that the new certificate is ISSUED and matches leaf
+// before anything is deleted.
+func verifyRotated(ctx context.Context, client *acm.Client, certificateArn string, leaf *x509.Certificate) error {
+	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	detail, err := waitForIssued(ctx, client, certificateArn)
+	if err != nil {
+		return err
+	}
+	if err := compareImported(detail, leaf); err != nil {
+		return fmt.Errorf("certificate %s does not match the imported file: %w", certificateArn, err)
+	}
+	progress.Printf("✓ Verified %s is ISSUED and matches the certificate file\n", certificateArn)
+	return nil
+}
+
+// describeResource names the kind of a resource from an InUseBy ARN, or
+// returns "" for resources rotate cannot update.
+func describeResource(resource string) string {
+	parsed, err := arn.Parse(resource)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case parsed.Service == "elasticloadbalancing" && strings.HasPrefix(parsed.Resource, "loadbalancer/"):
+		return "load balancer"
+	case parsed.Service == "cloudfront" && strings.HasPrefix(parsed.Resource, "distribution/"):
+		return "CloudFront distribution"
+	case parsed.Service == "apigateway" && strings.HasPrefix(parsed.Resource, "/domainnames/"):
+		return "API Gateway custom domain"
+	}
+	return ""
+}
+
+// repointResource moves a resource from InUseBy over to newArn. It returns
+// false for resources it does not know how to update.
+func repointResource(ctx context.Context, awsCfg aws.Config, resource, oldArn, newArn string) (bool, error) {
+	parsed, err := arn.Parse(resource)
+	if err != nil {
+		return false, nil
+	}
+
+	switch describeResource(resource) {
+	case "load balancer":
+		n, err := replaceOnLoadBalancer(ctx, awsCfg, resource, oldArn, newArn)
+		if err != nil {
+			return false, err
+		}
+		return n > 0, nil
+	case "CloudFront distribution":
+		id := strings.TrimPrefix(parsed.Resource, "distribution/")
+		return true, attachDistribution(ctx, awsCfg, id, newArn, false)
+	case "API Gateway custom domain":
+		cfg := awsCfg.Copy()
+		cfg.Region = parsed.Region
+		name := strings.TrimPrefix(parsed.Resource, "/domainnames/")
+		return true, attachAPIDomain(ctx, cfg, name, newArn)
+	}
+	return false, nil
+}
+
+// deleteUnreferenced waits for ACM to report that nothing uses a
+// certificate any more and deletes it.
+func deleteUnreferenced(ctx context.Context, client *acm.Client, certificateArn string) error {
+	ctx, cancel := context.WithTimeout(ctx, unreferencedTimeout)
+	defer cancel()
+
+	for {
+		detail, err := describeCertificate(ctx, client, certificateArn)
+		if err != nil {
+			return err
+		}
+		if len(detail.InUseBy) == 0 {
+			break
+		}
+		progress.Debugf("%s is still used by %s\n", certificateArn, strings.Join(detail.InUseBy, ", "))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s is still in use by %s, not deleting it: %w", certificateArn, strings.Join(detail.InUseBy, ", "), ctx.Err())
+		case <-time.After(10 * time.Second):
+		}
+	}
+
+	_, err := client.DeleteCertificate(ctx, &acm.DeleteCertificateInput{CertificateArn: aws.String(certificateArn)})
+	if err != nil {
+		return fmt.Errorf("failed to delete old certificate %s: %w", certificateArn, err)
+	}
+	progress.Printf("✓ Deleted old certificate %s\n", certificateArn)
+	return nil
+}
+
+func printRotateResult(format string, result rotateResult) error {
+	if format == "json" {
+		result.Warnings = progress.Warnings()
+		return printJSON(result)
+	}
+	if progress.Quiet() {
+		if result.NewCertificateArn != "" {
+			fmt.Println(result.NewCertificateArn)
+		}
+		return nil
+	}
+
+	if result.DryRun {
+		fmt.Printf("Dry run: the new certificate would replace %s on\n", result.OldCertificateArn)
+		for _, resource := range result.Updated {
+			fmt.Printf("  %s (%s)\n", resource, describeResource(resource))
+		}
+		for _, resource := range result.Skipped {
+			fmt.Printf("  %s (not supported, update it yourself)\n", resource)
+		}
+		progress.Printf("✅ Dry run complete, no changes made\n")
+		return nil
+	}
+
+	stdout.Printf("✅ Certificate rotated successfully!\n")
+	fmt.Printf("New Certificate ARN: %s\n", result.NewCertificateArn)
+	fmt.Printf("Updated: %d resource(s)\n", len(result.Updated))
+	if len(result.Skipped) > 0 {
+		fmt.Printf("Not updated: %s\n", strings.Join(result.Skipped, ", "))
+	}
+	if result.Deleted {
+		fmt.Printf("Deleted: %s\n", result.OldCertificateArn)
+	}
+	return nil
+}
+
+// printRotateResults prints the rotations of -detect-regions, one per
+// region, as a JSON array or a block of text each.
+func printRotateResults(format string, results []rotateResult) error {
+	if format == "json" {
+		return printJSON(results)
+	}
+	if progress.Quiet() {
+		for _, r := range results {
+			if r.NewCertificateArn != "" {
+				fmt.Println(r.NewCertificateArn)
+			}
+		}
+		return nil
+	}
+
+	for _, r := range results {
+		fmt.Printf("\n%s: %s\n", r.Region, r.OldCertificateArn)
+		switch {
+		case r.Error != "":
+			fmt.Printf("  Failed: %s\n", r.Error)
+			if r.NewCertificateArn != "" {
+				fmt.Printf("  New Certificate ARN: %s\n", r.NewCertificateArn)
+			}
+			continue
+		case r.DryRun:
+			for _, resource := range r.Updated {
+				fmt.Printf("  would update %s (%s)\n", resource, describeResource(resource))
+			}
+			for _, resource := range r.Skipped {
+				fmt.Printf("  would skip %s (not supported, update it yourself)\n", resource)
+			}
+			continue
+		}
+		fmt.Printf("  New Certificate ARN: %s\n", r.NewCertificateArn)
+		fmt.Printf("  Updated: %d resource(s)\n", len(r.Updated))
+		if len(r.Skipped) > 0 {
+			fmt.Printf("  Not updated: %s\n", strings.Join(r.Skipped, ", "))
+		}
+		if r.Deleted {
+			fmt.Printf("  Deleted: %s\n", r.OldCertificateArn)
+		}
+	}
+	fmt.Println()
+	rotated := 0
+	for _, r := range results {
+		if r.Error == "" {
+			rotated++
+		}
+	}
+	if results[0].DryRun {
+		progress.Printf("✅ Dry run complete, no changes made\n")
+	} else if rotated > 0 {
+		stdout.Printf("✅ %d of %d certificate(s) rotated\n", rotated, len(results))
+	}
+	return nil
+}
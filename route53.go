@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// dnsChangeTimeout bounds the wait for Route 53 to apply a change to all of
+// its authoritative servers, which usually takes well under a minute.
+const dnsChangeTimeout = 5 * time.Minute
+
+// dnsRecord is a record set created to prove control of a domain.
+type dnsRecord struct {
+	Name   string
+	Type   r53types.RRType
+	Values []string
+}
+
+// zoneFinder finds the public hosted zone holding a DNS name. The zones are
+// listed once and reused for every lookup.
+type zoneFinder struct {
+	client *route53.Client
+	zoneID string // set by -hosted-zone-id, used for every name
+	zones  []r53types.HostedZone
+	listed bool
+}
+
+func newZoneFinder(client *route53.Client, zoneID string) *zoneFinder {
+	return &zoneFinder{client: client, zoneID: strings.TrimPrefix(zoneID, "/hostedzone/")}
+}
+
+// find returns the ID of the most specific public hosted zone that name
+// belongs to.
+func (f *zoneFinder) find(ctx context.Context, name string) (string, error) {
+	if f.zoneID != "" {
+		return f.zoneID, nil
+	}
+	zone, err := f.lookup(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if zone == nil {
+		return "", fmt.Errorf("no public Route 53 hosted zone found for %s (use -hosted-zone-id)", strings.ToLower(strings.TrimSuffix(name, ".")))
+	}
+	return strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/"), nil
+}
+
+// lookup returns the most specific public hosted zone that name belongs to,
+// or nil if there is none.
+func (f *zoneFinder) lookup(ctx context.Context, name string) (*r53types.HostedZone, error) {
+	if !f.listed {
+		paginator := route53.NewListHostedZonesPaginator(f.client, &route53.ListHostedZonesInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list Route 53 hosted zones: %w", err)
+			}
+			f.zones = append(f.zones, page.HostedZones...)
+		}
+		f.listed = true
+	}
+
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	var best *r53types.HostedZone
+	bestLen := -1
+	for i, zone := range f.zones {
+		if zone.Config != nil && zone.Config.PrivateZone {
+			continue
+		}
+		zoneName := strings.ToLower(strings.TrimSuffix(aws.ToString(zone.Name), "."))
+		if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+			continue
+		}
+		if len(zoneName) > bestLen {
+			best, bestLen = &f.zones[i], len(zoneName)
+		}
+	}
+	return best, nil
+}
+
+// changeRecords applies action to every record, batching the changes per
+// hosted zone, and waits until Route 53 reports them as INSYNC.
+func changeRecords(ctx context.Context, client *route53.Client, zones *zoneFinder, action r53types.ChangeAction, records []dnsRecord) error {
+	var zoneIDs []string
+	changes := make(map[string][]r53types.Change)
+	for _, record := range records {
+		zoneID, err := zones.find(ctx, record.Name)
+		if err != nil {
+			return err
+		}
+		if _, ok := changes[zoneID]; !ok {
+			zoneIDs = append(zoneIDs, zoneID)
+		}
+
+		set := &r53types.ResourceRecordSet{
+			Name: aws.String(record.Name),
+			Type: record.Type,
+			TTL:  aws.Int64(60),
+		}
+		for _, value := range record.Values {
+			set.ResourceRecords = append(set.ResourceRecords, r53types.ResourceRecord{Value: aws.String(value)})
+		}
+		changes[zoneID] = append(changes[zoneID], r53types.Change{Action: action, ResourceRecordSet: set})
+	}
+
+	waiter := route53.NewResourceRecordSetsChangedWaiter(client)
+	for _, zoneID := range zoneIDs {
+		out, err := client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(zoneID),
+			ChangeBatch:  &r53types.ChangeBatch{Changes: changes[zoneID]},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update records in hosted zone %s: %w", zoneID, err)
+		}
+		progress.Debugf("Route 53 change %s submitted for zone %s\n", aws.ToString(out.ChangeInfo.Id), zoneID)
+
+		if err := waiter.Wait(ctx, &route53.GetChangeInput{Id: out.ChangeInfo.Id}, dnsChangeTimeout); err != nil {
+			return fmt.Errorf("failed waiting for hosted zone %s to apply the change: %w", zoneID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type SelfSignedConfig struct {
+	Import   CertImportConfig
+	Domains  []string
+	Days     int
+	KeyType  string
+	OutDir   string
+	DoImport bool
+}
+
+func runGenSelfSigned(ctx context.Context, args []string) error {
+	var cfg SelfSignedConfig
+	var domains, regions, tagString, publishArn string
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("gen-selfsigned", flag.ExitOnError)
+	fs.StringVar(&domains, "domain", "", "Names to include in the certificate, e.g. 'example.com,*.example.com' - REQUIRED")
+	fs.IntVar(&cfg.Days, "days", 30, "Validity of the certificate in days")
+	fs.StringVar(&cfg.KeyType, "key-type", "ec256", "Certificate key type: "+keyTypes)
+	fs.StringVar(&cfg.OutDir, "out-dir", "", "Write cert.pem, chain.pem, fullchain.pem and privkey.pem to this directory")
+	fs.BoolVar(&cfg.DoImport, "import", false, "Import the certificate into ACM")
+	fs.StringVar(&regions, "regions", "", "Import into several regions concurrently, e.g. 'us-east-1,eu-west-1'")
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.BoolVar(&cfg.Import.AutoTags, "auto-tags", false, "Add tags describing the certificate and the import (Domain, Issuer, NotAfter, ImportedBy, ...)")
+	fs.BoolVar(&cfg.Import.Upsert, "upsert", false, "Re-import over an existing imported certificate with the same domain and SANs")
+	fs.StringVar(&publishArn, "publish-arn", "", "After importing, write the ARN to 'ssm:/path/to/param' or 'secretsmanager:name' (comma-separated for several)")
+	fs.StringVar(&cfg.Import.Output, "output", "text", "Output format: text or json")
+	addAWSFlags(fs, &cfg.Import.Region, &cfg.Import.Profile, &cfg.Import.Timeout)
+	addPreflightFlags(fs)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s gen-selfsigned -domain NAMES [-out-dir DIR] [-import] [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Create a self-signed certificate and key for dev/test stacks that need an ACM ARN\n")
+		fmt.Fprintf(os.Stderr, "without a real CA. Browsers and clients will not trust it.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s gen-selfsigned -domain dev.example.com -days 30 -import -tag Environment=dev\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s gen-selfsigned -domain 'localhost,*.test.internal' -out-dir ./tls\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	cfg.Domains = cliutil.SplitList(domains)
+	if len(cfg.Domains) == 0 {
+		fail(errors.New("-domain is required"))
+	}
+	if cfg.Days <= 0 {
+		fail(errors.New("-days must be positive"))
+	}
+	if err := checkKeyType(cfg.KeyType); err != nil {
+		fail(err)
+	}
+	if !cfg.DoImport && cfg.OutDir == "" {
+		fail(errors.New("nothing to do, give -out-dir and/or -import"))
+	}
+
+	cfg.Import.Regions = cliutil.SplitList(regions)
+	var err error
+	cfg.Import.PublishArn, err = parsePublishTargets(publishArn)
+	if err != nil {
+		fail(err)
+	}
+	var inlineTags map[string]string
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Import.Tags = mergeTags(inlineTags, tagList)
+	if err := cfg.Import.validateOptions(); err != nil {
+		fail(err)
+	}
+
+	return genSelfSigned(ctx, cfg)
+}
+
+func genSelfSigned(ctx context.Context, cfg SelfSignedConfig) error {
+	material, err := selfSignedMaterial(cfg.Domains, cfg.KeyType, time.Duration(cfg.Days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	progress.Printf("✓ Self-signed certificate created for %s (expires %s)\n", strings.Join(cfg.Domains, ", "), material.Leaf.NotAfter.Format("2006-01-02"))
+
+	if cfg.OutDir != "" {
+		if err := writeMaterial(cfg.OutDir, material); err != nil {
+			return err
+		}
+		progress.Printf("✓ Certificate written to %s\n", cfg.OutDir)
+	}
+	if !cfg.DoImport {
+		return nil
+	}
+
+	ctx, cancel := withTimeout(ctx, cfg.Import.Timeout)
+	defer cancel()
+
+	awsCfg, regions, err := importTargets(ctx, &cfg.Import)
+	if err != nil {
+		return err
+	}
+	return importLoaded(ctx, cfg.Import, awsCfg, regions, material)
+}
+
+// selfSignedMaterial creates a server certificate for names signed by its
+// own new key. It is not marked as a CA so that it is used as a leaf.
+func selfSignedMaterial(names []string, keyType string, validity time.Duration) (*certimport.Material, error) {
+	key, err := generateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: strings.TrimPrefix(names[0], "*.")},
+		DNSNames:              names,
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &certimport.Material{Leaf: leaf, Key: key}, nil
+}
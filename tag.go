@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/internal/inventory"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type TagConfig struct {
+	CertificateArn string
+	Tags           map[string]string
+	Keys           []string
+	Region         string
+	Profile        string
+	Timeout        time.Duration
+}
+
+func runTag(ctx context.Context, args []string) error {
+	var cfg TagConfig
+	var tagString, tagsFile string
+	tagList := tagFlags{}
+
+	fs := flag.NewFlagSet("tag", flag.ExitOnError)
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to tag - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.StringVar(&tagString, "tags", "", "Tags in format 'key1=value1,key2=value2'")
+	fs.Var(tagList, "tag", "Tag in format 'key=value' (may be repeated)")
+	fs.StringVar(&tagsFile, "tags-file", "", "JSON or YAML file with a map of tag keys to values")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s tag -arn <arn> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Add or update tags on a certificate without re-importing it\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s tag -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -tag Environment=prod\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s tag -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -tags-file tags.yaml\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	if cfg.CertificateArn == "" {
+		fmt.Fprintf(os.Stderr, "Error: -arn is required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	var fileTags, inlineTags map[string]string
+	if tagsFile != "" {
+		var err error
+		fileTags, err = loadTagsFile(tagsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if tagString != "" {
+		inlineTags = parseTags(tagString)
+	}
+	cfg.Tags = mergeTags(fileTags, inlineTags, tagList)
+
+	if len(cfg.Tags) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: at least one tag is required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if err := certimport.ValidateTags(cfg.Tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return tagCertificate(ctx, cfg)
+}
+
+func runUntag(ctx context.Context, args []string) error {
+	var cfg TagConfig
+	var keys string
+
+	fs := flag.NewFlagSet("untag", flag.ExitOnError)
+	fs.StringVar(&cfg.CertificateArn, "arn", "", "ARN of the certificate to untag - REQUIRED")
+	finder := addArnFromFindFlag(fs, &cfg.CertificateArn)
+	fs.StringVar(&keys, "keys", "", "Tag keys to remove, e.g. 'Owner,CostCenter' - REQUIRED")
+	addAWSFlags(fs, &cfg.Region, &cfg.Profile, &cfg.Timeout)
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s untag -arn <arn> -keys <keys> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Remove tags from a certificate without re-importing it\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s untag -arn arn:aws:acm:us-east-1:123456789012:certificate/abc -keys Owner,CostCenter\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+	if err := finder.resolve(ctx, cfg.Region, cfg.Profile); err != nil {
+		return err
+	}
+
+	cfg.Keys = cliutil.SplitList(keys)
+	if cfg.CertificateArn == "" || len(cfg.Keys) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: -arn and -keys are required\n\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	return untagCertificate(ctx, cfg)
+}
+
+func tagCertificate(ctx context.Context, cfg TagConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	client := acm.NewFromConfig(awsCfg)
+
+	// ACM counts existing tags against the limit, except system tags such
+	// as aws:cloudformation:stack-name which are not ours to validate
+	existing, err := inventory.Tags(ctx, client, cfg.CertificateArn)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for key := range mergeTags(existing, cfg.Tags) {
+		if !strings.HasPrefix(strings.ToLower(key), "aws:") {
+			count++
+		}
+	}
+	if count > certimport.MaxTags {
+		return fmt.Errorf("too many tags: the certificate would have %d tags (ACM allows at most %d)", count, certimport.MaxTags)
+	}
+
+	var tags []types.Tag
+	for _, key := range sortedKeys(cfg.Tags) {
+		tags = append(tags, types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(cfg.Tags[key]),
+		})
+	}
+
+	progress.Printf("Tagging certificate %s...\n", cfg.CertificateArn)
+
+	_, err = client.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: aws.String(cfg.CertificateArn),
+		Tags:           tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag certificate: %w", err)
+	}
+
+	if !progress.Quiet() {
+		stdout.Printf("✅ %d tags added or updated\n", len(tags))
+	}
+	return nil
+}
+
+func untagCertificate(ctx context.Context, cfg TagConfig) error {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+	client := acm.NewFromConfig(awsCfg)
+
+	var tags []types.Tag
+	for _, key := range cfg.Keys {
+		tags = append(tags, types.Tag{Key: aws.String(key)})
+	}
+
+	progress.Printf("Removing tags from certificate %s...\n", cfg.CertificateArn)
+
+	_, err = client.RemoveTagsFromCertificate(ctx, &acm.RemoveTagsFromCertificateInput{
+		CertificateArn: aws.String(cfg.CertificateArn),
+		Tags:           tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove tags: %w", err)
+	}
+
+	if !progress.Quiet() {
+		stdout.Printf("✅ %d tags removed\n", len(tags))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"gopkg.in/yaml.v3"
+)
+
+func parseTags(tagString string) map[string]string {
+	tags := make(map[string]string)
+	pairs := strings.Split(tagString, ",")
+
+	for _, pair := range pairs {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) == 2 {
+			key := strings.TrimSpace(kv[0])
+			value := strings.TrimSpace(kv[1])
+			if key != "" && value != "" {
+				tags[key] = value
+			}
+		}
+	}
+
+	return tags
+}
+
+// tagFlags collects repeated -tag key=value flags. Unlike -tags, empty
+// values are kept.
+type tagFlags map[string]string
+
+func (t tagFlags) String() string {
+	var pairs []string
+	for key, value := range t {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (t tagFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return fmt.Errorf("tag must be in format key=value, got %q", value)
+	}
+	t[strings.TrimSpace(kv[0])] = kv[1]
+	return nil
+}
+
+// loadTagsFile reads a flat map of tag keys to values from a JSON or YAML
+// file, chosen by extension.
+func loadTagsFile(path string) (map[string]string, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &tags)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tags)
+	default:
+		return nil, fmt.Errorf("tags file %s must have a .json, .yaml or .yml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tags file %s: %w", path, err)
+	}
+	return tags, nil
+}
+
+// mergeTags returns the union of the given tag sets. Later sets override
+// earlier ones, so inline flags win over a tags file.
+func mergeTags(sets ...map[string]string) map[string]string {
+	var merged map[string]string
+	for _, set := range sets {
+		for key, value := range set {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// importMetadataTags returns tags recording who imported a certificate, when
+// and with which version of aws-certs. The caller is taken from STS so the
+// tag names the AWS identity, falling back to the local user name.
+func importMetadataTags(ctx context.Context, awsCfg aws.Config) map[string]string {
+	importedBy := localUser()
+	identity, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		progress.Warnf("Could not determine AWS identity for the ImportedBy tag: %v", err)
+	} else {
+		importedBy = aws.ToString(identity.Arn)
+	}
+
+	return map[string]string{
+		"ImportedBy":  certimport.SanitizeTagValue(importedBy),
+		"ImportedAt":  time.Now().UTC().Format(time.RFC3339),
+		"ToolVersion": certimport.SanitizeTagValue("aws-certs " + toolVersion()),
+	}
+}
+
+func localUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
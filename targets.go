@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// targetsFile lists the accounts and regions -targets imports into.
+type targetsFile struct {
+	Targets []importTarget `json:"targets" yaml:"targets"`
+}
+
+// importTarget is one account to import into, reached through a profile, a
+// role assumed with the base credentials, or both.
+type importTarget struct {
+	Name       string   `json:"name" yaml:"name"`
+	Profile    string   `json:"profile" yaml:"profile"`
+	RoleArn    string   `json:"roleArn" yaml:"roleArn"`
+	ExternalID string   `json:"externalId" yaml:"externalId"`
+	Regions    []string `json:"regions" yaml:"regions"`
+}
+
+func loadTargets(path string) ([]importTarget, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f targetsFile
+	if err := decodeStrict(path, data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse targets file %s: %w", path, err)
+	}
+	if len(f.Targets) == 0 {
+		return nil, fmt.Errorf("targets file %s lists no targets", path)
+	}
+	for i := range f.Targets {
+		if f.Targets[i].Name == "" {
+			f.Targets[i].Name = fmt.Sprintf("#%d", i+1)
+		}
+	}
+	return f.Targets, nil
+}
+
+// importToTargets reads the certificate once and imports it into every
+// target in cfg.TargetsFile, cfg.Concurrency targets at a time.
+func importToTargets(ctx context.Context, cfg CertImportConfig) error {
+	targets, err := loadTargets(cfg.TargetsFile)
+	if err != nil {
+		return err
+	}
+
+	// The -role-arn credentials are shared by every configuration loaded,
+	// so they would replace a target's own profile
+	for _, target := range targets {
+		if target.Profile != "" && assumeRole.roleArn != "" {
+			return fmt.Errorf("-role-arn cannot be used with targets that name a profile (target %s)", target.Name)
+		}
+	}
+
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	base, err := loadAWSConfig(ctx, cfg.Region, cfg.Profile)
+	if err != nil {
+		return err
+	}
+
+	progress.Printf("Reading certificate files...\n")
+	material, err := loadMaterial(ctx, cfg, base, progress)
+	if err != nil {
+		return err
+	}
+
+	progress.Printf("Importing into %d targets (concurrency: %d, %s)...\n", len(targets), cfg.Concurrency, rateNote())
+	results := make([]batchResult, len(targets))
+	spin := progress.Spin("Importing into targets", len(targets))
+	runConcurrently(cfg.Concurrency, len(targets), func(i int) {
+		results[i] = importToTarget(ctx, base, cfg, targets[i], material)
+		spin.Step()
+	})
+	spin.Stop()
+
+	if err := printBatchResults(cfg.Output, results); err != nil {
+		return err
+	}
+	if err := batchSummary(results, "targets"); err != nil {
+		return err
+	}
+	if cfg.DryRun {
+		progress.Printf("✅ Dry run complete, no changes made\n")
+	}
+	if cfg.DeleteKey {
+		return deleteKeyFiles(cfg)
+	}
+	return nil
+}
+
+func importToTarget(ctx context.Context, base aws.Config, cfg CertImportConfig, target importTarget, material *certimport.Material) batchResult {
+	logger := prefixLogger{prefix: "[" + target.Name + "] ", progressLogger: progress}
+
+	awsCfg, err := targetConfig(ctx, base, cfg.Region, target)
+	if err != nil {
+		result := batchResult{Name: target.Name}
+		result.setError(err)
+		return result
+	}
+	if _, err := preflight(ctx, awsCfg, logger); err != nil {
+		result := batchResult{Name: target.Name}
+		result.setError(err)
+		return result
+	}
+	if len(target.Regions) > 0 {
+		cfg.Regions = target.Regions
+	}
+	return importMaterial(ctx, awsCfg, logger, target.Name, cfg, material)
+}
+
+// targetConfig returns the AWS configuration for target: its own profile if
+// it names one, with its role assumed on top if set.
+func targetConfig(ctx context.Context, base aws.Config, region string, target importTarget) (aws.Config, error) {
+	awsCfg := base.Copy()
+	if target.Profile != "" {
+		var err error
+		awsCfg, err = loadAWSConfig(ctx, region, target.Profile)
+		if err != nil {
+			return aws.Config{}, err
+		}
+	}
+
+	if target.RoleArn != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(awsCfg), target.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "aws-certs-" + sessionUser()
+			if target.ExternalID != "" {
+				o.ExternalID = aws.String(target.ExternalID)
+			}
+		})
+		awsCfg.Credentials = aws.NewCredentialsCache(provider)
+		// The role's account has ACM quotas of its own
+		limitImports(&awsCfg)
+	}
+	return awsCfg, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// printTerraform prints aws_acm_certificate resources matching the imported
+// certificates, and the terraform import commands that adopt them, so that
+// an environment managed with Terraform can take them over.
+func printTerraform(cfg CertImportConfig, prepared *certimport.Prepared, result *certimport.Result) error {
+	body, key, chain := terraformSources(cfg)
+	if len(prepared.Chain) == 0 {
+		chain = ""
+	}
+
+	tags := map[string]string{}
+	for _, tag := range prepared.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var commands []string
+	for _, r := range result.Regions {
+		if r.Err != nil {
+			continue
+		}
+		name := terraformName(result.DomainName)
+		if len(result.Regions) > 1 {
+			name += "_" + terraformName(r.Region)
+		}
+
+		fmt.Printf("resource \"aws_acm_certificate\" %q {\n", name)
+		if len(result.Regions) > 1 {
+			// Each region needs an aliased provider, e.g. provider "aws" { alias = "eu_west_1", region = "eu-west-1" }
+			fmt.Printf("  provider          = aws.%s\n", terraformName(r.Region))
+		}
+		fmt.Printf("  certificate_body  = %s\n", body)
+		fmt.Printf("  private_key       = %s\n", key)
+		if chain != "" {
+			fmt.Printf("  certificate_chain = %s\n", chain)
+		}
+		if len(keys) > 0 {
+			fmt.Printf("\n  tags = {\n")
+			for _, k := range keys {
+				fmt.Printf("    %q = %q\n", k, tags[k])
+			}
+			fmt.Printf("  }\n")
+		}
+		fmt.Printf("\n  lifecycle {\n    create_before_destroy = true\n  }\n}\n\n")
+
+		commands = append(commands, fmt.Sprintf("terraform import aws_acm_certificate.%s %s", name, r.CertificateArn))
+	}
+
+	// The commands are comments so that the output stays a valid .tf file
+	fmt.Printf("# Adopt the imported certificates into the Terraform state:\n")
+	for _, c := range commands {
+		fmt.Printf("#   %s\n", c)
+	}
+	return nil
+}
+
+// terraformSources returns the expressions for the certificate, key and chain
+// of an import: file() for local files, and variables for inputs that were
+// read from stdin, AWS, Vault, the environment or a bundle.
+func terraformSources(cfg CertImportConfig) (body, key, chain string) {
+	body, key, chain = "var.certificate_body", "var.private_key", "var.certificate_chain"
+	local := func(path string) bool {
+		return path != "" && path != "-" && !strings.Contains(path, "://")
+	}
+	file := func(path string) string {
+		return fmt.Sprintf("file(%q)", path)
+	}
+
+	if cfg.SecretDir != "" {
+		return file(filepath.Join(cfg.SecretDir, secretCertFile)), file(filepath.Join(cfg.SecretDir, secretKeyFile)), chain
+	}
+	if local(cfg.CertFile) {
+		body = file(cfg.CertFile)
+	}
+	if local(cfg.PrivateKeyFile) {
+		key = file(cfg.PrivateKeyFile)
+	}
+	// A chain that was fixed or fetched differs from the -chain file
+	if local(cfg.ChainFile) && !cfg.FixChain && !cfg.FetchChain {
+		chain = file(cfg.ChainFile)
+	}
+	return body, key, chain
+}
+
+// terraformName turns a domain name or region into a Terraform identifier,
+// e.g. *.example.com into wildcard_example_com.
+func terraformName(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "*", "wildcard")
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "cert_" + name
+	}
+	return name
+}
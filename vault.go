@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// vaultResponses caches Vault responses by path and query so that several
+// inputs naming different fields of one secret, or of one issued PKI
+// certificate, all see the same data.
+var vaultResponses = struct {
+	sync.Mutex
+	m map[string]map[string]interface{}
+}{m: make(map[string]map[string]interface{})}
+
+// readVault reads certificate material from HashiCorp Vault using VAULT_ADDR
+// and VAULT_TOKEN. The URI has the form vault://<api path>[?params][#field]:
+//
+//	vault://secret/data/tls#certificate   field of a KV v1 or v2 secret
+//	vault://pki/issue/web?common_name=x   issue a certificate from a PKI role
+//
+// Query parameters are passed on to reads, e.g. ?version=2 for KV v2. For a
+// PKI issue path (<mount>/issue/<role>) they become the body of the write
+// that issues the certificate instead. Without a field, a response with the certificate, ca_chain and private_key
+// fields returned by pki/issue is combined into a PEM bundle for -bundle.
+func readVault(ctx context.Context, uri string) ([]byte, error) {
+	ref, field, _ := strings.Cut(strings.TrimPrefix(uri, "vault://"), "#")
+
+	data, err := vaultRead(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if field == "" {
+		if _, ok := data["private_key"]; ok {
+			return pkiBundle(data), nil
+		}
+		return nil, fmt.Errorf("%s: select a field with #name (available: %s)", uri, strings.Join(fieldNames(data), ", "))
+	}
+
+	switch value := data[field].(type) {
+	case string:
+		return []byte(value), nil
+	case []interface{}:
+		// ca_chain is returned as a list of PEM certificates
+		return []byte(joinPEM(value)), nil
+	case nil:
+		return nil, fmt.Errorf("%s: field %q not found (available: %s)", uri, field, strings.Join(fieldNames(data), ", "))
+	default:
+		return nil, fmt.Errorf("%s: field %q is not a string", uri, field)
+	}
+}
+
+func vaultRead(ctx context.Context, ref string) (map[string]interface{}, error) {
+	vaultResponses.Lock()
+	defer vaultResponses.Unlock()
+	if data, ok := vaultResponses.m[ref]; ok {
+		return data, nil
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR must be set to read vault:// inputs")
+	}
+	token, err := vaultToken()
+	if err != nil {
+		return nil, err
+	}
+	client, err := vaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	path, query, _ := strings.Cut(ref, "?")
+	endpoint := strings.TrimRight(addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters in vault://%s: %w", ref, err)
+	}
+
+	// Issuing a certificate is a write with the parameters as its body; any
+	// other path is read with the parameters in the query string
+	method, body := http.MethodGet, io.Reader(nil)
+	if isPKIIssue(path) {
+		params := make(map[string]string, len(values))
+		for key := range values {
+			params[key] = values.Get(key)
+		}
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		method, body = http.MethodPost, bytes.NewReader(encoded)
+	} else if len(values) > 0 {
+		endpoint += "?" + values.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	progress.Debugf("Vault %s %s\n", method, endpoint)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault://%s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, certimport.MaxMaterialSize)).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode Vault response for %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read vault://%s: HTTP %d %s", path, resp.StatusCode, strings.Join(result.Errors, "; "))
+	}
+
+	// KV v2 nests the secret under data.data next to data.metadata
+	data := result.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	vaultResponses.m[ref] = data
+	return data, nil
+}
+
+// isPKIIssue reports whether path is a PKI secrets engine issue endpoint,
+// <mount>/issue/<role>.
+func isPKIIssue(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	return len(parts) >= 3 && parts[len(parts)-2] == "issue"
+}
+
+// vaultToken returns VAULT_TOKEN or the token saved by "vault login".
+func vaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	home, err := os.UserHomeDir()
+	if err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+	return "", errors.New("VAULT_TOKEN must be set (or log in with 'vault login') to read vault:// inputs")
+}
+
+// vaultClient returns an HTTP client trusting VAULT_CACERT when it is set.
+func vaultClient() (*http.Client, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	caFile := os.Getenv("VAULT_CACERT")
+	if caFile == "" {
+		return client, nil
+	}
+	pem, err := readFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("VAULT_CACERT %s contains no certificates", caFile)
+	}
+	client.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+	return client, nil
+}
+
+// pkiBundle combines a PKI issue response into one PEM bundle.
+func pkiBundle(data map[string]interface{}) []byte {
+	var parts []string
+	if cert, ok := data["certificate"].(string); ok {
+		parts = append(parts, cert)
+	}
+	if chain, ok := data["ca_chain"].([]interface{}); ok && len(chain) > 0 {
+		parts = append(parts, joinPEM(chain))
+	} else if ca, ok := data["issuing_ca"].(string); ok {
+		parts = append(parts, ca)
+	}
+	if key, ok := data["private_key"].(string); ok {
+		parts = append(parts, key)
+	}
+	return []byte(strings.Join(parts, "\n") + "\n")
+}
+
+func joinPEM(values []interface{}) string {
+	var parts []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			parts = append(parts, strings.TrimSpace(s))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func fieldNames(data map[string]interface{}) []string {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
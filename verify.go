@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// waitTimeout bounds -wait. Imported certificates are usually ISSUED at once.
+const waitTimeout = 2 * time.Minute
+
+// waitForImported polls every successfully imported certificate until ACM
+// reports it as ISSUED and checks that ACM holds the certificate in leaf.
+func waitForImported(ctx context.Context, awsCfg aws.Config, result *certimport.Result, leaf *x509.Certificate) error {
+	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	for _, r := range result.Regions {
+		if r.Err != nil {
+			continue
+		}
+		cfg := awsCfg.Copy()
+		cfg.Region = r.Region
+
+		detail, err := waitForIssued(ctx, acm.NewFromConfig(cfg), r.CertificateArn)
+		if err != nil {
+			return err
+		}
+		if err := compareImported(detail, leaf); err != nil {
+			return fmt.Errorf("certificate %s does not match the imported file: %w", r.CertificateArn, err)
+		}
+		progress.Printf("✓ Verified %s is ISSUED and matches the certificate file (region: %s)\n", r.CertificateArn, r.Region)
+	}
+	return nil
+}
+
+// waitForIssued polls a certificate until its status is ISSUED, failing at
+// once on a status it cannot leave.
+func waitForIssued(ctx context.Context, client *acm.Client, arn string) (*types.CertificateDetail, error) {
+	for {
+		detail, err := describeCertificate(ctx, client, arn)
+		if err != nil {
+			return nil, err
+		}
+
+		switch detail.Status {
+		case types.CertificateStatusIssued:
+			return detail, nil
+		case types.CertificateStatusPendingValidation:
+			progress.Debugf("%s is %s, waiting\n", arn, detail.Status)
+		default:
+			if reason := detail.FailureReason; reason != "" {
+				return nil, fmt.Errorf("certificate %s is %s: %s", arn, detail.Status, reason)
+			}
+			return nil, fmt.Errorf("certificate %s is %s", arn, detail.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("certificate %s is still %s: %w", arn, detail.Status, ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// compareImported checks the serial number, expiry and names that ACM
+// reports against leaf.
+func compareImported(detail *types.CertificateDetail, leaf *x509.Certificate) error {
+	// ACM formats serials as colon separated hex bytes
+	serial := strings.TrimLeft(strings.ReplaceAll(strings.ToLower(aws.ToString(detail.Serial)), ":", ""), "0")
+	if want := leaf.SerialNumber.Text(16); serial != want {
+		return fmt.Errorf("serial number is %s, expected %s", serial, want)
+	}
+	if detail.NotAfter == nil || !detail.NotAfter.Equal(leaf.NotAfter.Truncate(time.Second)) {
+		return fmt.Errorf("expiry is %v, expected %s", aws.ToTime(detail.NotAfter), leaf.NotAfter.Format(time.RFC3339))
+	}
+	for _, name := range leaf.DNSNames {
+		if !slices.Contains(detail.SubjectAlternativeNames, name) {
+			return fmt.Errorf("%s is missing from the subject alternative names", name)
+		}
+	}
+	return nil
+}
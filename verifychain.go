@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+type VerifyChainConfig struct {
+	CertFile  string
+	ChainFile string
+	Output    string
+}
+
+// verifiedStore is the JSON representation of the verification against one
+// trust store.
+type verifiedStore struct {
+	Store   string   `json:"store"`
+	Trusted bool     `json:"trusted"`
+	Path    []string `json:"path,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	// MissingIssuer is the subject of the certificate missing from the chain
+	MissingIssuer string `json:"missingIssuer,omitempty"`
+	MissingURL    string `json:"missingIssuerUrl,omitempty"`
+}
+
+func runVerifyChain(ctx context.Context, args []string) error {
+	var cfg VerifyChainConfig
+
+	fs := flag.NewFlagSet("verify-chain", flag.ExitOnError)
+	fs.StringVar(&cfg.CertFile, "cert", "", "Certificate file (PEM or DER), or - for stdin; further certificates in it are the chain - REQUIRED")
+	fs.StringVar(&cfg.ChainFile, "chain", "", "Certificate chain file, if not in the -cert file")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text or json")
+	addLogFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify-chain -cert <file> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Verify a certificate and its chain against the system root store and the Amazon Trust\n")
+		fmt.Fprintf(os.Stderr, "Services roots, naming the intermediate that is missing when a chain is incomplete.\n")
+		fmt.Fprintf(os.Stderr, "Exits non-zero unless at least one store trusts the chain.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s verify-chain -cert fullchain.pem\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s verify-chain -cert cert.pem -chain chain.pem -output json\n", os.Args[0])
+	}
+
+	parseFlags(fs, args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	// A positional argument is treated as the certificate file
+	if cfg.CertFile == "" && fs.NArg() > 0 {
+		cfg.CertFile = fs.Arg(0)
+	}
+	if cfg.CertFile == "" {
+		fail(errors.New("-cert is required"))
+	}
+	if cfg.CertFile == "-" && cfg.ChainFile == "-" {
+		fail(errors.New("only one input can be read from stdin"))
+	}
+	if cfg.Output != "text" && cfg.Output != "json" {
+		fail(errors.New("-output must be text or json"))
+	}
+
+	return verifyChain(cfg)
+}
+
+func verifyChain(cfg VerifyChainConfig) error {
+	certs, err := readLocalCertificates(cfg.CertFile, "certificate")
+	if err != nil {
+		return err
+	}
+	if cfg.ChainFile != "" {
+		chain, err := readLocalCertificates(cfg.ChainFile, "certificate chain")
+		if err != nil {
+			return err
+		}
+		certs = append(certs, chain...)
+	}
+
+	leaf := findLeaf(certs)
+	chain := certimport.OrderChain(leaf, certs)
+	for _, cert := range chain.Unused {
+		progress.Warnf("%s is not part of the chain of %s", cert.Subject, leaf.Subject)
+	}
+
+	var results []verifiedStore
+	trusted := false
+	for _, store := range certimport.TrustStores() {
+		r := newVerifiedStore(certimport.VerifyTrust(leaf, chain, store))
+		trusted = trusted || r.Trusted
+		results = append(results, r)
+	}
+
+	if cfg.Output == "json" {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.Trusted {
+				stdout.Printf("✓ Trusted by the %s store: %s\n", r.Store, strings.Join(r.Path, " → "))
+			} else {
+				stdout.Printf("✗ Not trusted by the %s store: %s\n", r.Store, r.Error)
+			}
+		}
+	}
+
+	if !trusted {
+		return &exitCodeError{code: exitInvalid, err: fmt.Errorf("the chain of %s is not trusted by any store", leaf.Subject)}
+	}
+	return nil
+}
+
+func newVerifiedStore(result certimport.TrustResult) verifiedStore {
+	r := verifiedStore{Store: result.Store, Trusted: result.Err == nil}
+	for _, cert := range result.Path {
+		r.Path = append(r.Path, certName(cert))
+	}
+	if result.Err != nil {
+		r.Error = result.Err.Error()
+	}
+	if result.Missing != nil {
+		r.MissingIssuer = result.Missing.Issuer.String()
+		if urls := result.Missing.IssuingCertificateURL; len(urls) > 0 {
+			r.MissingURL = urls[0]
+		}
+	}
+	return r
+}
+
+// certName names a certificate by its common name, or its whole subject when
+// it has none.
+func certName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
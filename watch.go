@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"github.com/fsnotify/fsnotify"
+)
+
+// settleDelay is how long -daemon waits after a file changes before reading
+// it, so that a renewal writing the certificate and key one after the other
+// is imported once, with both files updated.
+const settleDelay = 2 * time.Second
+
+// validateDaemon checks the options that -daemon cannot be combined with.
+func (cfg *CertImportConfig) validateDaemon() error {
+	if !cfg.Daemon {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		return errors.New("-interval must be positive")
+	}
+	if cfg.Dir != "" || cfg.TargetsFile != "" || cfg.IAM || cfg.DryRun {
+		return errors.New("-daemon cannot be combined with -dir, -targets, -iam or -dry-run")
+	}
+	for _, path := range []string{cfg.CertFile, cfg.PrivateKeyFile, cfg.ChainFile, cfg.BundleFile, cfg.PKCS12File} {
+		if path == "-" {
+			return errors.New("-daemon cannot read inputs from stdin")
+		}
+	}
+	return nil
+}
+
+// watchImport keeps importing the certificate: every -interval and whenever
+// one of its local files changes, the inputs are read again and imported if
+// the certificate or chain differs from the last import. Later imports
+// replace the certificate with the same names, as with -upsert. Failures are
+// reported and retried on the next change or interval; watchImport only
+// returns when ctx is cancelled.
+func watchImport(ctx context.Context, cfg CertImportConfig) error {
+	// Nobody is around to answer a passphrase prompt
+	cfg.NoPrompt = true
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch files: %w", err)
+	}
+	defer watcher.Close()
+
+	files := watchedFiles(cfg)
+	for dir := range files {
+		// Watching the directory catches files replaced by a rename or a
+		// symlink swap, as certbot does, which a watch on the file would miss
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+		progress.Printf("Watching %s for changes\n", dir)
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	settle := time.NewTimer(0)
+	defer settle.Stop()
+
+	var last string
+	for {
+		select {
+		case <-ctx.Done():
+			progress.Printf("Stopping\n")
+			return nil
+		case err := <-watcher.Errors:
+			progress.Warnf("File watch error: %v", err)
+			continue
+		case event := <-watcher.Events:
+			if files[filepath.Dir(event.Name)][filepath.Base(event.Name)] {
+				progress.Debugf("%s changed (%s)\n", event.Name, event.Op)
+				settle.Reset(settleDelay)
+			}
+			continue
+		case <-ticker.C:
+		case <-settle.C:
+		}
+
+		ticker.Reset(cfg.Interval)
+		digest, err := syncCertificate(ctx, cfg, last)
+		if err != nil {
+			progress.Warnf("%v (retrying on the next change or at %s)", errorHint(err), time.Now().Add(cfg.Interval).Format(time.RFC3339))
+			continue
+		}
+		last = digest
+		if cfg.CertificateArn == "" {
+			cfg.Upsert = true
+		}
+		progress.Printf("Next check at %s\n", time.Now().Add(cfg.Interval).Format(time.RFC3339))
+	}
+}
+
+// syncCertificate reads the inputs and imports them unless their digest is
+// last. It returns the digest of the certificate read.
+func syncCertificate(ctx context.Context, cfg CertImportConfig, last string) (string, error) {
+	ctx, cancel := withTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	awsCfg, regions, err := importTargets(ctx, &cfg)
+	if err != nil {
+		return "", err
+	}
+	material, err := loadMaterial(ctx, cfg, awsCfg, progress)
+	if err != nil {
+		notifyFailure(ctx, awsCfg, cfg.Notify, err)
+		return "", err
+	}
+
+	digest := materialDigest(material)
+	if digest == last {
+		progress.Printf("✓ Certificate unchanged (%s)\n", certimport.Fingerprint(material.Leaf))
+		return digest, nil
+	}
+	if err := importLoaded(ctx, cfg, awsCfg, regions, material); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// materialDigest identifies the certificate and chain, so that a renewal
+// that only changes the intermediates is imported too.
+func materialDigest(m *certimport.Material) string {
+	h := sha256.New()
+	h.Write(m.Leaf.Raw)
+	for _, cert := range m.Chain {
+		h.Write(cert.Raw)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// kubeletDataDir is the symlink the kubelet swaps when a mounted secret is
+// updated; the secret's files are links through it and do not change
+// themselves.
+const kubeletDataDir = "..data"
+
+// watchedFiles returns the local input files by directory. Inputs read from
+// AWS, Vault or the environment are only re-read on the interval.
+func watchedFiles(cfg CertImportConfig) map[string]map[string]bool {
+	files := map[string]map[string]bool{}
+	if cfg.SecretDir != "" {
+		dir := filepath.Clean(cfg.SecretDir)
+		files[dir] = map[string]bool{secretCertFile: true, secretKeyFile: true, secretCAFile: true, kubeletDataDir: true}
+	}
+	for _, path := range []string{cfg.CertFile, cfg.PrivateKeyFile, cfg.ChainFile, cfg.BundleFile, cfg.PKCS12File} {
+		if path == "" || strings.Contains(path, "://") {
+			continue
+		}
+		dir, name := filepath.Split(filepath.Clean(path))
+		dir = filepath.Clean(dir)
+		if files[dir] == nil {
+			files[dir] = map[string]bool{}
+		}
+		files[dir][name] = true
+	}
+	return files
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Locations of the Windows personal ("My") certificate store. Without
+// -store-location both are searched, the user's first.
+var windowsStoreLocations = []string{"user", "machine"}
+
+// parseThumbprint decodes a certificate thumbprint as shown by the Windows
+// certificate manager or PowerShell: the SHA-1 fingerprint in hex, possibly
+// separated by spaces or colons. Copying from the certificate dialog adds an
+// invisible left-to-right mark, which is dropped too.
+func parseThumbprint(s string) ([]byte, error) {
+	clean := strings.NewReplacer(" ", "", ":", "", "\u200e", "").Replace(s)
+	hash, err := hex.DecodeString(clean)
+	if err != nil || len(hash) != 20 {
+		return nil, fmt.Errorf("invalid thumbprint %q, expected 40 hex digits", s)
+	}
+	return hash, nil
+}
+
+// validateWindowsStore checks the options of -from-windows-store.
+func (cfg *CertImportConfig) validateWindowsStore() error {
+	if !cfg.FromWindowsStore {
+		if cfg.Thumbprint != "" || cfg.StoreLocation != "" {
+			return errors.New("-thumbprint and -store-location require -from-windows-store")
+		}
+		return nil
+	}
+	if cfg.Thumbprint == "" {
+		return errors.New("-from-windows-store requires -thumbprint")
+	}
+	if _, err := parseThumbprint(cfg.Thumbprint); err != nil {
+		return err
+	}
+	if cfg.StoreLocation != "" && cfg.StoreLocation != "user" && cfg.StoreLocation != "machine" {
+		return errors.New("-store-location must be user or machine")
+	}
+	return nil
+}
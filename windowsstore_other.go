@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+)
+
+// loadWindowsStore is only implemented on Windows.
+func loadWindowsStore(logger certimport.Logger, thumbprint, location string) (*certimport.Material, error) {
+	return nil, errors.New("-from-windows-store is only supported on Windows")
+}
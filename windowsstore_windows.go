@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"golang.org/x/sys/windows"
+)
+
+// PFXExportCertStoreEx is not wrapped by x/sys/windows.
+var procPFXExportCertStoreEx = windows.NewLazySystemDLL("crypt32.dll").NewProc("PFXExportCertStoreEx")
+
+// Flags of PFXExportCertStoreEx.
+const (
+	reportNoPrivateKey              = 0x1
+	reportNotAbleToExportPrivateKey = 0x2
+	exportPrivateKeys               = 0x4
+)
+
+var errNotInStore = errors.New("certificate not found")
+
+// loadWindowsStore exports the certificate with the SHA-1 thumbprint and its
+// private key from the personal store of the current user or the local
+// machine. The key must have been marked exportable when it was installed.
+// The export is a PKCS#12 blob kept in memory, never a file.
+func loadWindowsStore(logger certimport.Logger, thumbprint, location string) (*certimport.Material, error) {
+	hash, err := parseThumbprint(thumbprint)
+	if err != nil {
+		return nil, err
+	}
+	locations := windowsStoreLocations
+	if location != "" {
+		locations = []string{location}
+	}
+
+	// The blob only lives until it is parsed, but PKCS#12 needs a password
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	password := hex.EncodeToString(secret)
+
+	for _, loc := range locations {
+		data, err := exportFromStore(loc, hash, password)
+		if errors.Is(err, errNotInStore) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		material, err := certimport.LoadPKCS12(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the certificate exported from the %s store: %w", loc, err)
+		}
+		logger.Printf("✓ Certificate exported from the %s certificate store (subject: %s)\n", loc, material.Leaf.Subject)
+		return material, nil
+	}
+	return nil, fmt.Errorf("no certificate with thumbprint %s in the %s personal certificate store", strings.ToUpper(hex.EncodeToString(hash)), strings.Join(locations, " or "))
+}
+
+// exportFromStore finds the certificate in the personal store at location
+// and exports it with its private key as PKCS#12.
+func exportFromStore(location string, hash []byte, password string) ([]byte, error) {
+	flags := uint32(windows.CERT_SYSTEM_STORE_CURRENT_USER)
+	if location == "machine" {
+		flags = windows.CERT_SYSTEM_STORE_LOCAL_MACHINE
+	}
+	name, err := windows.UTF16PtrFromString("MY")
+	if err != nil {
+		return nil, err
+	}
+	store, err := windows.CertOpenStore(windows.CERT_STORE_PROV_SYSTEM, 0, 0,
+		flags|windows.CERT_STORE_OPEN_EXISTING_FLAG|windows.CERT_STORE_READONLY_FLAG, uintptr(unsafe.Pointer(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the %s certificate store: %w", location, err)
+	}
+	defer windows.CertCloseStore(store, 0)
+
+	blob := windows.CryptHashBlob{Size: uint32(len(hash)), Data: &hash[0]}
+	cert, err := windows.CertFindCertificateInStore(store, windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING,
+		0, windows.CERT_FIND_HASH, unsafe.Pointer(&blob), nil)
+	if errors.Is(err, windows.Errno(windows.CRYPT_E_NOT_FOUND)) {
+		return nil, errNotInStore
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search the %s certificate store: %w", location, err)
+	}
+	defer windows.CertFreeCertificateContext(cert)
+
+	// Export from a memory store holding just this certificate; its
+	// properties, including the link to the private key, are copied along
+	mem, err := windows.CertOpenStore(windows.CERT_STORE_PROV_MEMORY, 0, 0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CertCloseStore(mem, 0)
+	if err := windows.CertAddCertificateContextToStore(mem, cert, windows.CERT_STORE_ADD_ALWAYS, nil); err != nil {
+		return nil, err
+	}
+
+	pw, err := windows.UTF16PtrFromString(password)
+	if err != nil {
+		return nil, err
+	}
+	var pfx windows.CryptDataBlob
+	if err := pfxExport(mem, &pfx, pw); err != nil {
+		return nil, fmt.Errorf("failed to export the certificate and private key from the %s store (is the key exportable?): %w", location, err)
+	}
+	data := make([]byte, pfx.Size)
+	pfx.Data = &data[0]
+	if err := pfxExport(mem, &pfx, pw); err != nil {
+		return nil, fmt.Errorf("failed to export the certificate and private key from the %s store: %w", location, err)
+	}
+	return data[:pfx.Size], nil
+}
+
+// pfxExport calls PFXExportCertStoreEx, which reports the size needed when
+// pfx.Data is nil.
+func pfxExport(store windows.Handle, pfx *windows.CryptDataBlob, password *uint16) error {
+	ok, _, err := procPFXExportCertStoreEx.Call(uintptr(store), uintptr(unsafe.Pointer(pfx)), uintptr(unsafe.Pointer(password)), 0,
+		exportPrivateKeys|reportNoPrivateKey|reportNotAbleToExportPrivateKey)
+	if ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bldmgr/aws-certs.git/internal/cliutil"
+	"github.com/bldmgr/aws-certs.git/pkg/certimport"
+	"golang.org/x/term"
+)
+
+// errCancelled is returned when the summary of an -interactive import is
+// not confirmed.
+var errCancelled = errors.New("import cancelled")
+
+// pemExtensions are the files offered by the -interactive wizard.
+var pemExtensions = map[string]bool{".pem": true, ".crt": true, ".cer": true, ".cert": true, ".key": true, ".der": true}
+
+// pemFile is what the wizard found in a file.
+type pemFile struct {
+	Certs  []*x509.Certificate
+	HasKey bool
+}
+
+// wizard asks the questions of -interactive on the terminal. Prompts go to
+// stderr so that stdout only carries results.
+type wizard struct {
+	in    *bufio.Reader
+	files []string
+}
+
+// runWizard fills in the inputs, regions and tags that were not given as
+// flags by asking for them, shows a summary and asks for confirmation.
+func (cfg *CertImportConfig) runWizard() error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return errors.New("-interactive needs a terminal")
+	}
+	if cfg.Dir != "" || cfg.TargetsFile != "" || cfg.Daemon || progress.Quiet() {
+		return errors.New("-interactive cannot be combined with -dir, -targets, -daemon or -quiet")
+	}
+	if cfg.PKCS12File != "" || cfg.SecretDir != "" || countSet(cfg.CertEnv, cfg.CertSecret, cfg.CertData, cfg.KeyData, cfg.ChainData) > 0 {
+		return errors.New("-interactive only asks for PEM or DER files; use flags for other inputs")
+	}
+
+	w := &wizard{in: bufio.NewReader(os.Stdin), files: pemCandidates(".")}
+	fmt.Fprintf(os.Stderr, "Import a certificate into ACM. Press Enter to accept the [default].\n\n")
+
+	if cfg.CertFile == "" && cfg.BundleFile == "" {
+		if err := w.chooseCertificate(cfg); err != nil {
+			return err
+		}
+	}
+	leaf, chained, err := cfg.wizardLeaf()
+	if err != nil {
+		return err
+	}
+	if cfg.BundleFile == "" && cfg.PrivateKeyFile == "" && cfg.KeyEnv == "" && cfg.KeySecret == "" {
+		if err := w.chooseKey(cfg, leaf); err != nil {
+			return err
+		}
+	}
+	if cfg.BundleFile == "" && !chained && countSet(cfg.ChainFile, cfg.ChainEnv, cfg.ChainSecret) == 0 && !cfg.FetchChain {
+		if err := w.chooseChain(cfg); err != nil {
+			return err
+		}
+	}
+	if len(cfg.Regions) == 0 && !cfg.AllRegions {
+		if err := w.chooseRegions(cfg); err != nil {
+			return err
+		}
+	}
+	if len(cfg.Tags) == 0 {
+		if err := w.chooseTags(cfg); err != nil {
+			return err
+		}
+	}
+
+	cfg.printSummary(leaf)
+	ok, err := w.confirm("Import this certificate?", false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errCancelled
+	}
+	return nil
+}
+
+func (w *wizard) chooseCertificate(cfg *CertImportConfig) error {
+	if len(w.files) > 0 {
+		fmt.Fprintf(os.Stderr, "Certificate files in the current directory:\n")
+		for i, name := range w.files {
+			fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, name)
+		}
+	}
+	for {
+		path, err := w.chooseFile("Certificate file (number or path)", true)
+		if err != nil {
+			return err
+		}
+		f, err := previewFile(path)
+		if err != nil {
+			progress.Printf("✗ %v\n", err)
+			continue
+		}
+		if len(f.Certs) == 0 {
+			progress.Printf("✗ %s does not contain a certificate\n", path)
+			continue
+		}
+		ok, err := w.confirm("Use this file?", true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		// A file with the key too is imported as a bundle
+		if f.HasKey {
+			cfg.BundleFile = path
+		} else {
+			cfg.CertFile = path
+		}
+		return nil
+	}
+}
+
+func (w *wizard) chooseKey(cfg *CertImportConfig, leaf *x509.Certificate) error {
+	for {
+		path, err := w.chooseFile("Private key file (number or path)", true)
+		if err != nil {
+			return err
+		}
+		data, err := readFile(path)
+		if err != nil {
+			progress.Printf("✗ %v\n", err)
+			continue
+		}
+		key, err := certimport.ParsePrivateKey(data, nil)
+		clear(data)
+		switch {
+		case errors.Is(err, certimport.ErrEncryptedKey):
+			fmt.Fprintf(os.Stderr, "  Encrypted private key, the passphrase is asked for when importing\n")
+		case err != nil:
+			progress.Printf("✗ %v\n", err)
+			continue
+		case leaf != nil && certimport.VerifyKeyMatch(leaf, key) != nil:
+			progress.Printf("✗ The %s key in %s does not match the certificate\n", certimport.PublicKeyDescription(key.Public()), path)
+			continue
+		default:
+			progress.Printf("  ✓ %s private key matching the certificate\n", certimport.PublicKeyDescription(key.Public()))
+		}
+		cfg.PrivateKeyFile = path
+		return nil
+	}
+}
+
+func (w *wizard) chooseChain(cfg *CertImportConfig) error {
+	for {
+		path, err := w.chooseFile("Certificate chain file (number or path, Enter for none)", false)
+		if err != nil || path == "" {
+			return err
+		}
+		f, err := previewFile(path)
+		if err != nil {
+			progress.Printf("✗ %v\n", err)
+			continue
+		}
+		if len(f.Certs) == 0 || f.HasKey {
+			progress.Printf("✗ %s is not a certificate chain\n", path)
+			continue
+		}
+		cfg.ChainFile = path
+		return nil
+	}
+}
+
+func (w *wizard) chooseRegions(cfg *CertImportConfig) error {
+	region := cfg.Region
+	if region == "" {
+		region = firstSet(os.Getenv("AWS_REGION"), "us-east-1")
+	}
+	answer, err := w.ask("Regions to import into, comma-separated (CloudFront needs us-east-1)", region)
+	if err != nil {
+		return err
+	}
+	regions := cliutil.SplitList(answer)
+	if len(regions) == 1 {
+		cfg.Region = regions[0]
+	} else {
+		cfg.Regions = regions
+	}
+	return nil
+}
+
+func (w *wizard) chooseTags(cfg *CertImportConfig) error {
+	for {
+		answer, err := w.ask("Tags, e.g. Environment=prod,Owner=web (Enter for none)", "")
+		if err != nil || answer == "" {
+			return err
+		}
+		tags := parseTags(answer)
+		if err := certimport.ValidateTags(tags); err != nil {
+			progress.Printf("✗ %v\n", err)
+			continue
+		}
+		cfg.Tags = tags
+		return nil
+	}
+}
+
+// wizardLeaf returns the certificate chosen so far, and whether its file
+// holds the chain as well.
+func (cfg *CertImportConfig) wizardLeaf() (*x509.Certificate, bool, error) {
+	path := firstSet(cfg.BundleFile, cfg.CertFile)
+	if path == "" || path == "-" || strings.Contains(path, "://") {
+		return nil, false, nil
+	}
+	f, err := loadPEMFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(f.Certs) == 0 {
+		return nil, false, fmt.Errorf("%s does not contain a certificate", path)
+	}
+	return f.Certs[0], len(f.Certs) > 1, nil
+}
+
+// printSummary shows what is about to be imported.
+func (cfg *CertImportConfig) printSummary(leaf *x509.Certificate) {
+	fmt.Fprintf(os.Stderr, "\nSummary:\n")
+	if leaf != nil {
+		fmt.Fprintf(os.Stderr, "  Domain:       %s\n", leaf.Subject.CommonName)
+		if len(leaf.DNSNames) > 0 {
+			fmt.Fprintf(os.Stderr, "  SANs:         %s\n", strings.Join(leaf.DNSNames, ", "))
+		}
+		fmt.Fprintf(os.Stderr, "  Expires:      %s (%d days)\n", leaf.NotAfter.Format("2006-01-02"), daysUntil(leaf.NotAfter))
+	}
+	if cfg.BundleFile != "" {
+		fmt.Fprintf(os.Stderr, "  Bundle:       %s\n", cfg.BundleFile)
+	} else {
+		fmt.Fprintf(os.Stderr, "  Certificate:  %s\n", firstSet(cfg.CertFile, cfg.CertEnv, cfg.CertSecret))
+		fmt.Fprintf(os.Stderr, "  Private key:  %s\n", firstSet(cfg.PrivateKeyFile, cfg.KeyEnv, cfg.KeySecret))
+		if chain := firstSet(cfg.ChainFile, cfg.ChainEnv, cfg.ChainSecret); chain != "" {
+			fmt.Fprintf(os.Stderr, "  Chain:        %s\n", chain)
+		}
+	}
+	switch {
+	case cfg.AllRegions:
+		fmt.Fprintf(os.Stderr, "  Regions:      all enabled regions\n")
+	case len(cfg.Regions) > 0:
+		fmt.Fprintf(os.Stderr, "  Regions:      %s\n", strings.Join(cfg.Regions, ", "))
+	default:
+		fmt.Fprintf(os.Stderr, "  Region:       %s\n", firstSet(cfg.Region, "from the AWS configuration"))
+	}
+	if len(cfg.Tags) > 0 {
+		fmt.Fprintf(os.Stderr, "  Tags:         %s\n", formatTags(cfg.Tags))
+	}
+	if cfg.CertificateArn != "" {
+		fmt.Fprintf(os.Stderr, "  Replaces:     %s\n", cfg.CertificateArn)
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+// chooseFile asks for a file by its number in the list of candidates or by
+// path. An empty answer is only accepted when the file is optional.
+func (w *wizard) chooseFile(question string, required bool) (string, error) {
+	for {
+		answer, err := w.ask(question, "")
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			if required {
+				continue
+			}
+			return "", nil
+		}
+		if n, err := strconv.Atoi(answer); err == nil {
+			if n < 1 || n > len(w.files) {
+				progress.Printf("✗ Choose a number between 1 and %d\n", len(w.files))
+				continue
+			}
+			return w.files[n-1], nil
+		}
+		return answer, nil
+	}
+}
+
+// ask prints question and returns the answer, or def for an empty answer.
+func (w *wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", errCancelled
+		}
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// confirm asks a yes or no question.
+func (w *wizard) confirm(question string, def bool) (bool, error) {
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	for {
+		answer, err := w.ask(question+" ["+choices+"]", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// previewFile prints the certificates and keys in a PEM or DER file.
+func previewFile(path string) (*pemFile, error) {
+	f, err := loadPEMFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, cert := range f.Certs {
+		role := "Certificate"
+		if i > 0 {
+			role = "Chain"
+		}
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", role, cert.Subject)
+		if i == 0 && len(cert.DNSNames) > 0 {
+			fmt.Fprintf(os.Stderr, "    SANs:    %s\n", strings.Join(cert.DNSNames, ", "))
+		}
+		fmt.Fprintf(os.Stderr, "    Issuer:  %s\n", cert.Issuer)
+		fmt.Fprintf(os.Stderr, "    Expires: %s (%d days)\n", cert.NotAfter.Format("2006-01-02"), daysUntil(cert.NotAfter))
+	}
+	if f.HasKey {
+		fmt.Fprintf(os.Stderr, "  Private key\n")
+	}
+	return f, nil
+}
+
+// loadPEMFile parses the certificates in a PEM or DER file and notes whether
+// it holds a private key.
+func loadPEMFile(path string) (*pemFile, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &pemFile{}
+	if certimport.IsDER(data) {
+		if f.Certs, err = certimport.ParseCertificates(data, path); err != nil {
+			return nil, err
+		}
+	}
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s contains an invalid certificate: %w", path, err)
+			}
+			f.Certs = append(f.Certs, cert)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			f.HasKey = true
+		}
+	}
+	return f, nil
+}
+
+// pemCandidates lists the files in dir that look like certificates or keys.
+func pemCandidates(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && pemExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			files = append(files, e.Name())
+		}
+	}
+	sort.Strings(files)
+	return files
+}